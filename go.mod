@@ -35,6 +35,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/playwright-community/playwright-go v0.5200.0
 	github.com/spf13/viper v1.20.1
+	k8s.io/klog/v2 v2.130.1
 )
 
 require (
@@ -131,7 +132,6 @@ require (
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	k8s.io/apiextensions-apiserver v0.33.4 // indirect
 	k8s.io/cli-runtime v0.33.4 // indirect
-	k8s.io/klog/v2 v2.130.1 // indirect
 	k8s.io/kube-openapi v0.0.0-20250610211856-8b98d1ed966a // indirect
 	k8s.io/utils v0.0.0-20250604170112-4c0f3b243397 // indirect
 	sigs.k8s.io/json v0.0.0-20241014173422-cfa47c3a1cc8 // indirect
@@ -0,0 +1,222 @@
+package arrival
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Type is the kind of arrival profile
+type Type string
+
+const (
+	// Constant arrivals at a fixed rate
+	Constant Type = "constant"
+	// Step arrivals whose rate is increased by a fixed amount at a fixed interval
+	Step Type = "step"
+	// Burst arrivals of a fixed number of users at once, at a fixed interval
+	Burst Type = "burst"
+	// Poisson arrivals at a given average rate, ie. with exponentially distributed inter-arrival times
+	Poisson Type = "poisson"
+)
+
+// DefaultMaxInFlight is the default maximum number of arrivals that are processed concurrently
+const DefaultMaxInFlight = 100
+
+// Profile describes when the users arrive, ie. when their signup starts
+type Profile struct {
+	Type Type `json:"type"`
+	// Rate is the number of arrivals per second for the constant and poisson profiles, and the initial rate for the step profile
+	Rate float64 `json:"rate,omitempty"`
+	// RateStep is the amount by which the rate of the step profile is increased after each step
+	RateStep float64 `json:"rateStep,omitempty"`
+	// StepDuration is the duration of each step of the step profile
+	StepDuration metav1.Duration `json:"stepDuration,omitempty"`
+	// BurstSize is the number of users that arrive at once in each burst of the burst profile
+	BurstSize int `json:"burstSize,omitempty"`
+	// BurstInterval is the duration between two bursts of the burst profile
+	BurstInterval metav1.Duration `json:"burstInterval,omitempty"`
+	// Duration is the maximum duration over which the arrivals of all the users are spread, no limit when not set
+	Duration metav1.Duration `json:"duration,omitempty"`
+	// Seed is the seed of the random generator of the poisson profile
+	Seed int64 `json:"seed,omitempty"`
+	// MaxInFlight is the maximum number of arrivals processed concurrently, arrivals are delayed when it's reached
+	MaxInFlight int `json:"maxInFlight,omitempty"`
+}
+
+// Validate checks the settings of the profile and that the arrivals of the given number of users fit in the duration of the profile
+func (p Profile) Validate(count int) error {
+	switch p.Type {
+	case Constant, Poisson:
+		if p.Rate <= 0 {
+			return fmt.Errorf("invalid %s arrival profile: the rate must be more than 0", p.Type)
+		}
+	case Step:
+		if p.Rate <= 0 || p.RateStep < 0 || p.StepDuration.Duration <= 0 {
+			return fmt.Errorf("invalid %s arrival profile: the rate and the step duration must be more than 0 and the rate step must not be negative", p.Type)
+		}
+	case Burst:
+		if p.BurstSize < 1 || p.BurstInterval.Duration <= 0 {
+			return fmt.Errorf("invalid %s arrival profile: the burst size and the burst interval must be more than 0", p.Type)
+		}
+	default:
+		return fmt.Errorf("invalid arrival profile type '%s': must be one of %s, %s, %s or %s", p.Type, Constant, Step, Burst, Poisson)
+	}
+	if p.MaxInFlight < 0 {
+		return fmt.Errorf("invalid %s arrival profile: the max in-flight value must not be negative", p.Type)
+	}
+	if p.Duration.Duration > 0 && count > 0 {
+		// the poisson arrivals are checked against their expected duration since the actual one is random
+		needed := time.Duration(float64(count-1) / p.Rate * float64(time.Second))
+		if p.Type != Poisson {
+			needed = p.Schedule(count)[count-1]
+		}
+		if needed > p.Duration.Duration {
+			return fmt.Errorf("invalid %s arrival profile: the arrivals of %d users take %s, which exceeds the profile duration of %s", p.Type, count, needed, p.Duration.Duration)
+		}
+	}
+	return nil
+}
+
+// Schedule returns the offsets from the start of the run at which each of the given number of users arrives
+func (p Profile) Schedule(count int) []time.Duration {
+	schedule := make([]time.Duration, 0, count)
+	switch p.Type {
+	case Constant:
+		for i := 0; i < count; i++ {
+			schedule = append(schedule, seconds(float64(i)/p.Rate))
+		}
+	case Step:
+		var t float64
+		for i := 0; i < count; i++ {
+			schedule = append(schedule, seconds(t))
+			// the next arrival is computed with the rate of the step the current arrival belongs to
+			step := math.Floor(t / p.StepDuration.Seconds())
+			t += 1 / (p.Rate + step*p.RateStep)
+		}
+	case Burst:
+		for i := 0; i < count; i++ {
+			schedule = append(schedule, time.Duration(i/p.BurstSize)*p.BurstInterval.Duration)
+		}
+	case Poisson:
+		rnd := rand.New(rand.NewSource(p.Seed)) // nolint:gosec
+		var t float64
+		for i := 0; i < count; i++ {
+			schedule = append(schedule, seconds(t))
+			t += rnd.ExpFloat64() / p.Rate
+		}
+	default:
+		// no profile: all users arrive at once
+		for i := 0; i < count; i++ {
+			schedule = append(schedule, 0)
+		}
+	}
+	return schedule
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// String returns a short description of the profile
+func (p Profile) String() string {
+	switch p.Type {
+	case Constant, Poisson:
+		return fmt.Sprintf("%s (%.2f/s)", p.Type, p.Rate)
+	case Step:
+		return fmt.Sprintf("%s (%.2f/s +%.2f/s every %s)", p.Type, p.Rate, p.RateStep, p.StepDuration.Duration)
+	case Burst:
+		return fmt.Sprintf("%s (%d every %s)", p.Type, p.BurstSize, p.BurstInterval.Duration)
+	default:
+		return "none"
+	}
+}
+
+// Stats are the scheduled and achieved arrival times of a run
+type Stats struct {
+	// Arrivals is the number of arrivals that were dispatched
+	Arrivals int
+	// Scheduled is the offset of the last arrival according to the schedule
+	Scheduled time.Duration
+	// Elapsed is the actual offset of the last arrival
+	Elapsed time.Duration
+	// MaxLag is the longest delay of an arrival compared to the schedule
+	MaxLag time.Duration
+}
+
+// TargetRate returns the average number of arrivals per second according to the schedule
+func (s Stats) TargetRate() float64 {
+	return rate(s.Arrivals, s.Scheduled)
+}
+
+// AchievedRate returns the average number of arrivals per second that was actually achieved
+func (s Stats) AchievedRate() float64 {
+	return rate(s.Arrivals, s.Elapsed)
+}
+
+func rate(arrivals int, d time.Duration) float64 {
+	if arrivals < 2 || d <= 0 {
+		return 0
+	}
+	return float64(arrivals-1) / d.Seconds()
+}
+
+// Run calls the given function for each arrival of the schedule at its scheduled time, with at most maxInFlight calls running concurrently
+// (unlimited when 0). An arrival is delayed when the limit is reached. Run blocks until all the calls have returned.
+// When the context is done, the remaining arrivals are dropped and Run returns once the calls in flight have returned.
+func Run(ctx context.Context, schedule []time.Duration, maxInFlight int, arrive func(i int)) Stats {
+	if maxInFlight <= 0 {
+		maxInFlight = len(schedule)
+	}
+	slots := make(chan struct{}, maxInFlight)
+	var wg sync.WaitGroup
+	stats := Stats{}
+	start := time.Now()
+	for i, offset := range schedule {
+		if !waitUntil(ctx, start.Add(offset)) {
+			break
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		actual := time.Since(start)
+		stats.Arrivals++
+		stats.Scheduled = offset
+		stats.Elapsed = actual
+		if lag := actual - offset; lag > stats.MaxLag {
+			stats.MaxLag = lag
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			arrive(i)
+		}(i)
+	}
+	wg.Wait()
+	return stats
+}
+
+// waitUntil waits until the given time and returns true, or returns false as soon as the context is done
+func waitUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,184 @@
+package arrival
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSchedule(t *testing.T) {
+	t.Run("constant", func(t *testing.T) {
+		// given
+		p := Profile{Type: Constant, Rate: 4}
+
+		// when
+		schedule := p.Schedule(5)
+
+		// then
+		assert.Equal(t, []time.Duration{0, 250 * time.Millisecond, 500 * time.Millisecond, 750 * time.Millisecond, time.Second}, schedule)
+	})
+
+	t.Run("step", func(t *testing.T) {
+		// given
+		p := Profile{Type: Step, Rate: 1, RateStep: 1, StepDuration: metav1.Duration{Duration: 2 * time.Second}}
+
+		// when
+		schedule := p.Schedule(6)
+
+		// then
+		// 1/s during the first 2 seconds, 2/s during the next 2 seconds, then 3/s
+		assert.Equal(t, []time.Duration{0, time.Second, 2 * time.Second, 2500 * time.Millisecond, 3 * time.Second, 3500 * time.Millisecond}, schedule)
+	})
+
+	t.Run("burst", func(t *testing.T) {
+		// given
+		p := Profile{Type: Burst, BurstSize: 2, BurstInterval: metav1.Duration{Duration: time.Minute}}
+
+		// when
+		schedule := p.Schedule(5)
+
+		// then
+		assert.Equal(t, []time.Duration{0, 0, time.Minute, time.Minute, 2 * time.Minute}, schedule)
+	})
+
+	t.Run("poisson", func(t *testing.T) {
+		// given
+		p := Profile{Type: Poisson, Rate: 10, Seed: 42}
+
+		// when
+		schedule := p.Schedule(1000)
+
+		// then
+		assert.Equal(t, p.Schedule(1000), schedule) // same seed, same schedule
+		assert.NotEqual(t, Profile{Type: Poisson, Rate: 10, Seed: 24}.Schedule(1000), schedule)
+		for i := 1; i < len(schedule); i++ {
+			assert.GreaterOrEqual(t, schedule[i], schedule[i-1])
+		}
+		// the average rate is close to the expected one
+		assert.InDelta(t, 100*time.Second, schedule[999], float64(10*time.Second))
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		for name, p := range map[string]Profile{
+			"constant":         {Type: Constant, Rate: 1},
+			"constant in time": {Type: Constant, Rate: 1, Duration: metav1.Duration{Duration: 9 * time.Second}},
+			"step":             {Type: Step, Rate: 1, StepDuration: metav1.Duration{Duration: time.Second}},
+			"burst":            {Type: Burst, BurstSize: 5, BurstInterval: metav1.Duration{Duration: time.Second}, Duration: metav1.Duration{Duration: time.Second}},
+			"poisson":          {Type: Poisson, Rate: 2, Duration: metav1.Duration{Duration: 5 * time.Second}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				require.NoError(t, p.Validate(10))
+			})
+		}
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			profile Profile
+			err     string
+		}{
+			"unknown type": {
+				profile: Profile{Type: "linear", Rate: 1},
+				err:     "invalid arrival profile type 'linear': must be one of constant, step, burst or poisson",
+			},
+			"no rate": {
+				profile: Profile{Type: Constant},
+				err:     "invalid constant arrival profile: the rate must be more than 0",
+			},
+			"no step duration": {
+				profile: Profile{Type: Step, Rate: 1, RateStep: 1},
+				err:     "invalid step arrival profile: the rate and the step duration must be more than 0 and the rate step must not be negative",
+			},
+			"no burst size": {
+				profile: Profile{Type: Burst, BurstInterval: metav1.Duration{Duration: time.Second}},
+				err:     "invalid burst arrival profile: the burst size and the burst interval must be more than 0",
+			},
+			"negative max in-flight": {
+				profile: Profile{Type: Constant, Rate: 1, MaxInFlight: -1},
+				err:     "invalid constant arrival profile: the max in-flight value must not be negative",
+			},
+			"exceeds duration": {
+				profile: Profile{Type: Constant, Rate: 1, Duration: metav1.Duration{Duration: 5 * time.Second}},
+				err:     "invalid constant arrival profile: the arrivals of 10 users take 9s, which exceeds the profile duration of 5s",
+			},
+			"poisson exceeds duration": {
+				profile: Profile{Type: Poisson, Rate: 1, Duration: metav1.Duration{Duration: 5 * time.Second}},
+				err:     "invalid poisson arrival profile: the arrivals of 10 users take 9s, which exceeds the profile duration of 5s",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				require.EqualError(t, tc.profile.Validate(10), tc.err)
+			})
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("arrivals follow the schedule", func(t *testing.T) {
+		// given
+		schedule := Profile{Type: Constant, Rate: 50}.Schedule(5)
+		var mu sync.Mutex
+		var arrived []int
+
+		// when
+		stats := Run(context.TODO(), schedule, 0, func(i int) {
+			mu.Lock()
+			defer mu.Unlock()
+			arrived = append(arrived, i)
+		})
+
+		// then
+		assert.ElementsMatch(t, []int{0, 1, 2, 3, 4}, arrived)
+		assert.Equal(t, 5, stats.Arrivals)
+		assert.Equal(t, 80*time.Millisecond, stats.Scheduled)
+		assert.GreaterOrEqual(t, stats.Elapsed, stats.Scheduled)
+		assert.InDelta(t, 50, stats.TargetRate(), 0.01)
+		assert.LessOrEqual(t, stats.AchievedRate(), stats.TargetRate())
+	})
+
+	t.Run("arrivals are delayed when max in-flight is reached", func(t *testing.T) {
+		// given
+		schedule := Profile{Type: Burst, BurstSize: 4, BurstInterval: metav1.Duration{Duration: time.Minute}}.Schedule(4)
+
+		// when
+		stats := Run(context.TODO(), schedule, 2, func(_ int) {
+			time.Sleep(50 * time.Millisecond)
+		})
+
+		// then
+		assert.Equal(t, 4, stats.Arrivals)
+		assert.Equal(t, time.Duration(0), stats.Scheduled)
+		// the last 2 arrivals wait for the first 2 ones to be done
+		assert.GreaterOrEqual(t, stats.MaxLag, 50*time.Millisecond)
+		assert.Zero(t, stats.TargetRate())
+	})
+
+	t.Run("remaining arrivals are dropped when the context is done", func(t *testing.T) {
+		// given
+		schedule := Profile{Type: Constant, Rate: 10}.Schedule(100)
+		ctx, cancel := context.WithCancel(context.TODO())
+		var mu sync.Mutex
+		var arrived []int
+
+		// when
+		stats := Run(ctx, schedule, 0, func(i int) {
+			mu.Lock()
+			defer mu.Unlock()
+			arrived = append(arrived, i)
+			if i == 2 {
+				cancel()
+			}
+		})
+
+		// then
+		assert.Equal(t, []int{0, 1, 2}, arrived)
+		assert.Equal(t, 3, stats.Arrivals)
+	})
+}
@@ -2,15 +2,35 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 
 	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
 	routev1 "github.com/openshift/api/route/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// MetricsServiceAccountPrefix is the prefix of the name of the temporary service account whose token is used to query prometheus when no token
+// is provided, the name of the service account is also the name of the cluster role binding that grants it the monitoring role
+const MetricsServiceAccountPrefix = "toolchain-e2e-setup-metrics"
+
+// NewMetricsServiceAccountName returns a name for the metrics service account that is unique to the run, so that the service account
+// of another run is never reused nor deleted
+func NewMetricsServiceAccountName() string {
+	return fmt.Sprintf("%s-%s", MetricsServiceAccountPrefix, rand.String(5))
+}
+
+// monitoringClusterRole is the cluster role that allows to query the cluster monitoring stack
+const monitoringClusterRole = "cluster-monitoring-view"
+
 func GetTokenRequestURI(cl client.Client) (string, error) {
 	route := routev1.Route{}
 	if err := cl.Get(context.TODO(), types.NamespacedName{
@@ -31,3 +51,75 @@ func GetTokenFromOC() (string, error) {
 	}
 	return strings.TrimSpace(string(o)), nil
 }
+
+// GetServiceAccountToken creates the metrics service account with the given name in the given namespace, binds it to the
+// cluster-monitoring-view cluster role and returns a token of the service account that is valid for the given duration.
+// The service account and the cluster role binding must not exist yet, since they are deleted at the end of the run.
+// The token is requested through the TokenRequest API so that it works with any kubeconfig, eg. one with client certificates.
+func GetServiceAccountToken(ctx context.Context, cl client.Client, namespace, name string, expiration time.Duration) (string, error) {
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	if err := cl.Create(ctx, sa); err != nil {
+		return "", fmt.Errorf("unable to create the service account '%s' in namespace '%s': %w", name, namespace, err)
+	}
+	crb := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     monitoringClusterRole,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      name,
+				Namespace: namespace,
+			},
+		},
+	}
+	if err := cl.Create(ctx, crb); err != nil {
+		return "", fmt.Errorf("unable to bind the service account '%s' to the cluster role '%s': %w", name, monitoringClusterRole, err)
+	}
+	expirationSeconds := int64(expiration.Seconds())
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+	if err := cl.SubResource("token").Create(ctx, sa, tokenRequest); err != nil {
+		return "", fmt.Errorf("unable to request a token for the service account '%s': %w", name, err)
+	}
+	if tokenRequest.Status.Token == "" {
+		return "", fmt.Errorf("the token of the service account '%s' is empty", name)
+	}
+	return tokenRequest.Status.Token, nil
+}
+
+// DeleteServiceAccount deletes the metrics service account with the given name in the given namespace along with its cluster role binding,
+// which also revokes the tokens of the service account. The resources that don't exist, eg. when the creation failed, are ignored.
+func DeleteServiceAccount(ctx context.Context, cl client.Client, namespace, name string) error {
+	crb := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	if err := cl.Delete(ctx, crb); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("unable to delete the cluster role binding '%s': %w", name, err)
+	}
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	if err := cl.Delete(ctx, sa); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("unable to delete the service account '%s' in namespace '%s': %w", name, namespace, err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	namespace          = "toolchain-host-operator"
+	serviceAccountName = "toolchain-e2e-setup-metrics-abcde"
+)
+
+func TestNewMetricsServiceAccountName(t *testing.T) {
+	// when
+	first, second := NewMetricsServiceAccountName(), NewMetricsServiceAccountName()
+
+	// then
+	assert.Regexp(t, "^toolchain-e2e-setup-metrics-[a-z0-9]{5}$", first)
+	assert.NotEqual(t, first, second)
+}
+
+func TestGetServiceAccountToken(t *testing.T) {
+
+	t.Run("success", func(t *testing.T) {
+		t.Run("service account created", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+
+			// when
+			token, err := GetServiceAccountToken(context.TODO(), cl, namespace, serviceAccountName, 24*time.Hour)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "fake-token", token)
+			require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: serviceAccountName}, &corev1.ServiceAccount{}))
+			crb := &rbacv1.ClusterRoleBinding{}
+			require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Name: serviceAccountName}, crb))
+			assert.Equal(t, "cluster-monitoring-view", crb.RoleRef.Name)
+			require.Len(t, crb.Subjects, 1)
+			assert.Equal(t, rbacv1.Subject{Kind: "ServiceAccount", Name: serviceAccountName, Namespace: namespace}, crb.Subjects[0])
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("service account already exists", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t, serviceAccount())
+
+			// when
+			_, err := GetServiceAccountToken(context.TODO(), cl, namespace, serviceAccountName, 24*time.Hour)
+
+			// then
+			require.ErrorContains(t, err, "unable to create the service account 'toolchain-e2e-setup-metrics-abcde' in namespace 'toolchain-host-operator'")
+			assert.True(t, apierrors.IsAlreadyExists(errors.Unwrap(err)))
+		})
+
+		t.Run("service account not created", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			cl.MockCreate = func(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+				return fmt.Errorf("forbidden")
+			}
+
+			// when
+			_, err := GetServiceAccountToken(context.TODO(), cl, namespace, serviceAccountName, 24*time.Hour)
+
+			// then
+			require.EqualError(t, err, "unable to create the service account 'toolchain-e2e-setup-metrics-abcde' in namespace 'toolchain-host-operator': forbidden")
+		})
+
+		t.Run("cluster role binding not created", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			cl.MockCreate = func(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+				if _, ok := obj.(*rbacv1.ClusterRoleBinding); ok {
+					return fmt.Errorf("forbidden")
+				}
+				return cl.Client.Create(ctx, obj, opts...)
+			}
+
+			// when
+			_, err := GetServiceAccountToken(context.TODO(), cl, namespace, serviceAccountName, 24*time.Hour)
+
+			// then
+			require.EqualError(t, err, "unable to bind the service account 'toolchain-e2e-setup-metrics-abcde' to the cluster role 'cluster-monitoring-view': forbidden")
+		})
+	})
+}
+
+func TestDeleteServiceAccount(t *testing.T) {
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, serviceAccount(), &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: serviceAccountName}})
+
+		// when
+		err := DeleteServiceAccount(context.TODO(), cl, namespace, serviceAccountName)
+
+		// then
+		require.NoError(t, err)
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: serviceAccountName}, &corev1.ServiceAccount{})
+		assert.True(t, apierrors.IsNotFound(err))
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: serviceAccountName}, &rbacv1.ClusterRoleBinding{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("already deleted", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		err := DeleteServiceAccount(context.TODO(), cl, namespace, serviceAccountName)
+
+		// then
+		require.NoError(t, err)
+	})
+}
+
+func serviceAccount() *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serviceAccountName,
+			Namespace: namespace,
+		},
+	}
+}
@@ -0,0 +1,305 @@
+package checkpoint
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/idlers"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Phase is a step of the provisioning of a single user
+type Phase string
+
+const (
+	Signup                 Phase = "signup"
+	SpaceReady             Phase = "space-ready"
+	IdlerUpdated           Phase = "idler-updated"
+	DefaultTemplateApplied Phase = "default-template-applied"
+	CustomTemplateApplied  Phase = "custom-template-applied"
+	// Deactivated and Banned are the outcome of the lifecycle steps, the user is then deprovisioned on purpose
+	Deactivated Phase = "deactivated"
+	Banned      Phase = "banned"
+)
+
+// Phases lists all the phases in the order in which they are performed for a user
+var Phases = []Phase{Signup, SpaceReady, IdlerUpdated, DefaultTemplateApplied, CustomTemplateApplied, Deactivated, Banned}
+
+// entry is a single line of the checkpoint file, a forgotten entry undoes the previous entries of the same user and phase
+type entry struct {
+	Username  string `json:"username"`
+	Phase     Phase  `json:"phase"`
+	Forgotten bool   `json:"forgotten,omitempty"`
+}
+
+// Checkpoint keeps track of the phases that were completed for each user. Each completed phase is appended
+// as a JSON line to the checkpoint file so that the progress survives a failure of the setup run.
+type Checkpoint struct {
+	mu    sync.Mutex
+	path  string
+	f     *os.File
+	users map[string]map[Phase]bool
+}
+
+// New creates an empty checkpoint, truncating the file at the given path if it already exists
+func New(path string) (*Checkpoint, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{
+		path:  path,
+		f:     f,
+		users: map[string]map[Phase]bool{},
+	}, nil
+}
+
+// Load reads the checkpoint file at the given path so that a previous run can be resumed
+func Load(path string) (*Checkpoint, error) {
+	content, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	c := &Checkpoint{
+		path:  path,
+		users: map[string]map[Phase]bool{},
+	}
+	scanner := bufio.NewScanner(content)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		e := entry{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint file '%s' at line %d: %w", path, line, err)
+		}
+		if e.Forgotten {
+			c.forget(e.Username, e.Phase)
+		} else {
+			c.markDone(e.Username, e.Phase)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// rewrite the file so that any incomplete trailing line is discarded before new entries are appended
+	if err := c.Save(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Path returns the location of the checkpoint file
+func (c *Checkpoint) Path() string {
+	return c.path
+}
+
+// IsDone returns true if all the given phases were completed for the given user
+func (c *Checkpoint) IsDone(username string, phases ...Phase) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range phases {
+		if !c.users[username][p] {
+			return false
+		}
+	}
+	return true
+}
+
+// MarkDone records the given phase as completed for the given user and persists it to the checkpoint file
+func (c *Checkpoint) MarkDone(username string, phase Phase) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.users[username][phase] {
+		return nil
+	}
+	c.markDone(username, phase)
+	line, err := json.Marshal(entry{Username: username, Phase: phase})
+	if err != nil {
+		return err
+	}
+	_, err = c.f.Write(append(line, '\n'))
+	return err
+}
+
+func (c *Checkpoint) markDone(username string, phase Phase) {
+	if _, ok := c.users[username]; !ok {
+		c.users[username] = map[Phase]bool{}
+	}
+	c.users[username][phase] = true
+}
+
+// IsDeprovisioned returns true if the given user was deactivated or banned, its namespaces are then gone on purpose
+func (c *Checkpoint) IsDeprovisioned(username string) bool {
+	return c.IsDone(username, Deactivated) || c.IsDone(username, Banned)
+}
+
+// Forget removes the given phases of the given user from the checkpoint. Call Save to persist the change.
+func (c *Checkpoint) Forget(username string, phases ...Phase) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range phases {
+		c.forget(username, p)
+	}
+}
+
+// MarkForgotten removes the given phases of the given user from the checkpoint and persists it to the checkpoint file.
+// Unlike Forget followed by Save, the file isn't rewritten but a forgotten entry is appended for each phase that was done.
+func (c *Checkpoint) MarkForgotten(username string, phases ...Phase) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range phases {
+		if !c.users[username][p] {
+			continue
+		}
+		c.forget(username, p)
+		line, err := json.Marshal(entry{Username: username, Phase: p, Forgotten: true})
+		if err != nil {
+			return err
+		}
+		if _, err := c.f.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Checkpoint) forget(username string, phase Phase) {
+	delete(c.users[username], phase)
+	if len(c.users[username]) == 0 {
+		delete(c.users, username)
+	}
+}
+
+// Usernames returns the sorted names of all the users that have at least one phase completed
+func (c *Checkpoint) Usernames() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, 0, len(c.users))
+	for name := range c.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CountDone returns the number of users that have completed the given phase
+func (c *Checkpoint) CountDone(phase Phase) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	count := 0
+	for _, phases := range c.users {
+		if phases[phase] {
+			count++
+		}
+	}
+	return count
+}
+
+// Save rewrites the whole checkpoint file with the current state, new entries are then appended to the rewritten file
+func (c *Checkpoint) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.users))
+	for name := range c.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var content bytes.Buffer
+	for _, name := range names {
+		for _, p := range Phases {
+			if !c.users[name][p] {
+				continue
+			}
+			line, err := json.Marshal(entry{Username: name, Phase: p})
+			if err != nil {
+				return err
+			}
+			content.Write(append(line, '\n'))
+		}
+	}
+
+	// write to a temporary file first so that the checkpoint is never left half-written
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content.Bytes(), 0600); err != nil {
+		return err
+	}
+	if c.f != nil {
+		if err := c.f.Close(); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return err
+	}
+	var err error
+	c.f, err = os.OpenFile(c.path, os.O_APPEND|os.O_WRONLY, 0600)
+	return err
+}
+
+// Close closes the underlying checkpoint file
+func (c *Checkpoint) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.f.Close()
+}
+
+// Verify checks the phases recorded in the checkpoint against the UserSignup, Space and Idler resources that exist in the cluster
+// and forgets the phases (and the ones that depend on them) that can no longer be confirmed, so that they are performed again.
+// The users deactivated or banned by the lifecycle steps are deprovisioned on purpose, so only their UserSignup is checked.
+// The result is persisted to the checkpoint file.
+func (c *Checkpoint) Verify(cl client.Client, idlerTimeout time.Duration) error {
+	for _, username := range c.Usernames() {
+		if c.IsDone(username, Signup) {
+			if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: cfg.HostOperatorNamespace, Name: username}, &toolchainv1alpha1.UserSignup{}); err != nil {
+				if !k8serrors.IsNotFound(err) {
+					return err
+				}
+				c.Forget(username, Phases...)
+				continue
+			}
+		}
+		if c.IsDeprovisioned(username) {
+			continue
+		}
+
+		if c.IsDone(username, SpaceReady) {
+			space := &toolchainv1alpha1.Space{}
+			if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: cfg.HostOperatorNamespace, Name: username}, space); err != nil && !k8serrors.IsNotFound(err) {
+				return err
+			} else if err != nil || !condition.IsTrue(space.Status.Conditions, toolchainv1alpha1.ConditionReady) {
+				// the resources of the templates are gone together with the space, so they need to be applied again
+				c.Forget(username, SpaceReady, IdlerUpdated, DefaultTemplateApplied, CustomTemplateApplied)
+				continue
+			}
+		}
+
+		if c.IsDone(username, IdlerUpdated) {
+			updated, err := idlers.HasTimeout(cl, username, idlerTimeout)
+			if err != nil {
+				return err
+			}
+			if !updated {
+				c.Forget(username, IdlerUpdated)
+			}
+		}
+	}
+	return c.Save()
+}
@@ -0,0 +1,210 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	commontest "github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testspace "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckpoint(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		t.Run("completed phases are reloaded", func(t *testing.T) {
+			// given
+			path := filepath.Join(t.TempDir(), "zippy-checkpoint.jsonl")
+			cp, err := New(path)
+			require.NoError(t, err)
+			require.NoError(t, cp.MarkDone("zippy-0001", Signup))
+			require.NoError(t, cp.MarkDone("zippy-0001", SpaceReady))
+			require.NoError(t, cp.MarkDone("zippy-0002", Signup))
+			require.NoError(t, cp.MarkDone("zippy-0002", Signup)) // recording the same phase twice is a no-op
+			require.NoError(t, cp.Close())
+
+			// when
+			loaded, err := Load(path)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, []string{"zippy-0001", "zippy-0002"}, loaded.Usernames())
+			assert.True(t, loaded.IsDone("zippy-0001", Signup, SpaceReady))
+			assert.True(t, loaded.IsDone("zippy-0002", Signup))
+			assert.False(t, loaded.IsDone("zippy-0002", Signup, SpaceReady))
+			assert.False(t, loaded.IsDone("zippy-0003", Signup))
+			assert.Equal(t, 2, loaded.CountDone(Signup))
+			assert.Equal(t, 1, loaded.CountDone(SpaceReady))
+		})
+
+		t.Run("phases recorded after resuming are appended", func(t *testing.T) {
+			// given
+			path := filepath.Join(t.TempDir(), "zippy-checkpoint.jsonl")
+			require.NoError(t, os.WriteFile(path, []byte(`{"username":"zippy-0001","phase":"signup"}`+"\n"), 0600))
+			cp, err := Load(path)
+			require.NoError(t, err)
+
+			// when
+			require.NoError(t, cp.MarkDone("zippy-0001", SpaceReady))
+			require.NoError(t, cp.Close())
+
+			// then
+			loaded, err := Load(path)
+			require.NoError(t, err)
+			assert.True(t, loaded.IsDone("zippy-0001", Signup, SpaceReady))
+		})
+
+		t.Run("forgotten phases are not persisted", func(t *testing.T) {
+			// given
+			path := filepath.Join(t.TempDir(), "zippy-checkpoint.jsonl")
+			cp, err := New(path)
+			require.NoError(t, err)
+			require.NoError(t, cp.MarkDone("zippy-0001", Signup))
+			require.NoError(t, cp.MarkDone("zippy-0002", Signup))
+
+			// when
+			cp.Forget("zippy-0002", Phases...)
+			require.NoError(t, cp.Save())
+			require.NoError(t, cp.Close())
+
+			// then
+			loaded, err := Load(path)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"zippy-0001"}, loaded.Usernames())
+		})
+
+		t.Run("phases marked as forgotten are appended", func(t *testing.T) {
+			// given
+			path := filepath.Join(t.TempDir(), "zippy-checkpoint.jsonl")
+			cp, err := New(path)
+			require.NoError(t, err)
+			require.NoError(t, cp.MarkDone("zippy-0001", Signup))
+			require.NoError(t, cp.MarkDone("zippy-0001", Deactivated))
+
+			// when
+			require.NoError(t, cp.MarkForgotten("zippy-0001", Deactivated, IdlerUpdated)) // the phases not done are ignored
+			require.NoError(t, cp.Close())
+
+			// then
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, `{"username":"zippy-0001","phase":"signup"}
+{"username":"zippy-0001","phase":"deactivated"}
+{"username":"zippy-0001","phase":"deactivated","forgotten":true}
+`, string(content))
+			loaded, err := Load(path)
+			require.NoError(t, err)
+			assert.True(t, loaded.IsDone("zippy-0001", Signup))
+			assert.False(t, loaded.IsDone("zippy-0001", Deactivated))
+			assert.False(t, loaded.IsDeprovisioned("zippy-0001"))
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("file not found", func(t *testing.T) {
+			// when
+			_, err := Load(filepath.Join(t.TempDir(), "not-found.jsonl"))
+
+			// then
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+
+		t.Run("invalid content", func(t *testing.T) {
+			// given
+			path := filepath.Join(t.TempDir(), "zippy-checkpoint.jsonl")
+			require.NoError(t, os.WriteFile(path, []byte(`{"username":"zippy-0001","phase":"signup"}`+"\n"+"not json\n"), 0600))
+
+			// when
+			_, err := Load(path)
+
+			// then
+			require.EqualError(t, err, "invalid checkpoint file '"+path+"' at line 2: invalid character 'o' in literal null (expecting 'u')")
+		})
+	})
+}
+
+func TestVerify(t *testing.T) {
+	// given
+	configuration.HostOperatorNamespace = "toolchain-host-operator"
+	idlerTimeout := 15 * time.Second
+	readySpace := func(name string) *toolchainv1alpha1.Space {
+		return testspace.NewSpace(configuration.HostOperatorNamespace, name, testspace.WithCondition(
+			toolchainv1alpha1.Condition{
+				Type:   toolchainv1alpha1.ConditionReady,
+				Status: corev1.ConditionTrue,
+				Reason: "Provisioned",
+			}),
+			testspace.WithStatusProvisionedNamespaces([]toolchainv1alpha1.SpaceNamespace{{Name: name + "-dev"}, {Name: name + "-stage"}}))
+	}
+	userSignup := func(name string) *toolchainv1alpha1.UserSignup {
+		return &toolchainv1alpha1.UserSignup{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: configuration.HostOperatorNamespace,
+				Name:      name,
+			},
+		}
+	}
+	idler := func(name string, timeout int32) *toolchainv1alpha1.Idler {
+		return &toolchainv1alpha1.Idler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: toolchainv1alpha1.IdlerSpec{
+				TimeoutSeconds: timeout,
+			},
+		}
+	}
+	path := filepath.Join(t.TempDir(), "zippy-checkpoint.jsonl")
+	cp, err := New(path)
+	require.NoError(t, err)
+	provisioned := []Phase{Signup, SpaceReady, IdlerUpdated, DefaultTemplateApplied, CustomTemplateApplied}
+	for _, username := range []string{"zippy-0001", "zippy-0002", "zippy-0003", "zippy-0004", "zippy-0005", "zippy-0006"} {
+		for _, p := range provisioned {
+			require.NoError(t, cp.MarkDone(username, p))
+		}
+	}
+	require.NoError(t, cp.MarkDone("zippy-0005", Deactivated))
+	require.NoError(t, cp.MarkDone("zippy-0006", Banned))
+	cl := commontest.NewFakeClient(t,
+		// zippy-0001 is fully provisioned
+		userSignup("zippy-0001"), readySpace("zippy-0001"), idler("zippy-0001-dev", 15), idler("zippy-0001-stage", 15),
+		// zippy-0002 has an idler with a different timeout in one of its namespaces
+		userSignup("zippy-0002"), readySpace("zippy-0002"), idler("zippy-0002-dev", 15), idler("zippy-0002-stage", 43200),
+		// zippy-0003 has no space
+		userSignup("zippy-0003"),
+		// zippy-0004 has no usersignup
+		// zippy-0005 and zippy-0006 were deprovisioned by the lifecycle steps
+		userSignup("zippy-0005"), userSignup("zippy-0006"),
+	)
+
+	// when
+	err = cp.Verify(cl, idlerTimeout)
+
+	// then
+	require.NoError(t, err)
+	for _, c := range []*Checkpoint{cp, reload(t, cp)} {
+		assert.True(t, c.IsDone("zippy-0001", provisioned...))
+		assert.True(t, c.IsDone("zippy-0002", Signup, SpaceReady, DefaultTemplateApplied, CustomTemplateApplied))
+		assert.False(t, c.IsDone("zippy-0002", IdlerUpdated))
+		assert.True(t, c.IsDone("zippy-0003", Signup))
+		assert.False(t, c.IsDone("zippy-0003", SpaceReady))
+		assert.False(t, c.IsDone("zippy-0003", DefaultTemplateApplied))
+		assert.True(t, c.IsDone("zippy-0005", append(provisioned, Deactivated)...))
+		assert.True(t, c.IsDone("zippy-0006", append(provisioned, Banned)...))
+		assert.Equal(t, []string{"zippy-0001", "zippy-0002", "zippy-0003", "zippy-0005", "zippy-0006"}, c.Usernames())
+	}
+}
+
+func reload(t *testing.T, cp *Checkpoint) *Checkpoint {
+	require.NoError(t, cp.Close())
+	loaded, err := Load(cp.Path())
+	require.NoError(t, err)
+	return loaded
+}
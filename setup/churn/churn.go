@@ -0,0 +1,235 @@
+package churn
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/arrival"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/latency"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/resources"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Operation is a kind of change made to the resources of a user during the churn
+type Operation string
+
+const (
+	// Create creates a copy of a resource of the templates of the user
+	Create Operation = "create"
+	// Scale scales up the copy of a deployment
+	Scale Operation = "scale"
+	// Delete deletes the copy
+	Delete Operation = "delete"
+)
+
+// Operations lists all the operations in the order in which they are made for each copy
+var Operations = []Operation{Create, Scale, Delete}
+
+// nameSuffix is added to the name of the copies so that they don't conflict with the resources of the templates
+const nameSuffix = "-churn"
+
+// Profile defines the churn made after the users are provisioned
+type Profile struct {
+	// Duration is how long the churn lasts
+	Duration metav1.Duration `json:"duration"`
+	// Rate is the number of operations per second
+	Rate float64 `json:"rate"`
+	// Users is the number of users that make the operations, the first users of the run are used
+	Users int `json:"users"`
+	// Seed is the seed of the random generator that picks which resource of the templates is copied
+	Seed int64 `json:"seed,omitempty"`
+	// MaxInFlight is the maximum number of operations that are made concurrently, the following ones are delayed
+	MaxInFlight int `json:"maxInFlight,omitempty"`
+}
+
+// Validate checks the settings of the profile, the churn users are picked among the given number of users of the run
+func (p Profile) Validate(users int) error {
+	if p.Duration.Duration <= 0 || p.Rate <= 0 {
+		return fmt.Errorf("invalid churn: the duration and the rate must be more than 0")
+	}
+	if p.Users < 1 || p.Users > users {
+		return fmt.Errorf("invalid churn: invalid users value '%d': value must be between 1 and %d", p.Users, users)
+	}
+	if p.MaxInFlight < 0 {
+		return fmt.Errorf("invalid churn: the max in-flight value must not be negative")
+	}
+	return nil
+}
+
+// String returns a compact description of the profile
+func (p Profile) String() string {
+	return fmt.Sprintf("%.2f operations/s by %d users during %s", p.Rate, p.Users, p.Duration.Duration)
+}
+
+// Churner keeps creating, scaling and deleting copies of the resources of the templates of a set of users, in their namespaces.
+// The operations of each user are made in turn: a copy of a random resource of its templates is created, then scaled up if it's
+// a deployment, then deleted. The errors of the API calls don't stop the churn, they are counted per operation.
+type Churner struct {
+	cl      client.Client
+	profile Profile
+	users   []*userState
+	mu      sync.Mutex
+	stats   map[Operation]*operationStats
+}
+
+type userState struct {
+	mu       sync.Mutex
+	username string
+	objs     []client.Object
+	rand     *rand.Rand
+	// copy is the current copy of the user and next the operation to make on it
+	copy client.Object
+	next Operation
+}
+
+type operationStats struct {
+	durations []time.Duration
+	errors    int
+}
+
+// New returns a churner for the given users, with the resources of the given templates of each user processed with the given parameter values of the user.
+// When servedOnly is true, the resources whose kind isn't served by the cluster are left out.
+func New(cl client.Client, s *runtime.Scheme, profile Profile, usernames []string, templatesOf func(username string) []string, paramsOf func(username string) map[string]string,
+	servedOnly bool) (*Churner, error) {
+	c := &Churner{
+		cl:      cl,
+		profile: profile,
+		stats:   map[Operation]*operationStats{},
+	}
+	for _, op := range Operations {
+		c.stats[op] = &operationStats{}
+	}
+	for i, username := range usernames {
+		objs, err := resources.ProcessTemplateFiles(s, username, templatesOf(username), paramsOf(username))
+		if err != nil {
+			return nil, fmt.Errorf("unable to process the templates of user '%s': %w", username, err)
+		}
+		if servedOnly {
+			if objs, err = resources.ServedObjects(cl, objs); err != nil {
+				return nil, err
+			}
+		}
+		c.users = append(c.users, &userState{
+			username: username,
+			objs:     objs,
+			rand:     rand.New(rand.NewSource(profile.Seed + int64(i))), // nolint:gosec
+			next:     Create,
+		})
+	}
+	return c, nil
+}
+
+// Run makes the operations at the rate of the profile until its duration is reached or the context is done, then deletes the remaining copies
+func (c *Churner) Run(ctx context.Context) arrival.Stats {
+	count := int(c.profile.Rate * c.profile.Duration.Seconds())
+	schedule := arrival.Profile{Type: arrival.Constant, Rate: c.profile.Rate}.Schedule(count)
+	maxInFlight := c.profile.MaxInFlight
+	if maxInFlight == 0 {
+		maxInFlight = arrival.DefaultMaxInFlight
+	}
+	stats := arrival.Run(ctx, schedule, maxInFlight, func(i int) {
+		c.operate(c.users[i%len(c.users)])
+	})
+	// clean up so that the churn doesn't change the resources left once the run is over
+	for _, u := range c.users {
+		if u.copy != nil {
+			u.next = Delete
+			c.operate(u)
+		}
+	}
+	return stats
+}
+
+// operate makes the next operation of the given user
+func (c *Churner) operate(u *userState) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	op := u.next
+	if op == Scale && u.copy.GetObjectKind().GroupVersionKind().Kind != "Deployment" {
+		op = Delete
+	}
+	start := time.Now()
+	var err error
+	switch op {
+	case Create:
+		u.copy = copyOf(u.objs[u.rand.Intn(len(u.objs))], resources.UserNamespace(u.username))
+		err = c.cl.Create(context.TODO(), u.copy)
+		if k8serrors.IsAlreadyExists(err) {
+			// left by a previous run that was interrupted
+			err = nil
+		}
+		u.next = Scale
+	case Scale:
+		err = c.cl.Patch(context.TODO(), u.copy, client.RawPatch(types.MergePatchType, []byte(`{"spec":{"replicas":1}}`)))
+		u.next = Delete
+	case Delete:
+		err = c.cl.Delete(context.TODO(), u.copy)
+		if k8serrors.IsNotFound(err) {
+			err = nil
+		}
+		u.copy = nil
+		u.next = Create
+	}
+	if err != nil && op == Create {
+		// try again with another resource next time
+		u.copy = nil
+		u.next = Create
+	}
+	c.record(op, time.Since(start), err)
+}
+
+// copyOf returns a copy of the given processed template object in the given namespace, with a different name
+func copyOf(obj client.Object, namespace string) client.Object {
+	copied := obj.DeepCopyObject().(client.Object)
+	copied.SetName(obj.GetName() + nameSuffix)
+	copied.SetNamespace(namespace)
+	copied.SetResourceVersion("")
+	return copied
+}
+
+func (c *Churner) record(op Operation, d time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats[op]
+	s.durations = append(s.durations, d)
+	if err != nil {
+		s.errors++
+	}
+}
+
+// Results returns the number of operations, the errors, the error rate and the latency distribution of each operation that was made
+func (c *Churner) Results() [][]string {
+	var results [][]string
+	if c == nil {
+		return results
+	}
+	for _, op := range Operations {
+		c.mu.Lock()
+		durations := append([]time.Duration{}, c.stats[op].durations...)
+		errors := c.stats[op].errors
+		c.mu.Unlock()
+		if len(durations) == 0 {
+			continue
+		}
+		s := latency.Summarize(durations)
+		results = append(results,
+			[]string{fmt.Sprintf("Churn %s Operations", op), fmt.Sprintf("%d", s.Count)},
+			[]string{fmt.Sprintf("Churn %s Errors", op), fmt.Sprintf("%d", errors)},
+			[]string{fmt.Sprintf("Churn %s Error Rate (%%)", op), fmt.Sprintf("%.2f", 100*float64(errors)/float64(s.Count))},
+		)
+		for _, p := range latency.Percentiles {
+			results = append(results, []string{fmt.Sprintf("Churn %s Latency p%g (s)", op, p), fmt.Sprintf("%.3f", s.Percentiles[p].Seconds())})
+		}
+		results = append(results, []string{fmt.Sprintf("Churn %s Latency max (s)", op), fmt.Sprintf("%.3f", s.Max.Seconds())})
+	}
+	return results
+}
@@ -0,0 +1,158 @@
+package churn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commontest "github.com/codeready-toolchain/toolchain-common/pkg/test"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const templatePath = "../resources/user-workloads.yaml"
+
+func TestValidate(t *testing.T) {
+	valid := func() Profile {
+		return Profile{Duration: metav1.Duration{Duration: time.Minute}, Rate: 2, Users: 10}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		p := valid()
+		p.MaxInFlight = 5
+
+		// when
+		err := p.Validate(10)
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			modify func(p *Profile)
+			errMsg string
+		}{
+			"no duration": {
+				modify: func(p *Profile) { p.Duration = metav1.Duration{} },
+				errMsg: "invalid churn: the duration and the rate must be more than 0",
+			},
+			"no rate": {
+				modify: func(p *Profile) { p.Rate = 0 },
+				errMsg: "invalid churn: the duration and the rate must be more than 0",
+			},
+			"no users": {
+				modify: func(p *Profile) { p.Users = 0 },
+				errMsg: "invalid churn: invalid users value '0': value must be between 1 and 10",
+			},
+			"more users than the run": {
+				modify: func(p *Profile) { p.Users = 11 },
+				errMsg: "invalid churn: invalid users value '11': value must be between 1 and 10",
+			},
+			"negative max in-flight": {
+				modify: func(p *Profile) { p.MaxInFlight = -1 },
+				errMsg: "invalid churn: the max in-flight value must not be negative",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				p := valid()
+				tc.modify(&p)
+
+				// when
+				err := p.Validate(10)
+
+				// then
+				require.EqualError(t, err, tc.errMsg)
+			})
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	// given
+	s, err := configuration.NewScheme()
+	require.NoError(t, err)
+	profile := Profile{Duration: metav1.Duration{Duration: time.Second}, Rate: 20, Users: 2, Seed: 1}
+	templatesOf := func(username string) []string {
+		return []string{templatePath}
+	}
+	paramsOf := func(username string) map[string]string {
+		return nil
+	}
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		cl := commontest.NewFakeClient(t)
+		churner, err := New(cl, s, profile, []string{"user0001", "user0002"}, templatesOf, paramsOf, false)
+		require.NoError(t, err)
+
+		// when
+		stats := churner.Run(context.TODO())
+
+		// then
+		assert.Equal(t, 20, stats.Arrivals)
+		results := churner.Results()
+		assert.Contains(t, results, []string{"Churn create Errors", "0"})
+		assert.Contains(t, results, []string{"Churn delete Errors", "0"})
+		assertNoCopies(t, cl, "user0001-dev", "user0002-dev")
+	})
+
+	t.Run("api errors are counted", func(t *testing.T) {
+		// given
+		cl := commontest.NewFakeClient(t)
+		cl.MockCreate = func(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+			return assert.AnError
+		}
+		churner, err := New(cl, s, profile, []string{"user0001"}, templatesOf, paramsOf, false)
+		require.NoError(t, err)
+
+		// when
+		churner.Run(context.TODO())
+
+		// then
+		results := churner.Results()
+		assert.Contains(t, results, []string{"Churn create Operations", "20"})
+		assert.Contains(t, results, []string{"Churn create Errors", "20"})
+		assert.Contains(t, results, []string{"Churn create Error Rate (%)", "100.00"})
+		assertNoCopies(t, cl, "user0001-dev")
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		// given
+		cl := commontest.NewFakeClient(t)
+
+		// when
+		_, err := New(cl, s, profile, []string{"user0001"}, func(username string) []string {
+			return []string{"not-found.yaml"}
+		}, paramsOf, false)
+
+		// then
+		require.ErrorContains(t, err, "unable to process the templates of user 'user0001'")
+	})
+}
+
+func TestResultsOfNilChurner(t *testing.T) {
+	// given
+	var churner *Churner
+
+	// when
+	results := churner.Results()
+
+	// then
+	assert.Empty(t, results)
+}
+
+func assertNoCopies(t *testing.T, cl client.Client, namespaces ...string) {
+	for _, ns := range namespaces {
+		deployments := &appsv1.DeploymentList{}
+		require.NoError(t, cl.List(context.TODO(), deployments, client.InNamespace(ns)))
+		assert.Empty(t, deployments.Items)
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"text/tabwriter"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/compare"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/results"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
+
+	"github.com/spf13/cobra"
+)
+
+var tolerancesPath string
+
+func newCompareCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "compare <baseline-results-file> <results-file>...",
+		Short:         "compare the results of one or more runs to the results of a baseline run",
+		Long:          "compare the numeric results of one or more runs to the results of a baseline run. The results files are either csv or json files written by the setup command. The command fails when a delta exceeds the tolerance of its result item.",
+		SilenceErrors: true,
+		SilenceUsage:  false,
+		Args:          cobra.MinimumNArgs(2),
+		Run:           compareResults,
+	}
+
+	cmd.Flags().StringVar(&tolerancesPath, "tolerances", "", "the path to a YAML file with the absolute and/or relative (in percent) tolerated deltas of the result items")
+	return cmd
+}
+
+func compareResults(cmd *cobra.Command, args []string) {
+	cmd.SilenceUsage = true
+	term := terminal.New(cmd.InOrStdin, cmd.OutOrStdout, verbose)
+
+	tolerances := compare.Tolerances{}
+	if tolerancesPath != "" {
+		var err error
+		if tolerances, err = compare.LoadTolerances(tolerancesPath); err != nil {
+			term.Fatalf(err, "unable to load the tolerances file '%s'", tolerancesPath)
+		}
+	}
+
+	baseline, err := results.Load(args[0])
+	if err != nil {
+		term.Fatalf(err, "unable to load the baseline results file '%s'", args[0])
+	}
+
+	exceeded := 0
+	for _, path := range args[1:] {
+		other, err := results.Load(path)
+		if err != nil {
+			term.Fatalf(err, "unable to load the results file '%s'", path)
+		}
+		term.Infof("\n📊 %s compared to %s", path, args[0])
+
+		w := tabwriter.NewWriter(term.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Item\tBaseline\tValue\tDelta\tDelta (%)\t")
+		for _, d := range compare.Compare(baseline, other, tolerances) {
+			status := ""
+			if d.Exceeded != "" {
+				status = "❌ " + d.Exceeded
+				exceeded++
+			}
+			if d.Missing {
+				fmt.Fprintf(w, "%s\t-\t-\t-\t-\t%s\n", d.Name, status)
+				continue
+			}
+			relative := "n/a"
+			if !math.IsInf(d.Relative, 0) {
+				relative = fmt.Sprintf("%+.2f", d.Relative)
+			}
+			fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%+.2f\t%s\t%s\n", d.Name, d.Baseline, d.Value, d.Absolute, relative, status)
+		}
+		if err := w.Flush(); err != nil {
+			term.Fatalf(err, "failed to write the comparison")
+		}
+	}
+
+	if exceeded > 0 {
+		term.Fatalf(fmt.Errorf("%d deltas exceed their tolerance", exceeded), "the results regressed")
+	}
+	term.Infof("\n✅ all deltas are within their tolerance")
+}
@@ -2,30 +2,48 @@ package cmd
 
 import (
 	"context"
-	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/codeready-toolchain/toolchain-e2e/setup/arrival"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/auth"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/checkpoint"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/churn"
 	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/idlers"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/latency"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/lifecycle"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/metrics"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/metrics/queries"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/operators"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/plan"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/platform"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/registration"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/resources"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/results"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/scenario"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/users"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
 
 	"github.com/gosuri/uiprogress"
 	"github.com/gosuri/uitable/util/strutil"
@@ -44,13 +62,63 @@ var (
 	skipIdlerSetup       bool
 	skipInstallOperators bool
 	interactive          bool
+	resume               bool
 	operatorsLimit       int
 	idlerTimeout         string
 	token                string
 	workloads            []string
+	scenarioPath         string
+	signupProfile        string
+	signupRate           float64
+	signupRateStep       float64
+	signupStepDuration   time.Duration
+	signupBurstSize      int
+	signupBurstInterval  time.Duration
+	signupDuration       time.Duration
+	signupSeed           int64
+	signupMaxInFlight    int
+	outputFormats        []string
+	thresholdsPath       string
+	metricsStep          time.Duration
+	queriesPath          string
+	placement            string
+	memberWeights        map[string]int
+	signupMethod         string
+	registrationURL      string
+	churnDuration        time.Duration
+	churnRate            float64
+	churnUsers           int
+	churnSeed            int64
+	churnMaxInFlight     int
+	lifecycleSteps       []string
+	lifecycleMaxInFlight int
+	templateParams       map[string]string
+	templateParamsSeed   int64
+	operatorsParallelism int
+	dryRun               bool
+	prometheusURL        string
+	prometheusRoute      string
+	thanosQuerier        bool
+	clusterSelector      string
 )
 
+// the segments of the run in which the metrics are aggregated separately
+const (
+	OperatorsInstallSegment = "operators install"
+	SignupsSegment          = "signups"
+	TemplateApplySegment    = "template apply"
+	ChurnSegment            = "churn"
+	LifecycleSegment        = "lifecycle"
+	SettleSegment           = "settle"
+)
+
+// interruptedExitCode is the exit code of a run that was interrupted, like a shell does for a command interrupted with Ctrl-C
+const interruptedExitCode = 130
+
 var (
+	// interrupted is true when the run was interrupted by a signal, the partial results are then written before exiting
+	interrupted bool
+
 	IdlerUpdateTime         time.Duration
 	DefaultApplyTimePerUser time.Duration
 	CustomApplyTimePerUser  time.Duration
@@ -68,29 +136,70 @@ func Execute() {
 		Run:           setup,
 	}
 
-	cmd.Flags().StringVar(&usernamePrefix, "username", usernamePrefix, "the prefix used for usersignup names")
-	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
-	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "if 'debug' traces should be displayed in the console")
+	cmd.PersistentFlags().StringVar(&usernamePrefix, "username", usernamePrefix, "the prefix used for usersignup names")
+	cmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
+	cmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "if 'debug' traces should be displayed in the console")
 	cmd.Flags().IntVarP(&numberOfUsers, "users", "u", 2000, "the number of user accounts to provision")
-	cmd.Flags().StringVar(&cfg.HostOperatorNamespace, "host-ns", cfg.DefaultHostNS, "the namespace of Host operator")
-	cmd.Flags().StringVar(&cfg.MemberOperatorNamespace, "member-ns", cfg.DefaultMemberNS, "the namespace of the Member operator")
+	cmd.PersistentFlags().StringVar(&cfg.HostOperatorNamespace, "host-ns", cfg.DefaultHostNS, "the namespace of Host operator")
+	cmd.PersistentFlags().StringVar(&cfg.MemberOperatorNamespace, "member-ns", cfg.DefaultMemberNS, "the namespace of the Member operator")
 	cmd.Flags().StringSliceVar(&customTemplatePaths, "template", []string{}, "the path to the OpenShift template to apply for each custom user")
+	cmd.Flags().StringToStringVar(&templateParams, "template-param", nil, "the value of a parameter of the custom templates, as KEY=VALUE. the value can be generated for each user with 'index()' for the index of the user, 'random(N)' for a random string of N characters or 'pick(a|b|c)' for one of the values, eg. \"--template-param REPLICAS='pick(1|2|3)'\"")
+	cmd.Flags().Int64Var(&templateParamsSeed, "template-param-seed", 1, "the seed of the random values of the template parameters")
 	cmd.Flags().IntVarP(&defaultTemplateUsers, cfg.DefaultTemplateUsersParam, "d", 2000, "how many users will have the default user workloads template applied")
 	cmd.Flags().IntVarP(&customTemplateUsers, cfg.CustomTemplateUsersParam, "c", 2000, "how many users will have the custom user workloads template applied")
 	cmd.Flags().BoolVar(&skipAdditionalWait, "skip-wait", false, "skip the additional wait time after the setup is complete to allow the cluster to settle, primarily used for debugging")
 	cmd.Flags().BoolVar(&skipIdlerSetup, "skip-idler", false, "if the idler timeout should be modified for each user")
 	cmd.Flags().BoolVar(&skipInstallOperators, "skip-install-operators", false, "skip the installation of operators")
-	cmd.Flags().BoolVar(&interactive, "interactive", true, "if user is prompted to confirm all actions")
+	cmd.PersistentFlags().BoolVar(&interactive, "interactive", true, "if user is prompted to confirm all actions")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only report what the setup would create: the templates are processed for a sample user and applied with server-side dry-run calls, the APIs of their objects are checked and nothing is created on the cluster")
+	cmd.Flags().BoolVar(&resume, "resume", false, "resume a previous run with the same username prefix from its checkpoint file, only the remaining work is done for each user")
 	cmd.Flags().IntVar(&operatorsLimit, "operators-limit", len(operators.Templates), "can be specified to limit the number of additional operators to install (by default all operators are installed to simulate cluster load in production)")
+	cmd.Flags().IntVar(&operatorsParallelism, "operators-parallelism", 1, "the number of operators that are installed in parallel, the operators whose subscription already has a succeeded CSV are skipped")
 	cmd.Flags().StringVarP(&idlerTimeout, "idler-timeout", "i", "15s", "overrides the default idler timeout")
-	cmd.Flags().StringVar(&cfg.Testname, "testname", "", "a name that is added as a suffix to the result file names")
+	cmd.PersistentFlags().StringVar(&cfg.Testname, "testname", "", "a name that is added as a suffix to the result file names")
 	cmd.Flags().StringVarP(&token, "token", "t", "", "Openshift API token")
+	cmd.Flags().StringVar(&prometheusURL, "prometheus-url", "", "the URL of the prometheus API to query the metrics from, eg. the URL of an in-cluster service or of a port-forward, instead of the prometheus route")
+	cmd.Flags().StringVar(&prometheusRoute, "prometheus-route", metrics.DefaultBackend.Route, "the route of the prometheus API to query the metrics from, as namespace/name")
+	cmd.Flags().BoolVar(&thanosQuerier, "thanos-querier", false, fmt.Sprintf("query the metrics from the Thanos querier of the OpenShift monitoring stack (route '%s') instead of its prometheus instance, eg. to include the metrics of the user workload monitoring", metrics.ThanosQuerierRoute))
+	cmd.MarkFlagsMutuallyExclusive("prometheus-url", "prometheus-route", "thanos-querier")
+	cmd.Flags().StringVar(&clusterSelector, "cluster-selector", metrics.DefaultBackend.ClusterSelector, "the label matcher of the series of the cluster in the metrics, eg. 'cluster=\"my-cluster\"' when the monitoring stack is shared by several clusters, or '' to not filter the series")
+	cmd.PersistentFlags().StringVar(&scenarioPath, "scenario", "", "the path to a scenario file with the settings of the run and the cohorts of users to provision, the settings of the file take precedence over the flags")
+	cmd.Flags().StringVar(&signupProfile, "signup-profile", "", "the arrival profile of the user signups, one of constant, step, burst or poisson. users are signed up as fast as possible when not set")
+	cmd.Flags().Float64Var(&signupRate, "signup-rate", 1, "the number of user signups per second of the constant and poisson profiles, and the initial rate of the step profile")
+	cmd.Flags().Float64Var(&signupRateStep, "signup-rate-step", 1, "the number of user signups per second added after each step of the step profile")
+	cmd.Flags().DurationVar(&signupStepDuration, "signup-step-duration", 2*time.Minute, "the duration of each step of the step profile")
+	cmd.Flags().IntVar(&signupBurstSize, "signup-burst-size", 100, "the number of users signed up at once in each burst of the burst profile")
+	cmd.Flags().DurationVar(&signupBurstInterval, "signup-burst-interval", 5*time.Minute, "the duration between two bursts of the burst profile")
+	cmd.Flags().DurationVar(&signupDuration, "signup-duration", 0, "the maximum duration over which the user signups of the profile are spread, the setup fails upfront if the users don't fit in it")
+	cmd.Flags().Int64Var(&signupSeed, "signup-seed", 1, "the seed of the random arrivals of the poisson profile")
+	cmd.Flags().IntVar(&signupMaxInFlight, "signup-max-in-flight", arrival.DefaultMaxInFlight, "the maximum number of user signups of the profile that are processed concurrently, the following ones are delayed")
+	cmd.PersistentFlags().StringSliceVar(&outputFormats, "output-format", []string{results.CSV}, fmt.Sprintf("the formats of the results files, several formats can be comma-separated. supported formats: %s", strings.Join(results.Formats, ", ")))
+	cmd.PersistentFlags().StringVar(&thresholdsPath, "thresholds", "", "the path to a YAML file with the min and/or max thresholds of the result items, the results that break their threshold are marked as failures in the junit format")
+	cmd.Flags().DurationVar(&metricsStep, "metrics-step", 30*time.Second, "the resolution of the metrics series captured over the whole run and saved next to the results file, the series are not captured when set to 0")
+	cmd.Flags().StringVar(&queriesPath, "queries", "", "the path to a YAML file with additional PromQL queries to gather during the run, each with a name, a query and a result type (percentage, memory, simple, seconds or rate)")
+	cmd.Flags().StringVar(&placement, "placement", string(users.Single), fmt.Sprintf("how the users are placed on the member clusters, one of %v. 'single' provisions all the users on the member cluster whose operator runs in the member namespace, 'host' lets the host operator decide", users.PlacementStrategies))
+	cmd.Flags().StringToIntVar(&memberWeights, "member-weights", nil, "the weights of the member clusters of the weighted placement, eg. \"--member-weights member-1=3,member-2=1\"")
+	cmd.Flags().StringVar(&signupMethod, "signup-method", scenario.UserSignupMethod, fmt.Sprintf("how the users are signed up, either '%s' to create the UserSignups directly or '%s' to call the signup endpoint of the registration service with signed test tokens", scenario.UserSignupMethod, scenario.RegistrationServiceMethod))
+	cmd.Flags().StringVar(&registrationURL, "registration-service-url", "", "the URL of the registration service, the URL of its route in the host operator namespace is used when not set")
+	cmd.Flags().DurationVar(&churnDuration, "churn-duration", 0, "the duration of the churn phase after the users are provisioned, in which some users keep creating, scaling and deleting copies of the resources of their templates. there is no churn when set to 0")
+	cmd.Flags().Float64Var(&churnRate, "churn-rate", 1, "the number of churn operations per second")
+	cmd.Flags().IntVar(&churnUsers, "churn-users", 100, "the number of users that make the churn operations, among the users with templates applied")
+	cmd.Flags().Int64Var(&churnSeed, "churn-seed", 1, "the seed of the random choice of the resources that are copied during the churn")
+	cmd.Flags().IntVar(&churnMaxInFlight, "churn-max-in-flight", arrival.DefaultMaxInFlight, "the maximum number of churn operations that are made concurrently, the following ones are delayed")
+	cmd.Flags().StringSliceVar(&lifecycleSteps, "lifecycle", []string{}, fmt.Sprintf("the steps in which a percentage of the users are deactivated, reactivated or banned at a rate of users per second once the users are provisioned, as comma-separated action:percentage:rate triples with actions among %v, eg. \"--lifecycle deactivate:10:2,reactivate:5:2,ban:5:1\"", lifecycle.Actions))
+	cmd.Flags().IntVar(&lifecycleMaxInFlight, "lifecycle-max-in-flight", arrival.DefaultMaxInFlight, "the maximum number of users of a lifecycle step that are processed concurrently, the following ones are delayed")
 	cmd.Flags().StringSliceVar(&workloads, "workloads", []string{}, "workload namespace:name pairs that should have metrics collected during the setup. all values are comma-separated eg. \"--workloads service-binding-operator:service-binding-operator,rhoas-operator:rhoas-operator\"")
 
+	cmd.AddCommand(newTeardownCmd())
+	cmd.AddCommand(newCompareCmd())
+
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	if interrupted {
+		os.Exit(interruptedExitCode)
+	}
 }
 
 func setup(cmd *cobra.Command, _ []string) { // nolint:gocyclo
@@ -99,49 +208,101 @@ func setup(cmd *cobra.Command, _ []string) { // nolint:gocyclo
 
 	// call cfg.Init() to initialize variables that are dependent on any flags eg. testname
 	cfg.Init(term)
+	initLog(term)
+
+	// the context is cancelled when the run is interrupted: no more work is started, the work in progress is completed
+	// and the partial results are written
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	// the progress bars are stopped and the console unmuted before the interruption is displayed, stopProgress is set while the progress bars are displayed
+	var progressMu sync.Mutex
+	stopProgress := func() {}
+	go func() {
+		sig := <-signals
+		progressMu.Lock()
+		stopProgress()
+		progressMu.Unlock()
+		term.Infow("🛑 interrupted, completing the work in progress and writing the partial results, interrupt again to exit immediately", "signal", sig.String())
+		cancel()
+		// a second signal terminates the program right away, after the pre-fatal exit hooks, eg. the deletion of the temporary resources
+		sig = <-signals
+		signal.Stop(signals)
+		term.Fatalf(fmt.Errorf("interrupted again by signal '%s'", sig), "exiting immediately")
+	}()
 
-	term.Infof("Number of Users:           '%d'", numberOfUsers)
-	term.Infof("Default Template Users:    '%d'", defaultTemplateUsers)
-	term.Infof("Custom Template Users:     '%d'", customTemplateUsers)
-	term.Infof("Host Operator Namespace:   '%s'", cfg.HostOperatorNamespace)
-	term.Infof("Member Operator Namespace: '%s'\n", cfg.MemberOperatorNamespace)
-
-	generalResultsInfo := [][]string{
-		{"Number of Users", strconv.Itoa(numberOfUsers)},
-		{"Number of Default Template Users", strconv.Itoa(defaultTemplateUsers)},
-		{"Number of Custom Template Users", strconv.Itoa(customTemplateUsers)},
+	thresholds := loadResultsSettings(term)
+	sc := loadScenario(term)
+	if err := sc.Validate(len(operators.Templates)); err != nil {
+		term.Fatalf(err, "invalid scenario")
 	}
-
-	// validate params
-	if numberOfUsers < 1 {
-		term.Fatalf(fmt.Errorf("value must be more than 0"), "invalid users value '%d'", numberOfUsers)
+	idlerDuration, err := time.ParseDuration(sc.IdlerTimeout)
+	if err != nil {
+		term.Fatalf(err, "invalid idler-timeout value '%s'", sc.IdlerTimeout)
 	}
 
-	usersWithinBounds(term, defaultTemplateUsers, cfg.DefaultTemplateUsersParam)
-	usersWithinBounds(term, customTemplateUsers, cfg.CustomTemplateUsersParam)
-
-	if operatorsLimit > len(operators.Templates) {
-		term.Fatalf(fmt.Errorf("the operators limit value must be less than or equal to '%d'", len(operators.Templates)), "invalid operators limit value '%d'", operatorsLimit)
+	term.Infof("Scenario:                  '%s'", sc.Name)
+	term.Infof("Number of Users:           '%d'", sc.Users)
+	term.Infof("Default Template Users:    '%d'", sc.DefaultTemplateUsers)
+	term.Infof("Custom Template Users:     '%d'", sc.CustomTemplateUsers)
+	if sc.SignupProfile != nil {
+		term.Infof("Signup Profile:            '%s'", sc.SignupProfile)
 	}
-
-	idlerDuration, err := time.ParseDuration(idlerTimeout)
-	if err != nil {
-		term.Fatalf(err, "invalid idler-timeout value '%s'", idlerTimeout)
+	term.Infof("Host Operator Namespace:   '%s'", cfg.HostOperatorNamespace)
+	term.Infof("Member Operator Namespace: '%s'", cfg.MemberOperatorNamespace)
+	if sc.Churn != nil {
+		term.Infof("Churn:                     '%s'", sc.Churn)
+	}
+	for _, step := range sc.Lifecycle {
+		term.Infof("Lifecycle Step:            '%s'", step)
 	}
+	term.Infof("Placement:                 '%s'", sc.Placement)
+	term.Infof("Signup Method:             '%s'", sc.SignupMethod)
+	backend := metrics.Backend{URL: prometheusURL, Route: prometheusRoute, ClusterSelector: clusterSelector}
+	if thanosQuerier {
+		backend.Route = metrics.ThanosQuerierRoute
+	}
+	term.Infof("Metrics Backend:           '%s'\n", backend)
 
-	if customTemplateUsers > 0 && len(customTemplatePaths) == 0 {
-		term.Fatalf(errors.New(""), "'%d' users are set to have custom templates applied but no custom templates were provided", customTemplateUsers)
+	generalResultsInfo := [][]string{
+		{"Scenario", sc.String()},
+		{"Number of Users", strconv.Itoa(sc.Users)},
+		{"Number of Default Template Users", strconv.Itoa(sc.DefaultTemplateUsers)},
+		{"Number of Custom Template Users", strconv.Itoa(sc.CustomTemplateUsers)},
+		{"Metrics Backend", backend.String()},
 	}
 
-	for _, w := range workloads {
-		pair := strings.Split(w, ":")
-		if len(pair)%2 == 1 {
-			term.Fatalf(err, "invalid workloads values provided '%v' - values must be namespace:name pairs", workloads)
+	// add the default user-workloads.yaml file automatically, or its objects that don't depend on the OpenShift APIs on plain Kubernetes
+	defaultTemplatePath := "setup/resources/user-workloads.yaml"
+
+	// list the users of all cohorts along with the templates to apply for them
+	var allUsernames, defaultTemplateUsernames, customTemplateUsernames []string
+	cohortOf := map[string]scenario.Cohort{}
+	// the index of each user in the run, starting at 1, from which the values of the template parameters are generated
+	indexOf := map[string]int{}
+	for _, c := range sc.Cohorts {
+		for i := 1; i <= c.Users; i++ {
+			username := c.Username(i)
+			cohortOf[username] = c
+			allUsernames = append(allUsernames, username)
+			indexOf[username] = len(allUsernames)
+			if i <= c.DefaultTemplateUsers {
+				defaultTemplateUsernames = append(defaultTemplateUsernames, username)
+			}
+			if i <= c.CustomTemplateUsers {
+				customTemplateUsernames = append(customTemplateUsernames, username)
+			}
 		}
 	}
 
-	// add the default user-workloads.yaml file automatically
-	defaultTemplatePath := "setup/resources/user-workloads.yaml"
+	params, err := resources.ParseParams(sc.TemplateParams, sc.TemplateParamsSeed)
+	if err != nil {
+		term.Fatalf(err, "invalid template-param value")
+	}
+	paramsOf := func(username string) map[string]string {
+		return params.For(indexOf[username])
+	}
 
 	term.Infof("🕖 initializing...\n")
 	cl, config, scheme, err := cfg.NewClient(term, kubeconfig)
@@ -149,41 +310,134 @@ func setup(cmd *cobra.Command, _ []string) { // nolint:gocyclo
 		term.Fatalf(err, "cannot create client")
 	}
 
+	// the steps that depend on OpenShift or OLM are skipped or substituted on the clusters without them, eg. a local kind cluster
+	clusterPlatform, err := platform.Detect(cl)
+	if err != nil {
+		term.Fatalf(err, "unable to detect the platform of the cluster")
+	}
+	term.Infof("Platform: %s", clusterPlatform)
+	generalResultsInfo = append(generalResultsInfo, []string{"Platform", clusterPlatform.String()})
+	// without the OpenShift APIs, the objects of the templates that depend on them are skipped, eg. the routes and the builds
+	servedOnly := !clusterPlatform.OpenShift
+
+	if dryRun {
+		outputPlan(ctx, term, cl, scheme, sc, defaultTemplatePath, defaultTemplateUsernames, paramsOf, servedOnly)
+		return
+	}
+
+	if !clusterPlatform.OpenShift && backend.URL == "" {
+		term.Fatalf(fmt.Errorf("the cluster doesn't serve the routes"), "use the --prometheus-url flag to set the URL of the prometheus API, eg. the URL of a port-forward to the prometheus service")
+	}
+
 	if len(token) == 0 {
-		token, err = auth.GetTokenFromOC()
-		if err != nil {
-			tokenRequestURI, err := auth.GetTokenRequestURI(cl)
-			errMsg := "a token is required to capture metrics, use oc login with token to log into the cluster. eg. `oc login --token=<token> --server=<server>`"
-			if err != nil {
-				term.Fatalf(err, errMsg)
+		if token, err = auth.GetTokenFromOC(); err != nil {
+			// without the oc binary or a token in the kubeconfig, eg. with client certificates, the token of a temporary service account is used
+			serviceAccount := auth.NewMetricsServiceAccountName()
+			term.Infof("no token returned by 'oc whoami -t', using the token of the temporary service account '%s'", serviceAccount)
+			// the service account is deleted at the end of the run, as well as before a fatal exit, eg. when the run is interrupted twice.
+			// The deletion is registered before the creation so that the resources created before a failure are deleted too, and since the
+			// pre-fatal exit hooks run in the reverse order, it runs after the hook that captures the metrics with the token of the service account.
+			var deleteOnce sync.Once
+			deleteServiceAccount := func() {
+				deleteOnce.Do(func() {
+					if err := auth.DeleteServiceAccount(context.WithoutCancel(ctx), cl, cfg.HostOperatorNamespace, serviceAccount); err != nil {
+						term.Errorf(err, "failed to delete the temporary service account '%s'", serviceAccount)
+					}
+				})
+			}
+			term.AddPreFatalExitHook(deleteServiceAccount)
+			defer deleteServiceAccount()
+			if token, err = auth.GetServiceAccountToken(ctx, cl, cfg.HostOperatorNamespace, serviceAccount, 24*time.Hour); err != nil {
+				errMsg := "a token is required to capture metrics, provide it with the --token flag or use oc login with token to log into the cluster. eg. `oc login --token=<token> --server=<server>`"
+				tokenRequestURI, uriErr := auth.GetTokenRequestURI(cl)
+				if uriErr != nil {
+					term.Fatalf(err, errMsg)
+				}
+				term.Fatalf(fmt.Errorf("%w, a token can be requested from %s", err, tokenRequestURI), errMsg)
 			}
-			term.Fatalf(fmt.Errorf("a token can be requested from %s", tokenRequestURI), errMsg)
 		}
 	}
 
-	var templateListStr string
-	templateListStr += "\n - (default) " + defaultTemplatePath
-	for _, p := range customTemplatePaths {
-		absPath, err := filepath.Abs(p)
-		if err != nil {
-			term.Fatalf(err, "invalid template file: '%s'", absPath)
+	templateListStr := "\n - (default) " + defaultTemplatePath
+	for _, c := range sc.Cohorts {
+		tier := c.SpaceTier
+		if tier == "" {
+			tier = cfg.UserSpaceTier
 		}
-		_, err = os.ReadFile(absPath)
-		if err != nil {
-			term.Fatalf(err, "invalid template file: '%s'", absPath)
+		templateListStr += fmt.Sprintf("\n cohort '%s': %d users with prefix '%s' in space tier '%s'", c.Name, c.Users, c.UsernamePrefix, tier)
+		for _, p := range c.Templates {
+			absPath, err := filepath.Abs(p)
+			if err != nil {
+				term.Fatalf(err, "invalid template file: '%s'", p)
+			}
+			templateListStr += "\n - (custom) " + absPath
 		}
-		templateListStr += "\n - (custom) " + absPath
 	}
 
 	term.Infof("📋 template list: %s\n", templateListStr)
-	if interactive && !term.PromptBoolf("👤 provision %d users on %s using the templates listed above", numberOfUsers, config.Host) {
+
+	// load the checkpoint of the previous run when resuming, otherwise start with an empty one
+	checkpointPath := cfg.CheckpointFilepath(sc.Name)
+	var cp *checkpoint.Checkpoint
+	if resume {
+		if cp, err = checkpoint.Load(checkpointPath); err != nil {
+			term.Fatalf(err, "unable to load the checkpoint file '%s'", checkpointPath)
+		}
+		term.Infof("🔎 verifying the checkpoint against the cluster...")
+		if err := cp.Verify(cl, idlerDuration); err != nil {
+			term.Fatalf(err, "unable to verify the checkpoint file '%s'", checkpointPath)
+		}
+		term.Infof("♻️  resuming from checkpoint '%s': %d users signed up, %d spaces ready, %d idlers updated, %d default and %d custom templates applied\n", checkpointPath,
+			cp.CountDone(checkpoint.Signup), cp.CountDone(checkpoint.SpaceReady), cp.CountDone(checkpoint.IdlerUpdated),
+			cp.CountDone(checkpoint.DefaultTemplateApplied), cp.CountDone(checkpoint.CustomTemplateApplied))
+	}
+
+	if interactive && !term.PromptBoolf("👤 provision %d users on %s using the templates listed above", sc.Users, config.Host) {
 		return
 	}
 
-	if err := operators.VerifySandboxOperatorsInstalled(cl); err != nil {
+	if !resume {
+		if cp, err = checkpoint.New(checkpointPath); err != nil {
+			term.Fatalf(err, "unable to create the checkpoint file '%s'", checkpointPath)
+		}
+	}
+	defer cp.Close()
+	generalResultsInfo = append(generalResultsInfo,
+		[]string{"Resumed Run", strconv.FormatBool(resume)},
+		[]string{"Users Already Provisioned", strconv.Itoa(cp.CountDone(checkpoint.SpaceReady))},
+	)
+
+	verifySandboxOperators := operators.VerifySandboxOperatorsInstalled
+	if !clusterPlatform.OLM {
+		verifySandboxOperators = operators.VerifySandboxOperatorsDeployed
+	}
+	if err := verifySandboxOperators(cl); err != nil {
 		term.Fatalf(err, "ensure the sandbox host and member operators are installed successfully before running the setup")
 	}
 
+	userPlacement, err := users.NewPlacement(cl, cfg.HostOperatorNamespace, cfg.MemberOperatorNamespace, sc.Placement, sc.MemberWeights)
+	if err != nil {
+		term.Fatalf(err, "unable to place the users on the member clusters")
+	}
+	memberNames := []string{}
+	for _, m := range userPlacement.Members() {
+		memberNames = append(memberNames, m.Name)
+	}
+	term.Infof("Member Clusters: %v", memberNames)
+
+	// the users are signed up through the registration service when requested, the HTTP latency and errors are then part of the results
+	var registrationClient *registration.Client
+	if sc.SignupMethod == scenario.RegistrationServiceMethod {
+		url := registrationURL
+		if url == "" {
+			if url, err = registration.GetURL(cl, cfg.HostOperatorNamespace); err != nil {
+				term.Fatalf(err, "unable to find the registration service, use the --registration-service-url flag to set its URL")
+			}
+		}
+		term.Infof("Registration Service: %s", url)
+		registrationClient = registration.NewClient(url)
+	}
+
 	// =====================
 	// begin configuration
 	// =====================
@@ -191,10 +445,17 @@ func setup(cmd *cobra.Command, _ []string) { // nolint:gocyclo
 	if err := cfg.ConfigureDefaultSpaceTier(cl); err != nil {
 		term.Fatalf(err, "unable to set default space tier")
 	}
+	for _, tier := range sc.SpaceTiers() {
+		if err := cfg.VerifySpaceTier(cl, tier); err != nil {
+			term.Fatalf(err, "unable to find the space tier '%s' of the scenario", tier)
+		}
+	}
 
-	term.Infof("Disabling copied CSVs feature...")
-	if err := cfg.DisableCopiedCSVs(cl); err != nil {
-		term.Fatalf(err, "unable to disable OLM copy CSVs feature")
+	if clusterPlatform.OLMConfig {
+		term.Infof("Disabling copied CSVs feature...")
+		if err := cfg.DisableCopiedCSVs(cl); err != nil {
+			term.Fatalf(err, "unable to disable OLM copy CSVs feature")
+		}
 	}
 	// =====================
 	// end configuration
@@ -205,182 +466,531 @@ func setup(cmd *cobra.Command, _ []string) { // nolint:gocyclo
 	// =====================
 	setupStartTime := time.Now()
 
-	if !skipInstallOperators {
-		term.Infof("⏳ installing operators...")
-		// install operators for member clusters
-		templatePaths := []string{}
-		for i := 0; i < operatorsLimit; i++ {
-			templatePaths = append(templatePaths, "setup/operators/installtemplates/"+operators.Templates[i])
-		}
-		if err := operators.EnsureOperatorsInstalled(cmd.Context(), cl, scheme, templatePaths); err != nil {
-			term.Fatalf(err, "failed to ensure all operators are installed")
-		}
-	}
-
-	// provision the users
-	term.Infof("🍿 provisioning users...")
-
 	// init the metrics gatherer
-	metricsInstance := metrics.New(term, cl, token, 5*time.Minute)
+	metricsInstance := metrics.New(term, cl, token, backend, 5*time.Minute)
 
-	prometheusClient := metrics.GetPrometheusClient(term, cl, token)
+	prometheusClient := metrics.GetPrometheusClient(term, cl, token, backend)
+	if clusterPlatform.OpenShift {
+		metricsInstance.AddQueries(metrics.OpenShiftQueries(prometheusClient, backend.ClusterSelector)...)
+	}
 	// add queries for each custom workload
-	for _, w := range workloads {
+	for _, w := range sc.Workloads {
 		pair := strings.Split(w, ":")
-		if len(pair) != 2 {
-			term.Fatalf(err, "invalid workloads values provided '%v' - values must be namespace:name pairs", workloads)
-		}
 		if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: pair[0], Name: pair[1]}, &appsv1.Deployment{}); err != nil {
 			term.Fatalf(err, "invalid workload provided '%s'", w)
 		}
 		metricsInstance.AddQueries(
-			queries.QueryWorkloadCPUUsage(prometheusClient, pair[0], pair[1]),
-			queries.QueryWorkloadMemoryUsage(prometheusClient, pair[0], pair[1]),
+			queries.QueryWorkloadCPUUsage(prometheusClient, backend.ClusterSelector, pair[0], pair[1]),
+			queries.QueryWorkloadMemoryUsage(prometheusClient, backend.ClusterSelector, pair[0], pair[1]),
 		)
 	}
-
-	// redirect stdout and stderr to files due to issue with progress bars and client go logging for messages like
-	// I0619 11:12:22.620509   89316 request.go:601] Waited for 1.100053529s due to client-side throttling, not priority and fairness, request: POST:https://api.rajiv.devcluster.openshift.com:6443/apis/rbac.authorization.k8s.io/v1/namespaces/waffle4-0001-dev/rolebindings
-	tempStdout := os.Stdout
-	tempStderr := os.Stderr
-	stdOutFile, err := os.Create(cfg.StdOutFilepath())
-	if err != nil {
-		term.Fatalf(err, "failed creating stdout file: %s", cfg.StdOutFilepath())
+	// add the operator queries of each member cluster when the users are spread over several ones. The queries of the members only differ
+	// by the namespace of their operator, so only the members whose operator runs in the cluster of the prometheus instance can be queried
+	if members := userPlacement.Members(); len(members) > 1 {
+		queriedNamespaces := map[string]string{}
+		for _, m := range members {
+			if other, found := queriedNamespaces[m.Status.OperatorNamespace]; found {
+				term.Infof("⏭️  skipping the metrics of member cluster '%s', its operator namespace '%s' is the one of member cluster '%s'", m.Name, m.Status.OperatorNamespace, other)
+				continue
+			}
+			local, err := namespaceExists(cl, m.Status.OperatorNamespace)
+			if err != nil {
+				term.Fatalf(err, "unable to get the operator namespace of member cluster '%s'", m.Name)
+			}
+			if !local {
+				term.Infof("⏭️  skipping the metrics of member cluster '%s', its operator namespace '%s' is not in the cluster of the metrics backend", m.Name, m.Status.OperatorNamespace)
+				continue
+			}
+			queriedNamespaces[m.Status.OperatorNamespace] = m.Name
+			metricsInstance.AddQueries(
+				queries.QueryMemberOperatorCPUUsage(prometheusClient, backend.ClusterSelector, m.Name, m.Status.OperatorNamespace, cfg.MemberOperatorWorkload),
+				queries.QueryMemberOperatorMemoryUsage(prometheusClient, backend.ClusterSelector, m.Name, m.Status.OperatorNamespace, cfg.MemberOperatorWorkload),
+			)
+		}
+	}
+	// add the queries of the queries file
+	if sc.Queries != "" {
+		definitions, err := queries.LoadDefinitions(sc.Queries)
+		if err != nil {
+			term.Fatalf(err, "unable to load the queries file '%s'", sc.Queries)
+		}
+		if err := metricsInstance.AddDefinitions(prometheusClient, definitions); err != nil {
+			term.Fatalf(err, "invalid queries file '%s'", sc.Queries)
+		}
 	}
-	stdErrFile, err := os.Create(cfg.StdErrFilepath())
-	if err != nil {
-		term.Fatalf(err, "failed creating stderr file: %s", cfg.StdErrFilepath())
-	}
-	os.Stdout = stdOutFile
-	os.Stderr = stdErrFile
-	term.AddPreFatalExitHook(func() {
-		// restore stdout and stderr to originals
-		os.Stdout = tempStdout
-		os.Stderr = tempStderr
-	})
 
 	// start gathering metrics
-	stopMetrics := metricsInstance.StartGathering()
+	metricsInstance.StartGathering(ctx)
+
+	if !sc.SkipInstallOperators && !clusterPlatform.OLM {
+		term.Infof("⏭️  OLM is not installed, skipping the installation of the operators")
+	} else if !sc.SkipInstallOperators {
+		metricsInstance.StartSegment(OperatorsInstallSegment)
+		term.Infof("⏳ installing operators...")
+		// install operators for member clusters
+		templatePaths := []string{}
+		for i := 0; i < sc.OperatorsLimit; i++ {
+			templatePaths = append(templatePaths, "setup/operators/installtemplates/"+operators.Templates[i])
+		}
+		installs, err := operators.EnsureOperatorsInstalled(ctx, term, cl, scheme, templatePaths, sc.OperatorsParallelism)
+		if err != nil {
+			if ctx.Err() == nil {
+				term.Fatalf(err, "failed to ensure all operators are installed")
+			}
+			term.Errorf(err, "the installation of the operators was interrupted")
+		}
+		generalResultsInfo = append(generalResultsInfo, operators.InstallResults(installs)...)
+	}
+
+	// provision the users
+	term.Infof("🍿 provisioning users...")
+	metricsInstance.StartSegment(SignupsSegment)
 
 	// gather and write results
-	resultsWriter := results.New(term)
+	resultsWriter := results.New(term, outputFormats, thresholds)
 
+	// the time spent by each user is recorded per phase to report the tail latencies, not only the averages
+	latencies := latency.NewRecorder(string(checkpoint.SpaceReady), string(checkpoint.IdlerUpdated), string(checkpoint.DefaultTemplateApplied), string(checkpoint.CustomTemplateApplied))
+	// the churner is only set once the churn phase starts
+	var churner *churn.Churner
+	var lifecycleSimulator *lifecycle.Simulator
 	outputResults := func() {
-		addAndOutputResults(term, resultsWriter, func() [][]string { return generalResultsInfo }, metricsInstance.ComputeResults)
+		addAndOutputResults(term, resultsWriter, func() [][]string { return generalResultsInfo }, latencies.Results, registrationClient.Results, churner.Results, lifecycleSimulator.Results, metricsInstance.ComputeResults)
+		if metricsStep > 0 {
+			outputMetricsSeries(term, metricsInstance)
+		}
+		if err := latencies.WriteCSV(cfg.UserLatenciesFilepath()); err != nil {
+			term.Errorf(err, "failed to write the user latencies file")
+			return
+		}
+		term.Infof("User latencies file: %s", cfg.UserLatenciesFilepath())
 	}
 	// ensure metrics are dumped even if there's a fatal error
 	term.AddPreFatalExitHook(outputResults)
 
+	// the events are only written to the log file while the progress bars are displayed
+	unmuteConsole := term.MuteConsole()
 	uip := uiprogress.New()
 	uip.Start()
+	var stopOnce sync.Once
+	stopProgressBars := func() {
+		stopOnce.Do(func() {
+			uip.Stop()
+			unmuteConsole()
+		})
+	}
+	progressMu.Lock()
+	stopProgress = stopProgressBars
+	progressMu.Unlock()
 
 	// start the progress bars and work in go routines
 	var wg sync.WaitGroup
+	// the signups are waited for separately to start the template apply segment once all the users are signed up
+	var signupWg sync.WaitGroup
+
+	// the later phases of each user start once its space is ready, however the signups are spread over time by the arrival profile
+	spaceReady := newSpaceReadiness(allUsernames, cp)
 
 	concurrentUserSignups := 10
-	usersignupBar := addProgressBar(uip, "user signups", numberOfUsers)
-	signupUserFunc := func(cl client.Client, curUserNum int, username string) {
-		if err := users.Create(cl, username, cfg.HostOperatorNamespace, cfg.MemberOperatorNamespace); err != nil {
-			term.Fatalf(err, "failed to provision user '%s'", username)
+	usersignupBar := addProgressBar(uip, "user signups", len(allUsernames))
+	signupUserFunc := func(cl client.Client, curUserNum int, username string) bool {
+		if !cp.IsDone(username, checkpoint.Signup) {
+			if registrationClient != nil {
+				if err := registrationClient.Signup(username); err != nil {
+					term.Fatalf(err, "failed to sign up user '%s' through the registration service", username)
+				}
+				// the UserSignup created by the registration service is approved and placed like the ones created directly
+				if err := users.Approve(cl, username, cfg.HostOperatorNamespace, userPlacement.Next()); err != nil {
+					term.Fatalf(err, "failed to approve user '%s'", username)
+				}
+			} else if err := users.Create(cl, username, cfg.HostOperatorNamespace, userPlacement.Next()); err != nil {
+				term.Fatalf(err, "failed to provision user '%s'", username)
+			}
+			markDone(term, cp, username, checkpoint.Signup)
+		}
+		if registrationClient != nil {
+			if err := registrationClient.WaitUntilReady(username); err != nil {
+				term.Fatalf(err, "signup of user '%s' was not ready according to the registration service", username)
+			}
 		}
 
 		if err := wait.ForSpace(cl, username); err != nil {
 			term.Fatalf(err, "space '%s' was not ready or not found", username)
 		}
+
+		// move the space to the tier of the cohort if it's not the default one
+		if tier := cohortOf[username].SpaceTier; tier != "" && tier != cfg.UserSpaceTier {
+			if err := users.SetSpaceTier(cl, username, cfg.HostOperatorNamespace, tier); err != nil {
+				term.Fatalf(err, "failed to move space '%s' to tier '%s'", username, tier)
+			}
+			if err := wait.ForSpaceWithTier(cl, username, tier); err != nil {
+				term.Fatalf(err, "space '%s' was not ready in tier '%s'", username, tier)
+			}
+		}
+		markDone(term, cp, username, checkpoint.SpaceReady)
+		spaceReady.markReady(username)
+		return true
+	}
+	var signupStats arrival.Stats
+	if sc.SignupProfile != nil {
+		// the users are signed up according to the arrival profile instead of as fast as possible
+		signupWg.Add(1)
+		go func() {
+			defer signupWg.Done()
+			signupStats = arrivalRoutine(ctx, term, usersignupBar, allUsernames, cp, checkpoint.SpaceReady, latencies, *sc.SignupProfile, concurrentUserSignups, signupUserFunc)
+		}()
+	} else {
+		userSignupRoutine := userRoutine(ctx, term, usersignupBar, allUsernames, cp, checkpoint.SpaceReady, nil, latencies, signupUserFunc)
+		splitToMultipleRoutines(&signupWg, concurrentUserSignups, userSignupRoutine)
 	}
-	userSignupRoutine := userRoutine(term, usersignupBar, signupUserFunc)
-	splitToMultipleRoutines(&wg, concurrentUserSignups, userSignupRoutine)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		signupWg.Wait()
+		metricsInstance.StartSegment(TemplateApplySegment)
+	}()
 
 	var idlerBar *userProgressBar
-	if !skipIdlerSetup {
+	if !sc.SkipIdlerSetup {
 		concurrentIdlerSetups := 3
-		idlerBar = addProgressBar(uip, "idler setup", numberOfUsers)
-		updateIdlerFunc := func(cl client.Client, curUserNum int, username string) {
+		idlerBar = addProgressBar(uip, "idler setup", len(allUsernames))
+		updateIdlerFunc := func(cl client.Client, curUserNum int, username string) bool {
 			// update Idlers timeout to kill workloads faster to reduce impact of memory/cpu usage during testing
 			if err := idlers.UpdateTimeout(cl, username, idlerDuration); err != nil {
-				term.Fatalf(err, "failed to update idlers for user '%s'", username)
+				failUnlessInterrupted(ctx, term, err, "failed to update idlers for user '%s'", username)
+				return false
 			}
+			markDone(term, cp, username, checkpoint.IdlerUpdated)
+			return true
 		}
-		ur := userRoutine(term, idlerBar, updateIdlerFunc)
+		ur := userRoutine(ctx, term, idlerBar, allUsernames, cp, checkpoint.IdlerUpdated, spaceReady, latencies, updateIdlerFunc)
 		splitToMultipleRoutines(&wg, concurrentIdlerSetups, ur)
 	}
 
 	var defaultUserSetupBar *userProgressBar
 	concurrentUserSetups := 5
-	if defaultTemplateUsers > 0 {
-		defaultUserSetupBar = addProgressBar(uip, "setup default template users", defaultTemplateUsers)
-		setupDefaultUsersFunc := func(cl client.Client, curUserNum int, username string) {
-			if curUserNum <= defaultTemplateUsers {
-				if err := resources.CreateUserResourcesFromTemplateFiles(cmd.Context(), cl, scheme, username, []string{defaultTemplatePath}); err != nil {
-					term.Fatalf(err, "failed to create default template resources for user '%s'", username)
-				}
+	if len(defaultTemplateUsernames) > 0 {
+		defaultUserSetupBar = addProgressBar(uip, "setup default template users", len(defaultTemplateUsernames))
+		setupDefaultUsersFunc := func(cl client.Client, _ int, username string) bool {
+			if err := resources.CreateUserResourcesFromTemplateFiles(ctx, cl, scheme, username, []string{defaultTemplatePath}, nil, servedOnly); err != nil {
+				failUnlessInterrupted(ctx, term, err, "failed to create default template resources for user '%s'", username)
+				return false
 			}
+			markDone(term, cp, username, checkpoint.DefaultTemplateApplied)
+			return true
 		}
-		ur := userRoutine(term, defaultUserSetupBar, setupDefaultUsersFunc)
+		ur := userRoutine(ctx, term, defaultUserSetupBar, defaultTemplateUsernames, cp, checkpoint.DefaultTemplateApplied, spaceReady, latencies, setupDefaultUsersFunc)
 		splitToMultipleRoutines(&wg, concurrentUserSetups, ur)
 	}
 
 	var customUserSetupBar *userProgressBar
-	if customTemplateUsers > 0 && len(customTemplatePaths) > 0 {
-		customUserSetupBar = addProgressBar(uip, "setup custom template users", customTemplateUsers)
-		setupCustomUsersFunc := func(cl client.Client, curUserNum int, username string) {
-			if curUserNum <= customTemplateUsers {
-				if err := resources.CreateUserResourcesFromTemplateFiles(cmd.Context(), cl, scheme, username, customTemplatePaths); err != nil {
-					term.Fatalf(err, "failed to create custom template resources for user '%s'", username)
-				}
+	if len(customTemplateUsernames) > 0 {
+		customUserSetupBar = addProgressBar(uip, "setup custom template users", len(customTemplateUsernames))
+		setupCustomUsersFunc := func(cl client.Client, _ int, username string) bool {
+			if err := resources.CreateUserResourcesFromTemplateFiles(ctx, cl, scheme, username, cohortOf[username].Templates, paramsOf(username), servedOnly); err != nil {
+				failUnlessInterrupted(ctx, term, err, "failed to create custom template resources for user '%s'", username)
+				return false
 			}
+			markDone(term, cp, username, checkpoint.CustomTemplateApplied)
+			return true
 		}
-		ur := userRoutine(term, customUserSetupBar, setupCustomUsersFunc)
+		ur := userRoutine(ctx, term, customUserSetupBar, customTemplateUsernames, cp, checkpoint.CustomTemplateApplied, spaceReady, latencies, setupCustomUsersFunc)
 		splitToMultipleRoutines(&wg, concurrentUserSetups, ur)
 	}
 
-	defer close(stopMetrics)
 	wg.Wait()
-	uip.Stop()
-
-	// restore stdout and stderr to originals
-	os.Stdout = tempStdout
-	os.Stderr = tempStderr
+	stopProgressBars()
 
 	term.Infof("🏁 done provisioning users")
 
+	// keep creating, scaling and deleting resources in the namespaces of some users to see how the cluster copes with ongoing changes
+	var churnStats arrival.Stats
+	if sc.Churn != nil && ctx.Err() == nil {
+		churnUsernames := usernamesWithTemplates(allUsernames, defaultTemplateUsernames, customTemplateUsernames)
+		if len(churnUsernames) < sc.Churn.Users {
+			term.Fatalf(fmt.Errorf("only %d users have templates applied", len(churnUsernames)), "unable to pick %d churn users", sc.Churn.Users)
+		}
+		templatesOf := func(username string) []string {
+			var paths []string
+			if slices.Contains(defaultTemplateUsernames, username) {
+				paths = append(paths, defaultTemplatePath)
+			}
+			if slices.Contains(customTemplateUsernames, username) {
+				paths = append(paths, cohortOf[username].Templates...)
+			}
+			return paths
+		}
+		c, err := churn.New(cl, scheme, *sc.Churn, churnUsernames[:sc.Churn.Users], templatesOf, paramsOf, servedOnly)
+		if err != nil {
+			term.Fatalf(err, "unable to prepare the churn")
+		}
+		churner = c
+		metricsInstance.StartSegment(ChurnSegment)
+		term.Infof("🔁 churning for %s...", sc.Churn.Duration.Duration)
+		churnStats = churner.Run(ctx)
+		term.Infof("🏁 done churning")
+	}
+
+	// deactivate, reactivate and ban some users to load the deprovisioning path as well
+	var lifecycleStats []arrival.Stats
+	if len(sc.Lifecycle) > 0 && ctx.Err() == nil {
+		lifecycleSimulator = lifecycle.New(cl, cfg.HostOperatorNamespace, allUsernames, cp)
+		metricsInstance.StartSegment(LifecycleSegment)
+		for _, step := range sc.Lifecycle {
+			if ctx.Err() != nil {
+				break
+			}
+			term.Infof("♻️  %s...", step)
+			lifecycleStats = append(lifecycleStats, lifecycleSimulator.Run(ctx, step))
+		}
+		term.Infof("🏁 done with the lifecycle steps")
+	}
+
 	// continue gathering metrics for some time after creating all users and resources since memory usage was observed to continue changing
-	if !skipAdditionalWait {
+	if !sc.SkipAdditionalWait && ctx.Err() == nil {
+		metricsInstance.StartSegment(SettleSegment)
 		additionalMetricsDuration := 15 * time.Minute
 		term.Infof("Continuing to gather metrics for %s...", additionalMetricsDuration)
-		time.Sleep(additionalMetricsDuration)
+		select {
+		case <-time.After(additionalMetricsDuration):
+		case <-ctx.Done():
+		}
 	}
 
 	// =====================
 	// end of setup
 	// =====================
 
+	// the run is only reported as interrupted when it was stopped by a signal, not when the whole command was cancelled
+	interrupted = ctx.Err() != nil && cmd.Context().Err() == nil
 	totalRunningTime := time.Since(setupStartTime)
-	if idlerBar != nil {
-		IdlerUpdateTime = idlerBar.timeSpent
-	}
-	if defaultUserSetupBar != nil {
-		DefaultApplyTimePerUser = defaultUserSetupBar.timeSpent
-	}
-	if customUserSetupBar != nil {
-		CustomApplyTimePerUser = customUserSetupBar.timeSpent
-	}
+	// the averages only take into account the users that were processed during this run, users completed by a previous run are skipped when resuming
+	IdlerUpdateTime = idlerBar.averageTimeSpent()
+	DefaultApplyTimePerUser = defaultUserSetupBar.averageTimeSpent()
+	CustomApplyTimePerUser = customUserSetupBar.averageTimeSpent()
 
 	generalResultsInfo = append(generalResultsInfo,
-		[]string{"Average Idler Update Time (s)", fmt.Sprintf("%.2f", IdlerUpdateTime.Seconds()/float64(numberOfUsers))},
-		[]string{"Average Time Per User - default (s)", fmt.Sprintf("%.2f", DefaultApplyTimePerUser.Seconds()/float64(numberOfUsers))},
-		[]string{"Average Time Per User - custom (s)", fmt.Sprintf("%.2f", CustomApplyTimePerUser.Seconds()/float64(numberOfUsers))},
+		[]string{"Average Idler Update Time (s)", fmt.Sprintf("%.2f", IdlerUpdateTime.Seconds())},
+		[]string{"Average Time Per User - default (s)", fmt.Sprintf("%.2f", DefaultApplyTimePerUser.Seconds())},
+		[]string{"Average Time Per User - custom (s)", fmt.Sprintf("%.2f", CustomApplyTimePerUser.Seconds())},
 		[]string{"Total Running Time (m)", fmt.Sprintf("%f", totalRunningTime.Minutes())},
 	)
+	if sc.SignupProfile != nil {
+		generalResultsInfo = append(generalResultsInfo,
+			[]string{"Signup Profile", sc.SignupProfile.String()},
+			[]string{"Target Signup Rate (signups/s)", fmt.Sprintf("%.2f", signupStats.TargetRate())},
+			[]string{"Achieved Signup Rate (signups/s)", fmt.Sprintf("%.2f", signupStats.AchievedRate())},
+			[]string{"Max Signup Arrival Lag (s)", fmt.Sprintf("%.2f", signupStats.MaxLag.Seconds())},
+		)
+	}
+
+	if churner != nil {
+		generalResultsInfo = append(generalResultsInfo,
+			[]string{"Churn Profile", sc.Churn.String()},
+			[]string{"Target Churn Rate (operations/s)", fmt.Sprintf("%.2f", churnStats.TargetRate())},
+			[]string{"Achieved Churn Rate (operations/s)", fmt.Sprintf("%.2f", churnStats.AchievedRate())},
+		)
+	}
+
+	for i := range lifecycleStats {
+		step := sc.Lifecycle[i]
+		generalResultsInfo = append(generalResultsInfo,
+			[]string{fmt.Sprintf("Lifecycle Step %d", i+1), step.String()},
+			[]string{fmt.Sprintf("Achieved Lifecycle Rate - step %d (users/s)", i+1), fmt.Sprintf("%.2f", lifecycleStats[i].AchievedRate())},
+		)
+	}
+
+	// the users are counted per member cluster where their spaces were actually provisioned
+	usersPerMember, err := users.CountPerMember(cl, cfg.HostOperatorNamespace, allUsernames)
+	if err != nil {
+		term.Errorf(err, "failed to count the users per member cluster")
+	}
+	var countedMembers []string
+	for name := range usersPerMember {
+		countedMembers = append(countedMembers, name)
+	}
+	sort.Strings(countedMembers)
+	for _, name := range countedMembers {
+		generalResultsInfo = append(generalResultsInfo, []string{fmt.Sprintf("Number of Users - %s", name), strconv.Itoa(usersPerMember[name])})
+	}
+
+	// the number of users that reached each phase tells how far an interrupted run went
+	generalResultsInfo = append(generalResultsInfo, []string{"Interrupted", strconv.FormatBool(interrupted)})
+	for _, phase := range checkpoint.Phases {
+		generalResultsInfo = append(generalResultsInfo, []string{fmt.Sprintf("Users Reached Phase - %s", phase), strconv.Itoa(cp.CountDone(phase))})
+	}
 
 	outputResults()
+	if interrupted {
+		term.Infof("🛑 the run was interrupted, run the setup again with the same flags and the '--resume' flag to complete it")
+		return
+	}
 	term.Infof("👋 have fun!")
 }
 
-func usersWithinBounds(term terminal.Terminal, value int, templateType string) {
-	if value < 0 || value > numberOfUsers {
-		term.Fatalf(fmt.Errorf("value must be between 0 and %d", numberOfUsers), "invalid '%s' users value '%d'", templateType, value)
+// loadScenario returns the scenario of the run: the one defined by the flags, overridden by the scenario file if one was provided
+func loadScenario(term terminal.Terminal) scenario.Scenario {
+	sc := scenario.Scenario{
+		UsernamePrefix:       usernamePrefix,
+		Users:                numberOfUsers,
+		DefaultTemplateUsers: defaultTemplateUsers,
+		CustomTemplateUsers:  customTemplateUsers,
+		Templates:            customTemplatePaths,
+		TemplateParams:       templateParams,
+		TemplateParamsSeed:   templateParamsSeed,
+		IdlerTimeout:         idlerTimeout,
+		OperatorsLimit:       operatorsLimit,
+		OperatorsParallelism: operatorsParallelism,
+		Workloads:            workloads,
+		Queries:              queriesPath,
+		SkipAdditionalWait:   skipAdditionalWait,
+		SkipIdlerSetup:       skipIdlerSetup,
+		SkipInstallOperators: skipInstallOperators,
+		Placement:            users.PlacementStrategy(placement),
+		MemberWeights:        memberWeights,
+		SignupMethod:         signupMethod,
+	}
+	if signupProfile != "" {
+		sc.SignupProfile = &arrival.Profile{
+			Type:          arrival.Type(signupProfile),
+			Rate:          signupRate,
+			RateStep:      signupRateStep,
+			StepDuration:  metav1.Duration{Duration: signupStepDuration},
+			BurstSize:     signupBurstSize,
+			BurstInterval: metav1.Duration{Duration: signupBurstInterval},
+			Duration:      metav1.Duration{Duration: signupDuration},
+			Seed:          signupSeed,
+			MaxInFlight:   signupMaxInFlight,
+		}
+	}
+	if churnDuration > 0 {
+		sc.Churn = &churn.Profile{
+			Duration:    metav1.Duration{Duration: churnDuration},
+			Rate:        churnRate,
+			Users:       churnUsers,
+			Seed:        churnSeed,
+			MaxInFlight: churnMaxInFlight,
+		}
+	}
+	for _, value := range lifecycleSteps {
+		step, err := lifecycle.ParseStep(value)
+		if err != nil {
+			term.Fatalf(err, "invalid lifecycle value")
+		}
+		step.MaxInFlight = lifecycleMaxInFlight
+		sc.Lifecycle = append(sc.Lifecycle, step)
+	}
+	if scenarioPath != "" {
+		var err error
+		if sc, err = scenario.Load(scenarioPath, sc); err != nil {
+			term.Fatalf(err, "unable to load the scenario file '%s'", scenarioPath)
+		}
+	}
+	sc.Resolve()
+	return sc
+}
+
+// initLog writes the events of the run to the log file next to the results file, along with the client-go logs such as
+// "Waited for 1.1s due to client-side throttling" which would otherwise be printed in the middle of the progress bars
+func initLog(term terminal.Terminal) {
+	if err := term.SetLogFile(cfg.LogFilepath()); err != nil {
+		term.Fatalf(err, "failed creating the log file: %s", cfg.LogFilepath())
+	}
+	flags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	for name, value := range map[string]string{"logtostderr": "false", "one_output": "true"} {
+		if err := flags.Set(name, value); err != nil {
+			term.Fatalf(err, "failed configuring the client-go logs")
+		}
 	}
+	klog.SetOutput(terminal.NewLogWriter(term, "client-go"))
+}
+
+// namespaceExists returns true if the namespace with the given name exists in the cluster of the given client
+func namespaceExists(cl client.Client, name string) (bool, error) {
+	if err := cl.Get(context.TODO(), types.NamespacedName{Name: name}, &corev1.Namespace{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// usernamesWithTemplates returns the given users that have the default or custom templates applied, in the same order
+func usernamesWithTemplates(usernames, defaultTemplateUsernames, customTemplateUsernames []string) []string {
+	var result []string
+	for _, username := range usernames {
+		if slices.Contains(defaultTemplateUsernames, username) || slices.Contains(customTemplateUsernames, username) {
+			result = append(result, username)
+		}
+	}
+	return result
+}
+
+// outputPlan reports the objects that the templates would create for the users of the scenario, without mutating the cluster.
+// It fails when a dry-run apply fails or when an API of the objects is not served by the cluster, unless the objects of the missing APIs are skipped.
+func outputPlan(ctx context.Context, term terminal.Terminal, cl client.Client, s *runtime.Scheme, sc scenario.Scenario, defaultTemplatePath string, defaultTemplateUsernames []string,
+	paramsOf func(string) map[string]string, servedOnly bool) {
+	var usages []plan.Usage
+	if len(defaultTemplateUsernames) > 0 {
+		usages = append(usages, plan.Usage{Template: defaultTemplatePath, Username: defaultTemplateUsernames[0], Users: len(defaultTemplateUsernames)})
+	}
+	for _, c := range sc.Cohorts {
+		if c.CustomTemplateUsers == 0 {
+			continue
+		}
+		for _, t := range c.Templates {
+			usages = append(usages, plan.Usage{Template: t, Username: c.Username(1), Users: c.CustomTemplateUsers, Params: paramsOf(c.Username(1))})
+		}
+	}
+	term.Infof("🔎 planning the setup with server-side dry-run calls...")
+	p, err := plan.Make(ctx, term, cl, s, usages)
+	if err != nil {
+		term.Fatalf(err, "unable to plan the setup")
+	}
+	for _, r := range p.Results() {
+		term.Infof("%s: %s", r[0], r[1])
+	}
+	for _, err := range p.Errors {
+		term.Errorf(err, "the dry-run apply failed")
+	}
+	if len(p.MissingAPIs) > 0 && servedOnly {
+		term.Infof("the objects of the %d missing APIs are skipped on this platform", len(p.MissingAPIs))
+	}
+	if (len(p.MissingAPIs) > 0 && !servedOnly) || len(p.Errors) > 0 {
+		term.Fatalf(fmt.Errorf("%d missing APIs and %d dry-run errors", len(p.MissingAPIs), len(p.Errors)), "the setup would fail, nothing was created")
+	}
+	term.Infof("✅ the setup can be run, nothing was created")
+}
+
+// outputMetricsSeries captures the metrics series over the whole run and writes them next to the results file
+func outputMetricsSeries(term terminal.Terminal, g *metrics.Gatherer) {
+	if err := g.CaptureSeries(metricsStep); err != nil {
+		// the series of the other queries are still written
+		term.Errorf(err, "failed to capture some metrics series")
+	}
+	csvPath, jsonPath := cfg.MetricsSeriesFilepath("csv"), cfg.MetricsSeriesFilepath("json")
+	if err := g.WriteSeries(csvPath, jsonPath); err != nil {
+		term.Errorf(err, "failed to write the metrics series")
+		return
+	}
+	term.Infof("Metrics series files: %s, %s", csvPath, jsonPath)
+}
+
+// loadResultsSettings checks the output formats of the results and returns the thresholds of the results, if any
+func loadResultsSettings(term terminal.Terminal) results.Thresholds {
+	if err := results.ValidateFormats(outputFormats); err != nil {
+		term.Fatalf(err, "invalid output-format value")
+	}
+	if thresholdsPath == "" {
+		return nil
+	}
+	thresholds, err := results.LoadThresholds(thresholdsPath)
+	if err != nil {
+		term.Fatalf(err, "unable to load the thresholds file '%s'", thresholdsPath)
+	}
+	return thresholds
 }
 
 func addAndOutputResults(term terminal.Terminal, resultsWriter *results.Results, r ...func() [][]string) {
@@ -400,6 +1010,7 @@ func addAndOutputResults(term terminal.Terminal, resultsWriter *results.Results,
 type userProgressBar struct {
 	mu        sync.Mutex
 	timeSpent time.Duration
+	processed int
 	bar       *uiprogress.Bar
 }
 
@@ -422,9 +1033,23 @@ func (b *userProgressBar) Incr() (bool, int) {
 func (b *userProgressBar) AddTimeSpent(d time.Duration) {
 	b.mu.Lock()
 	b.timeSpent += d
+	b.processed++
 	b.mu.Unlock()
 }
 
+// averageTimeSpent returns the average time spent per user processed by this progress bar, or 0 if the bar is nil or no user was processed
+func (b *userProgressBar) averageTimeSpent() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.processed == 0 {
+		return 0
+	}
+	return b.timeSpent / time.Duration(b.processed)
+}
+
 func splitToMultipleRoutines(parent *sync.WaitGroup, concurrentRoutinesCount int, routine func(*sync.WaitGroup)) {
 	parent.Add(1)
 	go func() {
@@ -438,7 +1063,11 @@ func splitToMultipleRoutines(parent *sync.WaitGroup, concurrentRoutinesCount int
 	}()
 }
 
-func userRoutine(term terminal.Terminal, progressBar *userProgressBar, ua userAction) func(wg *sync.WaitGroup) {
+// userRoutine returns a routine that performs the given action for each of the given users, until the context is done. When a checkpoint is provided,
+// the users that have already completed the given phase or that were deactivated or banned are skipped. When a space readiness is provided, the action of each user waits until its space is ready.
+// When a recorder is provided, the time spent by each user whose action succeeded is recorded for the phase.
+func userRoutine(ctx context.Context, term terminal.Terminal, progressBar *userProgressBar, usernames []string, cp *checkpoint.Checkpoint, phase checkpoint.Phase,
+	ready spaceReadiness, latencies *latency.Recorder, ua userAction) func(wg *sync.WaitGroup) {
 	return func(subgroup *sync.WaitGroup) {
 		aCl, _, _, err := cfg.NewClient(term, kubeconfig)
 		if err != nil {
@@ -446,19 +1075,117 @@ func userRoutine(term terminal.Terminal, progressBar *userProgressBar, ua userAc
 		}
 
 		hasMore, curUserNum := progressBar.Incr()
-		for hasMore {
-			username := fmt.Sprintf("%s-%04d", usernamePrefix, curUserNum)
+		for hasMore && ctx.Err() == nil {
+			username := usernames[curUserNum-1]
+			if cp != nil && (cp.IsDone(username, phase) || cp.IsDeprovisioned(username)) {
+				hasMore, curUserNum = progressBar.Incr()
+				continue
+			}
 
+			// the time spent waiting for the space of the user is not part of the latency of the phase
+			if !ready.wait(ctx, username) {
+				break
+			}
 			startTime := time.Now()
 
-			ua(aCl, curUserNum, username)
+			// the failed and interrupted actions are not part of the time spent
+			if ua(aCl, curUserNum, username) {
+				timeSpent := time.Since(startTime)
+				progressBar.AddTimeSpent(timeSpent)
+				latencies.Record(string(phase), username, timeSpent)
+			}
+			hasMore, curUserNum = progressBar.Incr()
+		}
+		subgroup.Done()
+	}
+}
 
+// arrivalRoutine performs the given action for each of the given users at the arrival times of the given profile and blocks until all
+// the started actions are done, no more action is started once the context is done. The users that have already completed the given phase or that were deactivated or banned are skipped and are not part of the schedule.
+// The actions share a pool of clients of the given size.
+func arrivalRoutine(ctx context.Context, term terminal.Terminal, progressBar *userProgressBar, usernames []string, cp *checkpoint.Checkpoint, phase checkpoint.Phase,
+	latencies *latency.Recorder, profile arrival.Profile, clientsCount int, ua userAction) arrival.Stats {
+	clients := make([]client.Client, clientsCount)
+	for i := range clients {
+		aCl, _, _, err := cfg.NewClient(term, kubeconfig)
+		if err != nil {
+			term.Fatalf(err, "cannot create client")
+		}
+		clients[i] = aCl
+	}
+
+	var pending []int
+	for i, username := range usernames {
+		if cp.IsDone(username, phase) || cp.IsDeprovisioned(username) {
+			progressBar.Incr()
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	return arrival.Run(ctx, profile.Schedule(len(pending)), profile.MaxInFlight, func(i int) {
+		curUserNum := pending[i] + 1
+		startTime := time.Now()
+
+		username := usernames[curUserNum-1]
+
+		if ua(clients[i%len(clients)], curUserNum, username) {
 			timeSpent := time.Since(startTime)
 			progressBar.AddTimeSpent(timeSpent)
-			hasMore, curUserNum = progressBar.Incr()
+			latencies.Record(string(phase), username, timeSpent)
+		}
+		progressBar.Incr()
+	})
+}
+
+// userAction performs the work of a phase for the given user and returns true when it's done, or false when it failed or was interrupted
+type userAction func(cl client.Client, curUserNum int, username string) bool
+
+// spaceReadiness tells when the space of each user is ready, a nil spaceReadiness considers all the spaces ready
+type spaceReadiness map[string]chan struct{}
+
+// newSpaceReadiness returns the readiness of the spaces of the given users, the spaces already ready according to the checkpoint are ready right away
+func newSpaceReadiness(usernames []string, cp *checkpoint.Checkpoint) spaceReadiness {
+	r := make(spaceReadiness, len(usernames))
+	for _, username := range usernames {
+		r[username] = make(chan struct{})
+		if cp.IsDone(username, checkpoint.SpaceReady) {
+			close(r[username])
 		}
-		subgroup.Done()
 	}
+	return r
+}
+
+// markReady unblocks the actions waiting for the space of the given user
+func (r spaceReadiness) markReady(username string) {
+	close(r[username])
 }
 
-type userAction func(cl client.Client, curUserNum int, username string)
+// wait blocks until the space of the given user is ready and returns true, or returns false if the context is done first
+func (r spaceReadiness) wait(ctx context.Context, username string) bool {
+	if r == nil {
+		return true
+	}
+	select {
+	case <-r[username]:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// failUnlessInterrupted exits with the given error unless the run was interrupted, in which case the phase of the user is left
+// incomplete so that it is done again when the run is resumed
+func failUnlessInterrupted(ctx context.Context, term terminal.Terminal, err error, msg string, args ...interface{}) {
+	if ctx.Err() != nil {
+		term.Debugw("the work of the user was interrupted", "error", err)
+		return
+	}
+	term.Fatalf(err, msg, args...)
+}
+
+func markDone(term terminal.Terminal, cp *checkpoint.Checkpoint, username string, phase checkpoint.Phase) {
+	if err := cp.MarkDone(username, phase); err != nil {
+		term.Fatalf(err, "failed to record phase '%s' of user '%s' in the checkpoint file '%s'", phase, username, cp.Path())
+	}
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/operators"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/platform"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/results"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/users"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/gosuri/uiprogress"
+	"github.com/spf13/cobra"
+)
+
+var (
+	uninstallOperators         bool
+	concurrentDeletions        int
+	concurrentDeprovisionWaits int
+)
+
+func newTeardownCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "teardown",
+		Short:         "deprovision the user accounts created by the setup command",
+		SilenceErrors: true,
+		SilenceUsage:  false,
+		Args:          cobra.NoArgs,
+		Run:           teardown,
+	}
+
+	cmd.Flags().BoolVar(&uninstallOperators, "uninstall-operators", false, "if the operators installed by the setup command should be uninstalled as well")
+	cmd.Flags().IntVar(&operatorsLimit, "operators-limit", len(operators.Templates), "can be specified to limit the number of operators to uninstall")
+	cmd.Flags().IntVar(&concurrentDeletions, "concurrent-deletions", 10, "how many usersignups are deleted concurrently")
+	cmd.Flags().IntVar(&concurrentDeprovisionWaits, "concurrent-waits", 10, "how many users are concurrently waited for until their resources are deprovisioned")
+	return cmd
+}
+
+func teardown(cmd *cobra.Command, _ []string) {
+	cmd.SilenceUsage = true
+	term := terminal.New(cmd.InOrStdin, cmd.OutOrStdout, verbose)
+
+	// the results of the teardown are written to a separate file than the ones of the setup
+	cfg.Testname += "-teardown"
+	cfg.Init(term)
+	initLog(term)
+
+	thresholds := loadResultsSettings(term)
+	if concurrentDeletions < 1 || concurrentDeprovisionWaits < 1 {
+		term.Fatalf(fmt.Errorf("value must be more than 0"), "invalid concurrency values '%d' and '%d'", concurrentDeletions, concurrentDeprovisionWaits)
+	}
+	if operatorsLimit > len(operators.Templates) {
+		term.Fatalf(fmt.Errorf("the operators limit value must be less than or equal to '%d'", len(operators.Templates)), "invalid operators limit value '%d'", operatorsLimit)
+	}
+
+	term.Infof("🕖 initializing...\n")
+	cl, config, scheme, err := cfg.NewClient(term, kubeconfig)
+	if err != nil {
+		term.Fatalf(err, "cannot create client")
+	}
+	clusterPlatform, err := platform.Detect(cl)
+	if err != nil {
+		term.Fatalf(err, "unable to detect the platform of the cluster")
+	}
+
+	// the users of all the cohorts are deprovisioned when a scenario file is provided
+	var prefixes []string
+	for _, c := range loadScenario(term).Cohorts {
+		prefixes = append(prefixes, c.UsernamePrefix)
+	}
+	var usernames, bannedUsernames []string
+	for _, prefix := range prefixes {
+		names, err := users.ListNames(cl, cfg.HostOperatorNamespace, prefix)
+		if err != nil {
+			term.Fatalf(err, "unable to list the usersignups with prefix '%s'", prefix)
+		}
+		usernames = append(usernames, names...)
+		// the users banned by the lifecycle steps would be rejected by a later run with the same prefix
+		names, err = users.ListBannedNames(cl, cfg.HostOperatorNamespace, prefix)
+		if err != nil {
+			term.Fatalf(err, "unable to list the bannedusers with prefix '%s'", prefix)
+		}
+		bannedUsernames = append(bannedUsernames, names...)
+	}
+	if len(usernames) == 0 && len(bannedUsernames) == 0 && !uninstallOperators {
+		term.Infof("no usersignups found with prefixes %v, nothing to do", prefixes)
+		return
+	}
+
+	if interactive && !term.PromptBoolf("🗑  deprovision %d users and unban %d users with prefixes %v on %s (uninstall operators: %t)", len(usernames), len(bannedUsernames), prefixes, config.Host, uninstallOperators) {
+		return
+	}
+
+	generalResultsInfo := [][]string{
+		{"Number of Deprovisioned Users", strconv.Itoa(len(usernames))},
+	}
+	resultsWriter := results.New(term, outputFormats, thresholds)
+	outputResults := func() {
+		addAndOutputResults(term, resultsWriter, func() [][]string { return generalResultsInfo })
+	}
+	// ensure the timings collected so far are dumped even if there's a fatal error
+	term.AddPreFatalExitHook(outputResults)
+
+	teardownStartTime := time.Now()
+
+	if len(usernames) > 0 {
+		// the NSTemplateSets are in the member operator namespace of the member cluster the users were placed on, which is only known
+		// until the spaces are deleted. Only the members whose operator runs in this cluster can be waited for.
+		memberOperatorNamespaces, err := users.MemberOperatorNamespaces(cl, cfg.HostOperatorNamespace, usernames)
+		if err != nil {
+			term.Fatalf(err, "unable to get the member clusters of the users")
+		}
+		localNamespaces := map[string]bool{}
+		for _, namespace := range memberOperatorNamespaces {
+			if _, checked := localNamespaces[namespace]; checked {
+				continue
+			}
+			local, err := namespaceExists(cl, namespace)
+			if err != nil {
+				term.Fatalf(err, "unable to get the member operator namespace '%s'", namespace)
+			}
+			localNamespaces[namespace] = local
+			if !local {
+				term.Infof("⏭️  the member operator namespace '%s' is not in this cluster, the resources of its users are not waited for", namespace)
+			}
+		}
+
+		term.Infof("🔥 deprovisioning users...")
+		uip := uiprogress.New()
+		uip.Start()
+
+		var wg sync.WaitGroup
+		deletionBar := addProgressBar(uip, "usersignup deletions", len(usernames))
+		deleteUserFunc := func(cl client.Client, _ int, username string) bool {
+			if err := users.Delete(cl, username, cfg.HostOperatorNamespace); err != nil {
+				term.Fatalf(err, "failed to delete usersignup '%s'", username)
+			}
+			return true
+		}
+		splitToMultipleRoutines(&wg, concurrentDeletions, userRoutine(cmd.Context(), term, deletionBar, usernames, nil, "", nil, nil, deleteUserFunc))
+
+		deprovisionBar := addProgressBar(uip, "deprovisioned users", len(usernames))
+		waitForDeprovisionFunc := func(cl client.Client, _ int, username string) bool {
+			if err := wait.ForSpaceDeletion(cl, username); err != nil {
+				term.Fatalf(err, "failed to deprovision user '%s'", username)
+			}
+			// the users whose space was not provisioned have no NSTemplateSet, the default member operator namespace is then checked
+			memberOperatorNamespace, found := memberOperatorNamespaces[username]
+			if !found {
+				memberOperatorNamespace = cfg.MemberOperatorNamespace
+			} else if !localNamespaces[memberOperatorNamespace] {
+				return true
+			}
+			if err := wait.ForNSTemplateSetDeletion(cl, memberOperatorNamespace, username); err != nil {
+				term.Fatalf(err, "failed to deprovision user '%s'", username)
+			}
+			if err := wait.ForNamespaceDeletion(cl, fmt.Sprintf("%s-dev", username)); err != nil {
+				term.Fatalf(err, "failed to deprovision user '%s'", username)
+			}
+			return true
+		}
+		splitToMultipleRoutines(&wg, concurrentDeprovisionWaits, userRoutine(cmd.Context(), term, deprovisionBar, usernames, nil, "", nil, nil, waitForDeprovisionFunc))
+
+		wg.Wait()
+		uip.Stop()
+
+		generalResultsInfo = append(generalResultsInfo,
+			[]string{"Average UserSignup Deletion Time (s)", fmt.Sprintf("%.2f", deletionBar.averageTimeSpent().Seconds())},
+			[]string{"Average Deprovisioning Time Per User (s)", fmt.Sprintf("%.2f", deprovisionBar.averageTimeSpent().Seconds())},
+			[]string{"Users Deprovisioning Time (m)", fmt.Sprintf("%f", time.Since(teardownStartTime).Minutes())},
+		)
+		term.Infof("🏁 done deprovisioning users")
+	}
+
+	if len(bannedUsernames) > 0 {
+		for _, username := range bannedUsernames {
+			if err := users.Unban(cl, username, cfg.HostOperatorNamespace); err != nil {
+				term.Fatalf(err, "failed to delete the banneduser '%s'", username)
+			}
+		}
+		term.Infof("🏁 done deleting %d bannedusers", len(bannedUsernames))
+	}
+
+	// the operators can only have been installed with OLM
+	if uninstallOperators && !clusterPlatform.OLM {
+		term.Infof("⏭️  OLM is not installed, skipping the uninstallation of the operators")
+	} else if uninstallOperators {
+		term.Infof("⏳ uninstalling operators...")
+		operatorsStartTime := time.Now()
+		templatePaths := []string{}
+		for i := 0; i < operatorsLimit; i++ {
+			templatePaths = append(templatePaths, "setup/operators/installtemplates/"+operators.Templates[i])
+		}
+		if err := operators.EnsureOperatorsUninstalled(cmd.Context(), cl, scheme, templatePaths); err != nil {
+			term.Fatalf(err, "failed to uninstall the operators")
+		}
+		generalResultsInfo = append(generalResultsInfo,
+			[]string{"Number of Uninstalled Operators", strconv.Itoa(len(templatePaths))},
+			[]string{"Operators Uninstall Time (s)", fmt.Sprintf("%.2f", time.Since(operatorsStartTime).Seconds())},
+		)
+	}
+
+	generalResultsInfo = append(generalResultsInfo,
+		[]string{"Total Teardown Time (m)", fmt.Sprintf("%f", time.Since(teardownStartTime).Minutes())},
+	)
+	outputResults()
+	term.Infof("👋 all clean!")
+}
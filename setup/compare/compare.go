@@ -0,0 +1,151 @@
+package compare
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/results"
+
+	"github.com/ghodss/yaml"
+)
+
+// the directions of the deltas that are checked against a tolerance
+const (
+	Both     = ""
+	Increase = "increase"
+	Decrease = "decrease"
+)
+
+// Tolerance is the accepted delta of a result item compared to the baseline. The delta exceeds the tolerance when it's
+// above any of the limits that are set. When a direction is set, only the deltas in that direction are checked.
+type Tolerance struct {
+	// Absolute is the accepted absolute delta, in the unit of the result item
+	Absolute *float64 `json:"absolute,omitempty"`
+	// Relative is the accepted relative delta, in percent of the baseline value
+	Relative *float64 `json:"relative,omitempty"`
+	// Direction is either 'increase' or 'decrease', deltas in both directions are checked when not set
+	Direction string `json:"direction,omitempty"`
+}
+
+// Tolerances are the tolerances of the result items, by result item name
+type Tolerances map[string]Tolerance
+
+// LoadTolerances reads the tolerances from the YAML file at the given path, eg.
+//
+//	"Max host-operator-controller-manager Memory Usage (MB)":
+//	  relative: 10
+//	  direction: increase
+func LoadTolerances(path string) (Tolerances, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tolerances := Tolerances{}
+	if err := yaml.Unmarshal(content, &tolerances); err != nil {
+		return nil, fmt.Errorf("invalid tolerances file '%s': %w", path, err)
+	}
+	for name, t := range tolerances {
+		if t.Direction != Both && t.Direction != Increase && t.Direction != Decrease {
+			return nil, fmt.Errorf("invalid tolerances file '%s': invalid direction '%s' of '%s': must be either '%s' or '%s'", path, t.Direction, name, Increase, Decrease)
+		}
+	}
+	return tolerances, nil
+}
+
+// Delta is the difference of a numeric result item between a results file and the baseline
+type Delta struct {
+	Name     string
+	Baseline float64
+	Value    float64
+	// Absolute is the value minus the baseline value
+	Absolute float64
+	// Relative is the absolute delta in percent of the baseline value, it's infinite when the baseline value is 0 and the value is not
+	Relative float64
+	// Missing is set when the result item is in only one of the two files
+	Missing bool
+	// Exceeded describes why the delta exceeds its tolerance, it's empty if it doesn't
+	Exceeded string
+}
+
+// Compare returns the deltas of the numeric result items of the given results compared to the baseline results, in the order
+// of the baseline results followed by the items that are not in the baseline
+func Compare(baseline, other []results.Entry, tolerances Tolerances) []Delta {
+	values := numbers(other)
+	baselineValues := numbers(baseline)
+
+	var deltas []Delta
+	for _, e := range baseline {
+		b, ok := e.Value.(float64)
+		if !ok {
+			continue
+		}
+		v, found := values[e.Name]
+		if !found {
+			deltas = append(deltas, missing(e.Name, tolerances, "the result item is not found in the compared results"))
+			continue
+		}
+		d := Delta{
+			Name:     e.Name,
+			Baseline: b,
+			Value:    v,
+			Absolute: v - b,
+		}
+		switch {
+		case d.Absolute == 0:
+			d.Relative = 0
+		case b == 0:
+			d.Relative = math.Inf(1)
+		default:
+			d.Relative = d.Absolute / math.Abs(b) * 100
+		}
+		if t, ok := tolerances[e.Name]; ok {
+			d.Exceeded = t.check(d)
+		}
+		deltas = append(deltas, d)
+	}
+	for _, e := range other {
+		if _, ok := e.Value.(float64); !ok {
+			continue
+		}
+		if _, found := baselineValues[e.Name]; !found {
+			deltas = append(deltas, missing(e.Name, tolerances, "the result item is not found in the baseline results"))
+		}
+	}
+	return deltas
+}
+
+func numbers(es []results.Entry) map[string]float64 {
+	values := map[string]float64{}
+	for _, e := range es {
+		if v, ok := e.Value.(float64); ok {
+			values[e.Name] = v
+		}
+	}
+	return values
+}
+
+// missing returns the delta of a result item that is in only one of the results, it exceeds its tolerance if one is set
+func missing(name string, tolerances Tolerances, reason string) Delta {
+	d := Delta{
+		Name:    name,
+		Missing: true,
+	}
+	if _, ok := tolerances[name]; ok {
+		d.Exceeded = reason
+	}
+	return d
+}
+
+func (t Tolerance) check(d Delta) string {
+	if (t.Direction == Increase && d.Absolute <= 0) || (t.Direction == Decrease && d.Absolute >= 0) {
+		return ""
+	}
+	if t.Absolute != nil && math.Abs(d.Absolute) > *t.Absolute {
+		return fmt.Sprintf("the absolute delta %.2f exceeds the tolerance of %g", d.Absolute, *t.Absolute)
+	}
+	if t.Relative != nil && math.Abs(d.Relative) > *t.Relative {
+		return fmt.Sprintf("the relative delta %.2f%% exceeds the tolerance of %g%%", d.Relative, *t.Relative)
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package compare
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/results"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	// given
+	baseline := []results.Entry{
+		results.NewEntry("Scenario", "base"),
+		results.NewEntry("Average Idler Update Time (s)", "2.00"),
+		results.NewEntry("Max host-operator-controller-manager Memory Usage (MB)", "400.00"),
+		results.NewEntry("Number of Uninstalled Operators", "0"),
+		results.NewEntry("Removed Item", "1"),
+	}
+	other := []results.Entry{
+		results.NewEntry("Scenario", "other"),
+		results.NewEntry("Average Idler Update Time (s)", "1.00"),
+		results.NewEntry("Max host-operator-controller-manager Memory Usage (MB)", "500.00"),
+		results.NewEntry("Number of Uninstalled Operators", "2"),
+		results.NewEntry("Added Item", "1"),
+	}
+
+	t.Run("without tolerances", func(t *testing.T) {
+		// when
+		deltas := Compare(baseline, other, nil)
+
+		// then
+		assert.Equal(t, []Delta{
+			{Name: "Average Idler Update Time (s)", Baseline: 2, Value: 1, Absolute: -1, Relative: -50},
+			{Name: "Max host-operator-controller-manager Memory Usage (MB)", Baseline: 400, Value: 500, Absolute: 100, Relative: 25},
+			{Name: "Number of Uninstalled Operators", Baseline: 0, Value: 2, Absolute: 2, Relative: math.Inf(1)},
+			{Name: "Removed Item", Missing: true},
+			{Name: "Added Item", Missing: true},
+		}, deltas)
+	})
+
+	t.Run("with tolerances", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			tolerance Tolerance
+			item      string
+			exceeded  string
+		}{
+			"relative within tolerance": {
+				tolerance: Tolerance{Relative: ptr(30)},
+				item:      "Max host-operator-controller-manager Memory Usage (MB)",
+			},
+			"relative exceeded": {
+				tolerance: Tolerance{Relative: ptr(10)},
+				item:      "Max host-operator-controller-manager Memory Usage (MB)",
+				exceeded:  "the relative delta 25.00% exceeds the tolerance of 10%",
+			},
+			"absolute exceeded": {
+				tolerance: Tolerance{Absolute: ptr(50), Relative: ptr(30)},
+				item:      "Max host-operator-controller-manager Memory Usage (MB)",
+				exceeded:  "the absolute delta 100.00 exceeds the tolerance of 50",
+			},
+			"decrease exceeded": {
+				tolerance: Tolerance{Relative: ptr(10)},
+				item:      "Average Idler Update Time (s)",
+				exceeded:  "the relative delta -50.00% exceeds the tolerance of 10%",
+			},
+			"decrease ignored": {
+				tolerance: Tolerance{Relative: ptr(10), Direction: Increase},
+				item:      "Average Idler Update Time (s)",
+			},
+			"increase ignored": {
+				tolerance: Tolerance{Relative: ptr(10), Direction: Decrease},
+				item:      "Max host-operator-controller-manager Memory Usage (MB)",
+			},
+			"zero baseline": {
+				tolerance: Tolerance{Relative: ptr(1000)},
+				item:      "Number of Uninstalled Operators",
+				exceeded:  "the relative delta +Inf% exceeds the tolerance of 1000%",
+			},
+			"missing in the compared results": {
+				tolerance: Tolerance{Relative: ptr(10)},
+				item:      "Removed Item",
+				exceeded:  "the result item is not found in the compared results",
+			},
+			"missing in the baseline": {
+				tolerance: Tolerance{Relative: ptr(10)},
+				item:      "Added Item",
+				exceeded:  "the result item is not found in the baseline results",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// when
+				deltas := Compare(baseline, other, Tolerances{tc.item: tc.tolerance})
+
+				// then
+				for _, d := range deltas {
+					if d.Name == tc.item {
+						assert.Equal(t, tc.exceeded, d.Exceeded)
+					} else {
+						assert.Empty(t, d.Exceeded)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestLoadTolerances(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// given
+		path := writeFile(t, `
+"Max host-operator-controller-manager Memory Usage (MB)":
+  relative: 10
+  direction: increase
+"Average Idler Update Time (s)":
+  absolute: 0.5
+`)
+
+		// when
+		tolerances, err := LoadTolerances(path)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Tolerances{
+			"Max host-operator-controller-manager Memory Usage (MB)": {Relative: ptr(10), Direction: Increase},
+			"Average Idler Update Time (s)":                          {Absolute: ptr(0.5)},
+		}, tolerances)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("file not found", func(t *testing.T) {
+			_, err := LoadTolerances(filepath.Join(t.TempDir(), "not-found.yaml"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+
+		t.Run("invalid direction", func(t *testing.T) {
+			// given
+			path := writeFile(t, `
+"Average Idler Update Time (s)":
+  absolute: 0.5
+  direction: up
+`)
+
+			// when
+			_, err := LoadTolerances(path)
+
+			// then
+			require.EqualError(t, err, "invalid tolerances file '"+path+"': invalid direction 'up' of 'Average Idler Update Time (s)': must be either 'increase' or 'decrease'")
+		})
+	})
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "tolerances.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
@@ -50,8 +50,9 @@ var (
 
 	resultsDir       string
 	resultsFilepath  string
-	stdOutFilepath   string
-	stdErrFilepath   string
+	logFilepath      string
+	latencyFilepath  string
+	seriesFilepath   string
 	startedTimestamp = time.Now().Format("2006-01-02_15:04:05")
 )
 
@@ -68,8 +69,9 @@ func Init(term terminal.Terminal) {
 		Testname = "-" + Testname
 	}
 	resultsFilepath = fmt.Sprintf("%s%s%s.csv", resultsDir, startedTimestamp, Testname)
-	stdOutFilepath = fmt.Sprintf("%s%s%s-stdout.log", resultsDir, startedTimestamp, Testname)
-	stdErrFilepath = fmt.Sprintf("%s%s%s-stderr.log", resultsDir, startedTimestamp, Testname)
+	logFilepath = fmt.Sprintf("%s%s%s-log.jsonl", resultsDir, startedTimestamp, Testname)
+	latencyFilepath = fmt.Sprintf("%s%s%s-user-latencies.csv", resultsDir, startedTimestamp, Testname)
+	seriesFilepath = fmt.Sprintf("%s%s%s-metrics-series", resultsDir, startedTimestamp, Testname)
 }
 
 // NewClient returns a new client to the cluster defined by the current context in
@@ -123,7 +125,7 @@ func NewScheme() (*runtime.Scheme, error) {
 
 func ConfigureDefaultSpaceTier(cl client.Client) error {
 	// ensure the NSTemplateTier (SpaceTier) exists
-	if err := cl.Get(context.TODO(), types.NamespacedName{Name: UserSpaceTier, Namespace: HostOperatorNamespace}, &toolchainv1alpha1.NSTemplateTier{}); err != nil {
+	if err := VerifySpaceTier(cl, UserSpaceTier); err != nil {
 		return err
 	}
 
@@ -136,6 +138,11 @@ func ConfigureDefaultSpaceTier(cl client.Client) error {
 	return cl.Update(context.TODO(), toolchainCfg)
 }
 
+// VerifySpaceTier ensures that the NSTemplateTier with the given name exists
+func VerifySpaceTier(cl client.Client, tier string) error {
+	return cl.Get(context.TODO(), types.NamespacedName{Name: tier, Namespace: HostOperatorNamespace}, &toolchainv1alpha1.NSTemplateTier{})
+}
+
 // DisableCopiedCSVs disables OLM's CopiedCSVs feature, since OpenShift 4.13 the console no longer relies on CSVs to know which operators are installed
 func DisableCopiedCSVs(cl client.Client) error {
 	olmConfig := &operatorsv1.OLMConfig{}
@@ -190,12 +197,25 @@ func ResultsFilepath() string {
 	return resultsFilepath
 }
 
-func StdOutFilepath() string {
-	return stdOutFilepath
+// UserLatenciesFilepath returns the path of the file with the time spent by each user in each phase, next to the results file
+func UserLatenciesFilepath() string {
+	return latencyFilepath
+}
+
+// MetricsSeriesFilepath returns the path of the file with the given extension with the metrics series captured during the run, next to the results file
+func MetricsSeriesFilepath(ext string) string {
+	return seriesFilepath + "." + ext
+}
+
+// CheckpointFilepath returns the path of the checkpoint file of the scenario with the given name.
+// Unlike the results file, the name does not contain the start timestamp so that a later run can resume from it.
+func CheckpointFilepath(scenarioName string) string {
+	return fmt.Sprintf("%s%s%s-checkpoint.jsonl", resultsDir, scenarioName, Testname)
 }
 
-func StdErrFilepath() string {
-	return stdErrFilepath
+// LogFilepath returns the path of the file with the events of the run as JSON lines, next to the results file
+func LogFilepath() string {
+	return logFilepath
 }
 
 func StartedTimestamp() string {
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
 	"github.com/codeready-toolchain/toolchain-e2e/testsupport/wait"
@@ -13,22 +14,115 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	k8swait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// idlerSettleTime is how long the timeouts stay unchecked after an update, the member operator may revert them when it reconciles the
+// NSTemplateSet of the space right after the update
+var idlerSettleTime = 2 * time.Second
+
+// idlerUpdateAttempts is how many times the timeouts are set before giving up when they keep being reverted
+const idlerUpdateAttempts = 3
+
+// UpdateTimeout sets the given timeout on the idlers of all the namespaces provisioned for the space of the given user,
+// whatever its tier, and checks again after a settle period that the new timeout is still set on each of them. The timeouts
+// that were reverted in the meantime are set again.
 func UpdateTimeout(cl client.Client, username string, timeout time.Duration) error {
-	for _, suffix := range []string{"dev"} { // TODO: hard coded suffixes, we could probably get them from the tier instead
-		idlerName := fmt.Sprintf("%s-%s", username, suffix)
-		idler, err := getIdler(cl, idlerName)
-		if err != nil {
-			return err
+	names, err := Names(cl, username)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := getIdler(cl, name); err != nil {
+			return fmt.Errorf("idler '%s' is not running: %w", name, err)
+		}
+	}
+	timeoutSeconds := int(timeout.Seconds())
+	for attempt := 1; ; attempt++ {
+		var updated []string
+		for _, name := range names {
+			changed, err := setTimeout(cl, name, timeoutSeconds)
+			if err != nil {
+				return err
+			}
+			if changed {
+				updated = append(updated, name)
+			}
+		}
+		if len(updated) == 0 {
+			return nil
+		}
+		if attempt == idlerUpdateAttempts {
+			return fmt.Errorf("the timeout of the idlers %v was reverted after %d updates", updated, attempt-1)
 		}
-		idler.Spec.TimeoutSeconds = int32(timeout.Seconds())
-		if err = cl.Update(context.TODO(), idler); err != nil {
+		time.Sleep(idlerSettleTime)
+	}
+}
+
+// setTimeout sets the given timeout on the idler with the given name and returns true if the idler had another timeout
+func setTimeout(cl client.Client, name string, timeoutSeconds int) (bool, error) {
+	changed := false
+	// the idler is updated by the member operator at the same time, so retry on conflicts
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		idler := &toolchainv1alpha1.Idler{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Name: name}, idler); err != nil {
 			return err
 		}
+		if wait.IdlerHasTimeoutSeconds(timeoutSeconds).Match(idler) {
+			changed = false
+			return nil
+		}
+		idler.Spec.TimeoutSeconds = int32(timeoutSeconds) // nolint:gosec
+		changed = true
+		return cl.Update(context.TODO(), idler)
+	})
+	return changed, err
+}
+
+// Names returns the names of the idlers of the space of the given user, which are named after the namespaces provisioned for the space.
+// It waits until the space is ready so that all its namespaces are provisioned.
+func Names(cl client.Client, username string) ([]string, error) {
+	space := &toolchainv1alpha1.Space{}
+	err := k8swait.PollUntilContextTimeout(context.TODO(), cfg.DefaultRetryInterval, cfg.DefaultTimeout, true, func(ctx context.Context) (bool, error) {
+		err := cl.Get(context.TODO(), types.NamespacedName{Namespace: cfg.HostOperatorNamespace, Name: username}, space)
+		if errors.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return condition.IsTrue(space.Status.Conditions, toolchainv1alpha1.ConditionReady), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("space '%s' is not ready yet: %w", username, err)
+	}
+	names := make([]string, 0, len(space.Status.ProvisionedNamespaces))
+	for _, ns := range space.Status.ProvisionedNamespaces {
+		names = append(names, ns.Name)
+	}
+	return names, nil
+}
+
+// HasTimeout returns true when all the idlers of the ready space of the given user have the given timeout.
+// It returns false without waiting when the space is not ready or when an idler is missing.
+func HasTimeout(cl client.Client, username string, timeout time.Duration) (bool, error) {
+	space := &toolchainv1alpha1.Space{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: cfg.HostOperatorNamespace, Name: username}, space); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	if !condition.IsTrue(space.Status.Conditions, toolchainv1alpha1.ConditionReady) {
+		return false, nil
+	}
+	for _, ns := range space.Status.ProvisionedNamespaces {
+		idler := &toolchainv1alpha1.Idler{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Name: ns.Name}, idler); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		if !wait.IdlerHasTimeoutSeconds(int(timeout.Seconds())).Match(idler) {
+			return false, nil
+		}
 	}
-	return nil
+	return true, nil
 }
 
 func getIdler(cl client.Client, name string) (*toolchainv1alpha1.Idler, error) {
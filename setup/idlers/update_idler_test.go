@@ -0,0 +1,142 @@
+package idlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	commontest "github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testspace "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/testsupport/wait"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestUpdateTimeout(t *testing.T) {
+	// given
+	configuration.DefaultTimeout = time.Millisecond * 1
+	configuration.HostOperatorNamespace = "toolchain-host-operator"
+	timeout := 15 * time.Second
+	idlerSettleTime = time.Millisecond
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		cl := commontest.NewFakeClient(t, space("user0001", "user0001-dev", "user0001-stage"),
+			idler("user0001-dev", 43200), idler("user0001-stage", 43200))
+
+		// when
+		err := UpdateTimeout(cl, "user0001", timeout)
+
+		// then
+		require.NoError(t, err)
+		for _, name := range []string{"user0001-dev", "user0001-stage"} {
+			idler := &toolchainv1alpha1.Idler{}
+			require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Name: name}, idler))
+			assert.Equal(t, int32(15), idler.Spec.TimeoutSeconds)
+		}
+		updated, err := HasTimeout(cl, "user0001", timeout)
+		require.NoError(t, err)
+		assert.True(t, updated)
+	})
+
+	t.Run("reverted timeout is set again", func(t *testing.T) {
+		// given
+		cl := commontest.NewFakeClient(t, space("user0001", "user0001-dev", "user0001-stage"),
+			idler("user0001-dev", 43200), idler("user0001-stage", 43200))
+		updates := map[string]int{}
+		cl.MockUpdate = func(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+			updates[obj.GetName()]++
+			if obj.GetName() == "user0001-dev" && updates[obj.GetName()] == 1 {
+				// the first update is reverted by the member operator
+				return nil
+			}
+			return cl.Client.Update(ctx, obj, opts...)
+		}
+
+		// when
+		err := UpdateTimeout(cl, "user0001", timeout)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"user0001-dev": 2, "user0001-stage": 1}, updates)
+		updated, err := HasTimeout(cl, "user0001", timeout)
+		require.NoError(t, err)
+		assert.True(t, updated)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("timeout always reverted", func(t *testing.T) {
+			// given
+			cl := commontest.NewFakeClient(t, space("user0001", "user0001-dev"), idler("user0001-dev", 43200))
+			cl.MockUpdate = func(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+				return nil
+			}
+
+			// when
+			err := UpdateTimeout(cl, "user0001", timeout)
+
+			// then
+			require.EqualError(t, err, "the timeout of the idlers [user0001-dev] was reverted after 2 updates")
+		})
+
+		t.Run("space not ready", func(t *testing.T) {
+			// given
+			cl := commontest.NewFakeClient(t, testspace.NewSpace(configuration.HostOperatorNamespace, "user0001"))
+
+			// when
+			err := UpdateTimeout(cl, "user0001", timeout)
+
+			// then
+			require.EqualError(t, err, "space 'user0001' is not ready yet: context deadline exceeded")
+		})
+
+		t.Run("idler not found", func(t *testing.T) {
+			// given
+			cl := commontest.NewFakeClient(t, space("user0001", "user0001-dev", "user0001-stage"), idler("user0001-dev", 43200))
+
+			// when
+			err := UpdateTimeout(cl, "user0001", timeout)
+
+			// then
+			require.EqualError(t, err, "idler 'user0001-stage' is not running: context deadline exceeded")
+			updated, err := HasTimeout(cl, "user0001", timeout)
+			require.NoError(t, err)
+			assert.False(t, updated)
+		})
+	})
+}
+
+func space(name string, namespaces ...string) *toolchainv1alpha1.Space {
+	var provisioned []toolchainv1alpha1.SpaceNamespace
+	for _, ns := range namespaces {
+		provisioned = append(provisioned, toolchainv1alpha1.SpaceNamespace{Name: ns})
+	}
+	return testspace.NewSpace(configuration.HostOperatorNamespace, name,
+		testspace.WithCondition(toolchainv1alpha1.Condition{
+			Type:   toolchainv1alpha1.ConditionReady,
+			Status: corev1.ConditionTrue,
+			Reason: "Provisioned",
+		}),
+		testspace.WithStatusProvisionedNamespaces(provisioned))
+}
+
+func idler(name string, timeoutSeconds int32) *toolchainv1alpha1.Idler {
+	return &toolchainv1alpha1.Idler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: toolchainv1alpha1.IdlerSpec{
+			TimeoutSeconds: timeoutSeconds,
+		},
+		Status: toolchainv1alpha1.IdlerStatus{
+			Conditions: []toolchainv1alpha1.Condition{wait.Running()},
+		},
+	}
+}
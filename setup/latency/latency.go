@@ -0,0 +1,157 @@
+package latency
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Percentiles are the percentiles reported for each phase, in addition to the min and max values
+var Percentiles = []float64{50, 90, 95, 99}
+
+// Recorder collects the time spent by each user in each phase of the provisioning. It's safe for concurrent use.
+type Recorder struct {
+	mu        sync.Mutex
+	phases    []string
+	durations map[string]map[string]time.Duration
+}
+
+// NewRecorder returns a recorder for the given phases, the results are reported in the same order as the phases
+func NewRecorder(phases ...string) *Recorder {
+	durations := make(map[string]map[string]time.Duration, len(phases))
+	for _, p := range phases {
+		durations[p] = map[string]time.Duration{}
+	}
+	return &Recorder{
+		phases:    phases,
+		durations: durations,
+	}
+}
+
+// Record stores the time spent by the given user in the given phase. Nothing is recorded by a nil recorder or for an unknown phase.
+func (r *Recorder) Record(phase, username string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if durations, ok := r.durations[phase]; ok {
+		durations[username] = d
+	}
+}
+
+// Summary is the distribution of the time spent by the users in a phase
+type Summary struct {
+	Count       int
+	Min         time.Duration
+	Max         time.Duration
+	Percentiles map[float64]time.Duration
+}
+
+// Summary returns the distribution of the time spent by the users in the given phase
+func (r *Recorder) Summary(phase string) Summary {
+	r.mu.Lock()
+	durations := make([]time.Duration, 0, len(r.durations[phase]))
+	for _, d := range r.durations[phase] {
+		durations = append(durations, d)
+	}
+	r.mu.Unlock()
+	return Summarize(durations)
+}
+
+// Summarize returns the distribution of the given durations
+func Summarize(durations []time.Duration) Summary {
+	s := Summary{
+		Count:       len(durations),
+		Percentiles: map[float64]time.Duration{},
+	}
+	if len(durations) == 0 {
+		return s
+	}
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	s.Min = sorted[0]
+	s.Max = sorted[len(sorted)-1]
+	for _, p := range Percentiles {
+		s.Percentiles[p] = percentile(sorted, p)
+	}
+	return s
+}
+
+// percentile returns the p-th percentile of the given sorted durations using the nearest-rank method
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Results returns the min, percentiles and max of each phase in which at least one user was recorded
+func (r *Recorder) Results() [][]string {
+	var results [][]string
+	for _, phase := range r.phases {
+		s := r.Summary(phase)
+		if s.Count == 0 {
+			continue
+		}
+		results = append(results, []string{fmt.Sprintf("Time Per User - %s min (s)", phase), seconds(s.Min)})
+		for _, p := range Percentiles {
+			results = append(results, []string{fmt.Sprintf("Time Per User - %s p%g (s)", phase, p), seconds(s.Percentiles[p])})
+		}
+		results = append(results, []string{fmt.Sprintf("Time Per User - %s max (s)", phase), seconds(s.Max)})
+	}
+	return results
+}
+
+// WriteCSV writes the raw time spent by each user in each phase to the file at the given path, one line per user.
+// The cell is empty when the user was not recorded in a phase, eg. when the phase was completed by a previous run.
+func (r *Recorder) WriteCSV(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := map[string]bool{}
+	var usernames []string
+	header := []string{"username"}
+	for _, phase := range r.phases {
+		header = append(header, phase+" (s)")
+		for username := range r.durations[phase] {
+			if !found[username] {
+				found[username] = true
+				usernames = append(usernames, username)
+			}
+		}
+	}
+	sort.Strings(usernames)
+
+	lines := [][]string{header}
+	for _, username := range usernames {
+		line := []string{username}
+		for _, phase := range r.phases {
+			cell := ""
+			if d, ok := r.durations[phase][username]; ok {
+				cell = seconds(d)
+			}
+			line = append(line, cell)
+		}
+		lines = append(lines, line)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := csv.NewWriter(f).WriteAll(lines); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write the user latencies to '%s': %w", path, err)
+	}
+	return f.Close()
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.2f", d.Seconds())
+}
@@ -0,0 +1,135 @@
+package latency
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Run("no durations", func(t *testing.T) {
+		// when
+		s := Summarize(nil)
+
+		// then
+		assert.Equal(t, 0, s.Count)
+		assert.Zero(t, s.Min)
+		assert.Zero(t, s.Max)
+		assert.Empty(t, s.Percentiles)
+	})
+
+	t.Run("single duration", func(t *testing.T) {
+		// when
+		s := Summarize([]time.Duration{time.Second})
+
+		// then
+		assert.Equal(t, 1, s.Count)
+		assert.Equal(t, time.Second, s.Min)
+		assert.Equal(t, time.Second, s.Max)
+		for _, p := range Percentiles {
+			assert.Equal(t, time.Second, s.Percentiles[p])
+		}
+	})
+
+	t.Run("100 durations", func(t *testing.T) {
+		// given
+		var durations []time.Duration
+		for i := 100; i > 0; i-- {
+			durations = append(durations, time.Duration(i)*time.Second)
+		}
+
+		// when
+		s := Summarize(durations)
+
+		// then
+		assert.Equal(t, 100, s.Count)
+		assert.Equal(t, time.Second, s.Min)
+		assert.Equal(t, 100*time.Second, s.Max)
+		assert.Equal(t, map[float64]time.Duration{
+			50: 50 * time.Second,
+			90: 90 * time.Second,
+			95: 95 * time.Second,
+			99: 99 * time.Second,
+		}, s.Percentiles)
+		assert.Equal(t, 100*time.Second, durations[0]) // the given durations are not sorted in place
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	// given
+	r := NewRecorder("signup", "idler")
+	r.Record("signup", "user-0002", 4*time.Second)
+	r.Record("signup", "user-0001", 2*time.Second)
+	r.Record("idler", "user-0001", 500*time.Millisecond)
+	r.Record("unknown", "user-0001", time.Second)
+
+	t.Run("summary", func(t *testing.T) {
+		// when
+		s := r.Summary("signup")
+
+		// then
+		assert.Equal(t, 2, s.Count)
+		assert.Equal(t, 2*time.Second, s.Min)
+		assert.Equal(t, 4*time.Second, s.Max)
+		assert.Equal(t, 2*time.Second, s.Percentiles[50])
+		assert.Equal(t, 4*time.Second, s.Percentiles[99])
+	})
+
+	t.Run("results", func(t *testing.T) {
+		// when
+		results := r.Results()
+
+		// then
+		assert.Equal(t, [][]string{
+			{"Time Per User - signup min (s)", "2.00"},
+			{"Time Per User - signup p50 (s)", "2.00"},
+			{"Time Per User - signup p90 (s)", "4.00"},
+			{"Time Per User - signup p95 (s)", "4.00"},
+			{"Time Per User - signup p99 (s)", "4.00"},
+			{"Time Per User - signup max (s)", "4.00"},
+			{"Time Per User - idler min (s)", "0.50"},
+			{"Time Per User - idler p50 (s)", "0.50"},
+			{"Time Per User - idler p90 (s)", "0.50"},
+			{"Time Per User - idler p95 (s)", "0.50"},
+			{"Time Per User - idler p99 (s)", "0.50"},
+			{"Time Per User - idler max (s)", "0.50"},
+		}, results)
+	})
+
+	t.Run("results without records", func(t *testing.T) {
+		assert.Empty(t, NewRecorder("signup").Results())
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		// given
+		path := filepath.Join(t.TempDir(), "latencies.csv")
+
+		// when
+		err := r.WriteCSV(path)
+
+		// then
+		require.NoError(t, err)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "username,signup (s),idler (s)\nuser-0001,2.00,0.50\nuser-0002,4.00,\n", string(content))
+	})
+
+	t.Run("csv in missing dir", func(t *testing.T) {
+		// when
+		err := r.WriteCSV(filepath.Join(t.TempDir(), "missing", "latencies.csv"))
+
+		// then
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("nil recorder", func(t *testing.T) {
+		var nilRecorder *Recorder
+		assert.NotPanics(t, func() {
+			nilRecorder.Record("signup", "user-0001", time.Second)
+		})
+	})
+}
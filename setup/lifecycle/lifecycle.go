@@ -0,0 +1,302 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/arrival"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/checkpoint"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/latency"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/users"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/wait"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Action is a change of the lifecycle of the users
+type Action string
+
+const (
+	// Deactivate deactivates the users, which are then deprovisioned
+	Deactivate Action = "deactivate"
+	// Reactivate reactivates users that were deactivated by a previous step, which are then provisioned again
+	Reactivate Action = "reactivate"
+	// Ban bans the users, which are then deprovisioned
+	Ban Action = "ban"
+)
+
+// Actions lists all the supported actions
+var Actions = []Action{Deactivate, Reactivate, Ban}
+
+// the stages that are measured from the time the action is made, the deprovisioning stages are waited for in this order
+const (
+	MasterUserRecordDeleted = "masteruserrecord-deleted"
+	SpaceDeleted            = "space-deleted"
+	NamespacesDeleted       = "namespaces-deleted"
+	SpaceReady              = "space-ready"
+)
+
+// Step applies an action to a percentage of all the users of the run, at the given rate
+type Step struct {
+	Action Action `json:"action"`
+	// Percentage is the percentage of all the users of the run that the action is applied to
+	Percentage float64 `json:"percentage"`
+	// Rate is the number of users per second that the action is applied to
+	Rate float64 `json:"rate"`
+	// MaxInFlight is the maximum number of users that are processed concurrently, the following ones are delayed
+	MaxInFlight int `json:"maxInFlight,omitempty"`
+}
+
+// ParseStep returns the step described as an action:percentage:rate triple, eg. "deactivate:10:2"
+func ParseStep(value string) (Step, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return Step{}, fmt.Errorf("invalid lifecycle step '%s': must be an action:percentage:rate triple", value)
+	}
+	percentage, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return Step{}, fmt.Errorf("invalid lifecycle step '%s': invalid percentage: %w", value, err)
+	}
+	rate, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return Step{}, fmt.Errorf("invalid lifecycle step '%s': invalid rate: %w", value, err)
+	}
+	return Step{Action: Action(parts[0]), Percentage: percentage, Rate: rate}, nil
+}
+
+// String returns a compact description of the step
+func (s Step) String() string {
+	return fmt.Sprintf("%s %g%% of the users at %.2f users/s", s.Action, s.Percentage, s.Rate)
+}
+
+// ValidateSteps checks the settings of the given steps, which are applied in order: the users can only be deactivated or banned
+// while they are active, and only the users that were deactivated by the previous steps can be reactivated
+func ValidateSteps(steps []Step) error {
+	active, deactivated := 100.0, 0.0
+	for i, s := range steps {
+		if s.Percentage <= 0 || s.Percentage > 100 {
+			return fmt.Errorf("invalid lifecycle step %d: invalid percentage value '%g': value must be more than 0 and at most 100", i+1, s.Percentage)
+		}
+		if s.Rate <= 0 {
+			return fmt.Errorf("invalid lifecycle step %d: the rate must be more than 0", i+1)
+		}
+		if s.MaxInFlight < 0 {
+			return fmt.Errorf("invalid lifecycle step %d: the max in-flight value must not be negative", i+1)
+		}
+		switch s.Action {
+		case Deactivate, Ban:
+			if s.Percentage > active {
+				return fmt.Errorf("invalid lifecycle step %d: only %g%% of the users are still active", i+1, active)
+			}
+			active -= s.Percentage
+			if s.Action == Deactivate {
+				deactivated += s.Percentage
+			}
+		case Reactivate:
+			if s.Percentage > deactivated {
+				return fmt.Errorf("invalid lifecycle step %d: only %g%% of the users were deactivated", i+1, deactivated)
+			}
+			deactivated -= s.Percentage
+			active += s.Percentage
+		default:
+			return fmt.Errorf("invalid lifecycle step %d: invalid action '%s': must be one of %v", i+1, s.Action, Actions)
+		}
+	}
+	return nil
+}
+
+// Simulator applies the lifecycle steps to the users and measures the time until the users are deprovisioned or provisioned again.
+// The users are picked from the end of the list of users so that the first users of the run, eg. the churn users, are the last ones to be picked.
+// The errors don't stop the steps, they are counted per action. The users deactivated or banned are recorded in the checkpoint,
+// so that a resumed run doesn't wait for them to be provisioned again.
+type Simulator struct {
+	cl                    client.Client
+	hostOperatorNamespace string
+	cp                    *checkpoint.Checkpoint
+	total                 int
+	mu                    sync.Mutex
+	active                []string
+	deactivated           []string
+	latencies             *latency.Recorder
+	processed             map[Action]int
+	errors                map[Action]int
+}
+
+// New returns a simulator for the given users. The users deactivated by a previous run according to the given checkpoint
+// can be reactivated, the ones banned are left alone.
+func New(cl client.Client, hostOperatorNamespace string, usernames []string, cp *checkpoint.Checkpoint) *Simulator {
+	var phases []string
+	for _, action := range Actions {
+		for _, stage := range stagesOf(action) {
+			phases = append(phases, phase(action, stage))
+		}
+	}
+	s := &Simulator{
+		cl:                    cl,
+		hostOperatorNamespace: hostOperatorNamespace,
+		cp:                    cp,
+		total:                 len(usernames),
+		latencies:             latency.NewRecorder(phases...),
+		processed:             map[Action]int{},
+		errors:                map[Action]int{},
+	}
+	for _, username := range usernames {
+		switch {
+		case cp.IsDone(username, checkpoint.Banned):
+		case cp.IsDone(username, checkpoint.Deactivated):
+			s.deactivated = append(s.deactivated, username)
+		default:
+			s.active = append(s.active, username)
+		}
+	}
+	return s
+}
+
+// stagesOf returns the stages that are measured for the given action
+func stagesOf(action Action) []string {
+	if action == Reactivate {
+		return []string{SpaceReady}
+	}
+	return []string{MasterUserRecordDeleted, SpaceDeleted, NamespacesDeleted}
+}
+
+func phase(action Action, stage string) string {
+	return fmt.Sprintf("%s %s", action, stage)
+}
+
+// Run applies the given step at its rate and returns once all the users of the step are processed. When the context is done,
+// the users of the step that were not processed yet are left in the pool of the action.
+func (s *Simulator) Run(ctx context.Context, step Step) arrival.Stats {
+	picked := s.pick(step)
+	maxInFlight := step.MaxInFlight
+	if maxInFlight == 0 {
+		maxInFlight = arrival.DefaultMaxInFlight
+	}
+	schedule := arrival.Profile{Type: arrival.Constant, Rate: step.Rate}.Schedule(len(picked))
+	stats := arrival.Run(ctx, schedule, maxInFlight, func(i int) {
+		err := s.apply(step.Action, picked[i])
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.processed[step.Action]++
+		if err != nil {
+			s.errors[step.Action]++
+			return
+		}
+		if step.Action == Deactivate {
+			s.deactivated = append(s.deactivated, picked[i])
+		} else if step.Action == Reactivate {
+			s.active = append(s.active, picked[i])
+		}
+	})
+	// the arrivals are made in order, so the users that were not processed are the last ones
+	s.putBack(step, picked[stats.Arrivals:])
+	return stats
+}
+
+// putBack adds the given users that were picked for the given step back to the end of the pool of the action
+func (s *Simulator) putBack(step Step, usernames []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if step.Action == Reactivate {
+		s.deactivated = append(s.deactivated, usernames...)
+		return
+	}
+	s.active = append(s.active, usernames...)
+}
+
+// pick removes the users of the given step from the end of the pool of the users the action applies to
+func (s *Simulator) pick(step Step) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pool := &s.active
+	if step.Action == Reactivate {
+		pool = &s.deactivated
+	}
+	count := int(math.Round(step.Percentage / 100 * float64(s.total)))
+	if count > len(*pool) {
+		count = len(*pool)
+	}
+	picked := append([]string{}, (*pool)[len(*pool)-count:]...)
+	*pool = (*pool)[:len(*pool)-count]
+	return picked
+}
+
+// apply makes the action for the given user and waits for each of its stages, the time until each stage is recorded
+func (s *Simulator) apply(action Action, username string) error {
+	start := time.Now()
+	var err error
+	switch action {
+	case Deactivate:
+		err = users.Deactivate(s.cl, username, s.hostOperatorNamespace)
+	case Reactivate:
+		err = users.Reactivate(s.cl, username, s.hostOperatorNamespace)
+	case Ban:
+		err = users.Ban(s.cl, username, s.hostOperatorNamespace)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to %s user '%s': %w", action, username, err)
+	}
+	if err := s.recordOutcome(action, username); err != nil {
+		return fmt.Errorf("unable to record the outcome of the %s action of user '%s' in the checkpoint: %w", action, username, err)
+	}
+	for _, stage := range stagesOf(action) {
+		switch stage {
+		case MasterUserRecordDeleted:
+			err = wait.ForMasterUserRecordDeletion(s.cl, username)
+		case SpaceDeleted:
+			err = wait.ForSpaceDeletion(s.cl, username)
+		case NamespacesDeleted:
+			err = wait.ForSpaceNamespacesDeletion(s.cl, username)
+		case SpaceReady:
+			err = wait.ForSpace(s.cl, username)
+		}
+		if err != nil {
+			return err
+		}
+		s.latencies.Record(phase(action, stage), username, time.Since(start))
+	}
+	return nil
+}
+
+// recordOutcome records the given action applied to the given user in the checkpoint
+func (s *Simulator) recordOutcome(action Action, username string) error {
+	switch action {
+	case Deactivate, Ban:
+		// the namespaces of the user are deleted together with the idler and the resources of the templates
+		if err := s.cp.MarkForgotten(username, checkpoint.IdlerUpdated, checkpoint.DefaultTemplateApplied, checkpoint.CustomTemplateApplied); err != nil {
+			return err
+		}
+		if action == Ban {
+			return s.cp.MarkDone(username, checkpoint.Banned)
+		}
+		return s.cp.MarkDone(username, checkpoint.Deactivated)
+	default:
+		return s.cp.MarkForgotten(username, checkpoint.Deactivated)
+	}
+}
+
+// Results returns the number of users processed by each action, the errors and the distribution of the time until each stage.
+// There are no results for a nil simulator.
+func (s *Simulator) Results() [][]string {
+	var results [][]string
+	if s == nil {
+		return results
+	}
+	s.mu.Lock()
+	for _, action := range Actions {
+		if s.processed[action] == 0 {
+			continue
+		}
+		results = append(results,
+			[]string{fmt.Sprintf("Lifecycle %s Users", action), fmt.Sprintf("%d", s.processed[action])},
+			[]string{fmt.Sprintf("Lifecycle %s Errors", action), fmt.Sprintf("%d", s.errors[action])},
+		)
+	}
+	s.mu.Unlock()
+	return append(results, s.latencies.Results()...)
+}
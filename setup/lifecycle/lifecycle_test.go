@@ -0,0 +1,287 @@
+package lifecycle
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/states"
+	testspace "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/checkpoint"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/test"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/users"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestParseStep(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// when
+		step, err := ParseStep("deactivate:10:2.5")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Step{Action: Deactivate, Percentage: 10, Rate: 2.5}, step)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for value, errMsg := range map[string]string{
+			"deactivate:10":    "invalid lifecycle step 'deactivate:10': must be an action:percentage:rate triple",
+			"deactivate:ten:2": `invalid lifecycle step 'deactivate:ten:2': invalid percentage: strconv.ParseFloat: parsing "ten": invalid syntax`,
+			"ban:10:fast":      `invalid lifecycle step 'ban:10:fast': invalid rate: strconv.ParseFloat: parsing "fast": invalid syntax`,
+		} {
+			t.Run(value, func(t *testing.T) {
+				// when
+				_, err := ParseStep(value)
+
+				// then
+				require.EqualError(t, err, errMsg)
+			})
+		}
+	})
+}
+
+func TestValidateSteps(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// given
+		steps := []Step{
+			{Action: Deactivate, Percentage: 20, Rate: 1},
+			{Action: Ban, Percentage: 80, Rate: 1, MaxInFlight: 5},
+			{Action: Reactivate, Percentage: 20, Rate: 1},
+		}
+
+		// when
+		err := ValidateSteps(steps)
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			steps  []Step
+			errMsg string
+		}{
+			"invalid action": {
+				steps:  []Step{{Action: "suspend", Percentage: 10, Rate: 1}},
+				errMsg: "invalid lifecycle step 1: invalid action 'suspend': must be one of [deactivate reactivate ban]",
+			},
+			"no percentage": {
+				steps:  []Step{{Action: Deactivate, Rate: 1}},
+				errMsg: "invalid lifecycle step 1: invalid percentage value '0': value must be more than 0 and at most 100",
+			},
+			"more than all the users": {
+				steps:  []Step{{Action: Deactivate, Percentage: 101, Rate: 1}},
+				errMsg: "invalid lifecycle step 1: invalid percentage value '101': value must be more than 0 and at most 100",
+			},
+			"no rate": {
+				steps:  []Step{{Action: Deactivate, Percentage: 10}},
+				errMsg: "invalid lifecycle step 1: the rate must be more than 0",
+			},
+			"negative max in-flight": {
+				steps:  []Step{{Action: Deactivate, Percentage: 10, Rate: 1, MaxInFlight: -1}},
+				errMsg: "invalid lifecycle step 1: the max in-flight value must not be negative",
+			},
+			"not enough active users": {
+				steps: []Step{
+					{Action: Deactivate, Percentage: 60, Rate: 1},
+					{Action: Ban, Percentage: 50, Rate: 1},
+				},
+				errMsg: "invalid lifecycle step 2: only 40% of the users are still active",
+			},
+			"reactivate users that were not deactivated": {
+				steps: []Step{
+					{Action: Ban, Percentage: 10, Rate: 1},
+					{Action: Reactivate, Percentage: 10, Rate: 1},
+				},
+				errMsg: "invalid lifecycle step 2: only 0% of the users were deactivated",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// when
+				err := ValidateSteps(tc.steps)
+
+				// then
+				require.EqualError(t, err, tc.errMsg)
+			})
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	// given
+	configuration.DefaultTimeout = time.Millisecond * 1
+	configuration.HostOperatorNamespace = "toolchain-host-operator"
+	usernames := []string{"user0001", "user0002", "user0003", "user0004"}
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+		for _, username := range usernames {
+			require.NoError(t, users.Create(cl, username, configuration.HostOperatorNamespace, "member-1"))
+		}
+		// the spaces are provisioned and deprovisioned like the host operator does
+		cl.MockUpdate = func(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+			if err := cl.Client.Update(ctx, obj, opts...); err != nil {
+				return err
+			}
+			if usersignup, ok := obj.(*toolchainv1alpha1.UserSignup); ok {
+				if states.Deactivated(usersignup) {
+					return client.IgnoreNotFound(cl.Client.Delete(ctx, testspace.NewSpace(configuration.HostOperatorNamespace, usersignup.Name)))
+				}
+				return cl.Client.Create(ctx, readySpace(usersignup.Name))
+			}
+			return nil
+		}
+		cl.MockCreate = func(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+			if err := cl.Client.Create(ctx, obj, opts...); err != nil {
+				return err
+			}
+			if _, ok := obj.(*toolchainv1alpha1.BannedUser); ok {
+				return client.IgnoreNotFound(cl.Client.Delete(ctx, testspace.NewSpace(configuration.HostOperatorNamespace, obj.GetName())))
+			}
+			return nil
+		}
+		cp := newCheckpoint(t)
+		for _, username := range usernames {
+			require.NoError(t, cp.MarkDone(username, checkpoint.IdlerUpdated))
+			require.NoError(t, cp.MarkDone(username, checkpoint.DefaultTemplateApplied))
+		}
+		simulator := New(cl, configuration.HostOperatorNamespace, usernames, cp)
+
+		// when
+		// one user at a time so that the users are picked in a predictable order
+		simulator.Run(context.TODO(), Step{Action: Deactivate, Percentage: 50, Rate: 100, MaxInFlight: 1})
+		simulator.Run(context.TODO(), Step{Action: Reactivate, Percentage: 25, Rate: 100, MaxInFlight: 1})
+		stats := simulator.Run(context.TODO(), Step{Action: Ban, Percentage: 25, Rate: 100, MaxInFlight: 1})
+
+		// then
+		assert.Equal(t, 1, stats.Arrivals)
+		// the users are picked from the end of the list, the reactivated user is active again so it's the next one to be banned
+		assert.True(t, states.Deactivated(getUserSignup(t, cl, "user0003")))
+		assert.False(t, states.Deactivated(getUserSignup(t, cl, "user0004")))
+		bannedUser := &toolchainv1alpha1.BannedUser{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: configuration.HostOperatorNamespace, Name: "user0004"}, bannedUser))
+		assert.Equal(t, []string{"user0001", "user0002"}, simulator.active)
+		assert.Equal(t, []string{"user0003"}, simulator.deactivated)
+		assert.True(t, cp.IsDone("user0003", checkpoint.Deactivated))
+		assert.True(t, cp.IsDone("user0004", checkpoint.Banned))
+		assert.False(t, cp.IsDone("user0004", checkpoint.Deactivated))
+		// the idler and the resources of the templates are deleted together with the namespaces of the deprovisioned users
+		assert.True(t, cp.IsDone("user0001", checkpoint.IdlerUpdated, checkpoint.DefaultTemplateApplied))
+		assert.False(t, cp.IsDone("user0003", checkpoint.IdlerUpdated))
+		assert.False(t, cp.IsDone("user0003", checkpoint.DefaultTemplateApplied))
+		assert.False(t, cp.IsDone("user0004", checkpoint.IdlerUpdated))
+		assert.False(t, cp.IsDone("user0004", checkpoint.DefaultTemplateApplied))
+		assert.True(t, cp.IsDeprovisioned("user0003"))
+		assert.True(t, cp.IsDeprovisioned("user0004"))
+		assert.False(t, cp.IsDeprovisioned("user0001"))
+
+		results := simulator.Results()
+		assert.Contains(t, results, []string{"Lifecycle deactivate Users", "2"})
+		assert.Contains(t, results, []string{"Lifecycle deactivate Errors", "0"})
+		assert.Contains(t, results, []string{"Lifecycle reactivate Users", "1"})
+		assert.Contains(t, results, []string{"Lifecycle ban Users", "1"})
+		assertHasResult(t, results, "Time Per User - deactivate namespaces-deleted p99 (s)")
+		assertHasResult(t, results, "Time Per User - reactivate space-ready max (s)")
+		assertHasResult(t, results, "Time Per User - ban masteruserrecord-deleted p50 (s)")
+	})
+
+	t.Run("errors are counted", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t) // no usersignups
+		simulator := New(cl, configuration.HostOperatorNamespace, usernames, newCheckpoint(t))
+
+		// when
+		simulator.Run(context.TODO(), Step{Action: Deactivate, Percentage: 100, Rate: 100})
+
+		// then
+		results := simulator.Results()
+		assert.Contains(t, results, []string{"Lifecycle deactivate Users", "4"})
+		assert.Contains(t, results, []string{"Lifecycle deactivate Errors", "4"})
+		assert.Empty(t, simulator.deactivated)
+	})
+}
+
+func TestNewFromCheckpoint(t *testing.T) {
+	// given
+	cp := newCheckpoint(t)
+	require.NoError(t, cp.MarkDone("user0003", checkpoint.Deactivated))
+	require.NoError(t, cp.MarkDone("user0004", checkpoint.Banned))
+
+	// when
+	simulator := New(test.NewFakeClient(t), configuration.HostOperatorNamespace, []string{"user0001", "user0002", "user0003", "user0004"}, cp)
+
+	// then
+	assert.Equal(t, []string{"user0001", "user0002"}, simulator.active)
+	assert.Equal(t, []string{"user0003"}, simulator.deactivated)
+}
+
+func TestRunInterrupted(t *testing.T) {
+	// given
+	configuration.DefaultTimeout = time.Millisecond * 1
+	configuration.HostOperatorNamespace = "toolchain-host-operator"
+	cl := test.NewFakeClient(t)
+	simulator := New(cl, configuration.HostOperatorNamespace, []string{"user0001", "user0002", "user0003", "user0004"}, newCheckpoint(t))
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	// when
+	stats := simulator.Run(ctx, Step{Action: Deactivate, Percentage: 50, Rate: 100})
+
+	// then
+	assert.Zero(t, stats.Arrivals)
+	// the users that were not processed are still active
+	assert.Equal(t, []string{"user0001", "user0002", "user0003", "user0004"}, simulator.active)
+	assert.Empty(t, simulator.Results())
+}
+
+func TestResultsOfNilSimulator(t *testing.T) {
+	// given
+	var simulator *Simulator
+
+	// when
+	results := simulator.Results()
+
+	// then
+	assert.Empty(t, results)
+}
+
+func newCheckpoint(t *testing.T) *checkpoint.Checkpoint {
+	cp, err := checkpoint.New(filepath.Join(t.TempDir(), "checkpoint.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, cp.Close())
+	})
+	return cp
+}
+
+func readySpace(name string) *toolchainv1alpha1.Space {
+	return testspace.NewSpace(configuration.HostOperatorNamespace, name, testspace.WithCondition(toolchainv1alpha1.Condition{
+		Type:   toolchainv1alpha1.ConditionReady,
+		Status: corev1.ConditionTrue,
+		Reason: "Provisioned",
+	}))
+}
+
+func getUserSignup(t *testing.T, cl client.Client, name string) *toolchainv1alpha1.UserSignup {
+	usersignup := &toolchainv1alpha1.UserSignup{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: configuration.HostOperatorNamespace, Name: name}, usersignup))
+	return usersignup
+}
+
+func assertHasResult(t *testing.T, results [][]string, name string) {
+	for _, r := range results {
+		if r[0] == name {
+			return
+		}
+	}
+	assert.Failf(t, "result not found", "no result named '%s'", name)
+}
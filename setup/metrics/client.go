@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/codeready-toolchain/toolchain-e2e/setup/metrics/queries"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
 	routev1 "github.com/openshift/api/route/v1"
 	"github.com/prometheus/client_golang/api"
@@ -96,8 +97,37 @@ func (c *httpClient) Do(ctx context.Context, req *http.Request) (*http.Response,
 	return resp, body, err
 }
 
-func GetPrometheusClient(term terminal.Terminal, cl client.Client, token string) prometheus.API {
-	url, err := getPrometheusEndpoint(cl)
+// Backend tells where the prometheus API is served and which series belong to the cluster under test
+type Backend struct {
+	// URL is the address of the prometheus API, eg. the URL of an in-cluster service or of a port-forward. It takes precedence over the route.
+	URL string
+	// Route is the route of the prometheus API, as "<namespace>/<name>"
+	Route string
+	// ClusterSelector is the label matcher of the series of the cluster under test, eg. `cluster="my-cluster"` with a monitoring stack
+	// shared by several clusters, or an empty string to not filter the series
+	ClusterSelector string
+}
+
+// String returns the URL or the route of the prometheus API along with the cluster selector, eg. for the results
+func (b Backend) String() string {
+	endpoint := b.URL
+	if endpoint == "" {
+		endpoint = "route " + b.Route
+	}
+	return fmt.Sprintf("%s {%s}", endpoint, b.ClusterSelector)
+}
+
+// DefaultBackend is the prometheus instance of the OpenShift monitoring stack, reached through its route
+var DefaultBackend = Backend{
+	Route:           OpenshiftMonitoringNS + "/" + PrometheusRouteName,
+	ClusterSelector: queries.DefaultClusterSelector,
+}
+
+// ThanosQuerierRoute is the route of the Thanos querier of the OpenShift monitoring stack, which also serves the metrics of the user workload monitoring
+const ThanosQuerierRoute = OpenshiftMonitoringNS + "/" + ThanosQuerierRouteName
+
+func GetPrometheusClient(term terminal.Terminal, cl client.Client, token string, backend Backend) prometheus.API {
+	url, err := getPrometheusEndpoint(cl, backend)
 	if err != nil {
 		term.Fatalf(err, "error creating client: failed to get prometheus endpoint")
 	}
@@ -109,11 +139,18 @@ func GetPrometheusClient(term terminal.Terminal, cl client.Client, token string)
 	return prometheus.NewAPI(httpClient)
 }
 
-func getPrometheusEndpoint(client client.Client) (string, error) {
+func getPrometheusEndpoint(client client.Client, backend Backend) (string, error) {
+	if backend.URL != "" {
+		return backend.URL, nil
+	}
+	namespace, name, found := strings.Cut(backend.Route, "/")
+	if !found || namespace == "" || name == "" {
+		return "", fmt.Errorf("invalid prometheus route '%s': must be '<namespace>/<name>'", backend.Route)
+	}
 	prometheusRoute := routev1.Route{}
 	if err := client.Get(context.TODO(), types.NamespacedName{
-		Namespace: OpenshiftMonitoringNS,
-		Name:      PrometheusRouteName,
+		Namespace: namespace,
+		Name:      name,
 	}, &prometheusRoute); err != nil {
 		return "", err
 	}
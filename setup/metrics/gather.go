@@ -2,15 +2,19 @@ package metrics
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/codeready-toolchain/toolchain-e2e/setup/auth"
 	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/metrics/queries"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
+	prometheus "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 
 	k8sutil "k8s.io/apimachinery/pkg/util/wait"
@@ -18,8 +22,9 @@ import (
 )
 
 const (
-	OpenshiftMonitoringNS = "openshift-monitoring"
-	PrometheusRouteName   = "prometheus-k8s"
+	OpenshiftMonitoringNS  = "openshift-monitoring"
+	PrometheusRouteName    = "prometheus-k8s"
+	ThanosQuerierRouteName = "thanos-querier"
 
 	OLMOperatorNamespace = "openshift-operator-lifecycle-manager"
 	OLMOperatorWorkload  = "olm-operator"
@@ -28,111 +33,289 @@ const (
 	OSAPIServerWorkload  = "apiserver"
 )
 
+// Gatherer samples the metrics queries periodically and aggregates the samples over the whole run and per segment of the run.
+// It's safe for concurrent use since the samples are recorded by the gathering routine while the results can be computed at any time,
+// eg. by the pre-fatal exit hook.
 type Gatherer struct {
+	mu            sync.Mutex
 	k8sClient     client.Client
 	queryInterval time.Duration
 	mqueries      []queries.Query
 	results       map[string]aggregateResult
-	term          terminal.Terminal
+	segments      []*segment
+	series        map[string]model.Matrix
+	// lastSampled is when each query was last sampled or recorded as a gap, to count the intervals missed by a gap
+	lastSampled map[string]time.Time
+	startTime   time.Time
+	backoff     k8sutil.Backoff
+	term        terminal.Terminal
+}
+
+// segment is a named period of the run, eg. the signups, whose samples are aggregated separately
+type segment struct {
+	name    string
+	start   time.Time
+	end     time.Time
+	results map[string]aggregateResult
+}
+
+func (s segment) duration() time.Duration {
+	if s.end.IsZero() {
+		return time.Since(s.start)
+	}
+	return s.end.Sub(s.start)
+}
+
+// errUnexpectedResultType is returned when a query doesn't return the type of result it's meant to, which retrying the query won't change
+var errUnexpectedResultType = errors.New("unexpected result type")
+
+// defaultBackoff is how failed samples are retried: from 200ms up to 1m between the attempts, the retries of all the queries are also
+// stopped at the end of the sampling interval
+var defaultBackoff = k8sutil.Backoff{
+	Duration: cfg.DefaultRetryInterval,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    12,
+	Cap:      time.Minute,
 }
 
 type aggregateResult struct {
 	sampleCount int
 	max         float64
 	sum         float64
+	// gapCount is the number of samples that could not be retrieved, even after retrying
+	gapCount int
+	// degraded is set when the latest sample could not be retrieved
+	degraded bool
 }
 
 func (r aggregateResult) avg() float64 {
 	return r.sum / float64(r.sampleCount)
 }
 
-// New creates a new gatherer with default queries
-func New(t terminal.Terminal, cl client.Client, token string, interval time.Duration) *Gatherer {
+func (r aggregateResult) add(datapoint float64) aggregateResult {
+	r.max = math.Max(r.max, datapoint)
+	r.sum += datapoint
+	r.sampleCount++
+	r.degraded = false
+	return r
+}
+
+func (r aggregateResult) addGaps(count int) aggregateResult {
+	r.gapCount += count
+	r.degraded = true
+	return r
+}
+
+// New creates a new gatherer with default queries that are run against the given backend
+func New(t terminal.Terminal, cl client.Client, token string, backend Backend, interval time.Duration) *Gatherer {
+	return NewWithPrometheusClient(t, cl, GetPrometheusClient(t, cl, token, backend), backend.ClusterSelector, interval)
+}
+
+// NewWithPrometheusClient creates a new gatherer with default queries that are run with the given prometheus client
+// and select the series of the cluster with the given selector
+func NewWithPrometheusClient(t terminal.Terminal, cl client.Client, prometheusClient prometheus.API, clusterSelector string, interval time.Duration) *Gatherer {
 	g := &Gatherer{
 		k8sClient:     cl,
 		queryInterval: interval,
+		backoff:       defaultBackoff,
 		term:          t,
 	}
 
-	prometheusClient := GetPrometheusClient(t, cl, token)
-
 	// Add default queries
 	g.AddQueries(
-		queries.QueryClusterCPUUtilisation(prometheusClient),
-		queries.QueryClusterMemoryUtilisation(prometheusClient),
-		queries.QueryNodeMemoryUtilisation(prometheusClient),
-		queries.QueryEtcdMemoryUsage(prometheusClient),
-		queries.QueryWorkloadCPUUsage(prometheusClient, OLMOperatorNamespace, OLMOperatorWorkload),
-		queries.QueryWorkloadMemoryUsage(prometheusClient, OLMOperatorNamespace, OLMOperatorWorkload),
-		queries.QueryOpenshiftKubeAPIMemoryUtilisation(prometheusClient),
-		queries.QueryWorkloadCPUUsage(prometheusClient, OSAPIServerNamespace, OSAPIServerWorkload),
-		queries.QueryWorkloadMemoryUsage(prometheusClient, OSAPIServerNamespace, OSAPIServerWorkload),
-		queries.QueryWorkloadCPUUsage(prometheusClient, cfg.HostOperatorNamespace, cfg.HostOperatorWorkload),
-		queries.QueryWorkloadMemoryUsage(prometheusClient, cfg.HostOperatorNamespace, cfg.HostOperatorWorkload),
-		queries.QueryWorkloadCPUUsage(prometheusClient, cfg.MemberOperatorNamespace, cfg.MemberOperatorWorkload),
-		queries.QueryWorkloadMemoryUsage(prometheusClient, cfg.MemberOperatorNamespace, cfg.MemberOperatorWorkload),
+		queries.QueryClusterCPUUtilisation(prometheusClient, clusterSelector),
+		queries.QueryClusterMemoryUtilisation(prometheusClient, clusterSelector),
+		queries.QueryNodeMemoryUtilisation(prometheusClient, clusterSelector),
+		queries.QueryEtcdMemoryUsage(prometheusClient, clusterSelector),
+		queries.QueryWorkloadCPUUsage(prometheusClient, clusterSelector, cfg.HostOperatorNamespace, cfg.HostOperatorWorkload),
+		queries.QueryWorkloadMemoryUsage(prometheusClient, clusterSelector, cfg.HostOperatorNamespace, cfg.HostOperatorWorkload),
+		queries.QueryWorkloadCPUUsage(prometheusClient, clusterSelector, cfg.MemberOperatorNamespace, cfg.MemberOperatorWorkload),
+		queries.QueryWorkloadMemoryUsage(prometheusClient, clusterSelector, cfg.MemberOperatorNamespace, cfg.MemberOperatorWorkload),
 	)
 	g.results = make(map[string]aggregateResult, len(g.mqueries))
+	g.series = make(map[string]model.Matrix, len(g.mqueries))
 
 	return g
 }
 
+// OpenShiftQueries returns the queries of the workloads of the OpenShift control plane and of OLM, which only exist on OpenShift clusters
+func OpenShiftQueries(prometheusClient prometheus.API, clusterSelector string) []queries.Query {
+	return []queries.Query{
+		queries.QueryWorkloadCPUUsage(prometheusClient, clusterSelector, OLMOperatorNamespace, OLMOperatorWorkload),
+		queries.QueryWorkloadMemoryUsage(prometheusClient, clusterSelector, OLMOperatorNamespace, OLMOperatorWorkload),
+		queries.QueryOpenshiftKubeAPIMemoryUtilisation(prometheusClient, clusterSelector),
+		queries.QueryWorkloadCPUUsage(prometheusClient, clusterSelector, OSAPIServerNamespace, OSAPIServerWorkload),
+		queries.QueryWorkloadMemoryUsage(prometheusClient, clusterSelector, OSAPIServerNamespace, OSAPIServerWorkload),
+	}
+}
+
 // nolint
 func NewEmpty(t terminal.Terminal, cl client.Client, interval time.Duration) *Gatherer {
 	g := &Gatherer{
 		k8sClient:     cl,
 		queryInterval: interval,
+		backoff:       defaultBackoff,
 		term:          t,
 	}
 	g.results = make(map[string]aggregateResult, len(g.mqueries))
+	g.series = make(map[string]model.Matrix, len(g.mqueries))
 	return g
 }
 
 func (g *Gatherer) AddQueries(queries ...queries.Query) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.mqueries = append(g.mqueries, queries...)
 }
 
-func (g *Gatherer) StartGathering() chan struct{} {
-	if len(g.mqueries) == 0 {
+// AddDefinitions adds the queries of the given definitions, eg. of a queries file. It fails without adding any query if the name of a definition
+// is already used by a query of the gatherer, eg. a default query, since the results of both queries would be mixed up. Each query is also run
+// once so that an invalid query or a query that doesn't return an instant vector is reported now rather than as gaps during the whole run.
+func (g *Gatherer) AddDefinitions(prometheusClient prometheus.API, definitions []queries.Definition) error {
+	existing := g.queries()
+	for _, d := range definitions {
+		for _, q := range existing {
+			if q.Name() == d.Name {
+				return fmt.Errorf("the name '%s' is already used by a built-in query", d.Name)
+			}
+		}
+	}
+	added := make([]queries.Query, 0, len(definitions))
+	for _, d := range definitions {
+		q := queries.QueryFromDefinition(prometheusClient, d)
+		if err := checkVectorQuery(q); err != nil {
+			return err
+		}
+		added = append(added, q)
+	}
+	g.AddQueries(added...)
+	return nil
+}
+
+// checkVectorQuery runs the query once and returns an error if the query is invalid or doesn't return an instant vector. Transient errors,
+// eg. prometheus being temporarily unavailable, are ignored since the query is retried when it's sampled.
+func checkVectorQuery(q queries.Query) error {
+	val, _, err := q.Execute()
+	if err != nil {
+		if isTransient(err) {
+			return nil
+		}
+		return fmt.Errorf("the query '%s' is invalid: %w", q.Name(), err)
+	}
+	if _, ok := val.(model.Vector); !ok {
+		return fmt.Errorf("the query '%s' must return an instant vector: %w '%s'", q.Name(), errUnexpectedResultType, val.Type())
+	}
+	return nil
+}
+
+// queries returns a copy of the queries so that they can be iterated over without holding the lock
+func (g *Gatherer) queries() []queries.Query {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]queries.Query{}, g.mqueries...)
+}
+
+// StartGathering samples the queries at the interval of the gatherer until the given context is done
+func (g *Gatherer) StartGathering(ctx context.Context) {
+	if len(g.queries()) == 0 {
 		g.term.Infof("Metrics gatherer has no queries defined, skipping metrics gathering...")
-		return nil
+		return
 	}
 
-	stop := make(chan struct{})
+	g.mu.Lock()
+	g.startTime = time.Now()
+	g.mu.Unlock()
 	go func() {
-		k8sutil.Until(func() {
-			for _, q := range g.mqueries {
-				var metricsErr error
-				// added retry mechanism since temporary metrics errors have been observed, poll until the query returns a non-error result or the poll times out
-				err := k8sutil.PollUntilContextTimeout(context.TODO(), cfg.DefaultRetryInterval, cfg.DefaultTimeout, true, func(ctx context.Context) (bool, error) {
-					metricsErr = g.sample(q)
-					return metricsErr == nil, nil
-				})
-				if err != nil {
-					g.term.Fatalf(metricsErr, "metrics error")
-				}
-			}
-		}, g.queryInterval, stop)
+		k8sutil.NonSlidingUntilWithContext(ctx, g.sampleAll, g.queryInterval)
 	}()
-	return stop
+}
+
+// sampleAll samples the queries concurrently. Each query is retried until the next sampling at most, so that a failing query doesn't delay
+// nor use up the sampling interval of the other queries.
+func (g *Gatherer) sampleAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, q := range g.queries() {
+		wg.Add(1)
+		go func(q queries.Query) {
+			defer wg.Done()
+			sampleCtx, cancel := context.WithTimeout(ctx, g.queryInterval)
+			defer cancel()
+			g.sampleWithRetry(sampleCtx, q)
+		}(q)
+	}
+	wg.Wait()
+}
+
+// StartSegment ends the current segment, if any, and starts a new segment with the given name. The samples are aggregated
+// in the current segment in addition to the whole run, so that eg. the memory used while provisioning the users can be told
+// apart from the memory used once the cluster has settled.
+func (g *Gatherer) StartSegment(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	if len(g.segments) > 0 {
+		g.segments[len(g.segments)-1].end = now
+	}
+	g.segments = append(g.segments, &segment{
+		name:    name,
+		start:   now,
+		results: map[string]aggregateResult{},
+	})
+}
+
+// sampleWithRetry samples the given query, retrying with backoff until the given context is done since temporary metrics errors have been observed.
+// When all the attempts fail, a gap is recorded for each interval missed since the previous sample of the query and it's marked as degraded until a later
+// sample succeeds, so that a long run isn't aborted if prometheus restarts. No gap is recorded when the context is canceled, ie. the gathering is stopped.
+func (g *Gatherer) sampleWithRetry(ctx context.Context, q queries.Query) {
+	metricsErr := fmt.Errorf("the sampling interval of %s is over", g.queryInterval)
+	err := k8sutil.ExponentialBackoffWithContext(ctx, g.backoff, func(context.Context) (bool, error) {
+		metricsErr = g.sample(q)
+		return metricsErr == nil, nil
+	})
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+
+	missed := g.sampled(q.Name())
+	if err != nil {
+		g.record(q.Name(), func(r aggregateResult) aggregateResult {
+			return r.addGaps(missed)
+		})
+		g.term.Errorf(metricsErr, "failed to sample the metrics query '%s', %d sampling interval(s) missed, the query is degraded", q.Name(), missed)
+	}
+}
+
+// sampled records that the query with the given name was just sampled and returns the number of sampling intervals since the previous sample,
+// at least one
+func (g *Gatherer) sampled(name string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.lastSampled == nil {
+		g.lastSampled = map[string]time.Time{}
+	}
+	now := time.Now()
+	previous, found := g.lastSampled[name]
+	if !found {
+		previous = g.startTime
+	}
+	g.lastSampled[name] = now
+	if previous.IsZero() || g.queryInterval <= 0 {
+		return 1
+	}
+	return max(1, int(now.Sub(previous)/g.queryInterval))
 }
 
 func (g *Gatherer) sample(q queries.Query) error {
 	val, warnings, err := q.Execute()
-	if err != nil {
-		if strings.Contains(err.Error(), "client error: 403") {
-			url, tokenErr := auth.GetTokenRequestURI(g.k8sClient)
-			if tokenErr != nil {
-				return fmt.Errorf("metrics query failed with 403 (Forbidden): %w", err)
-			}
-			return fmt.Errorf("metrics query failed with 403 (Forbidden) - retrieve a new token from %s: %w", url, err)
-		}
-		return fmt.Errorf("metrics query failed - check whether prometheus is still healthy in the cluster: %w", err)
-	} else if len(warnings) > 0 {
-		return fmt.Errorf("metrics query had unexpected warnings: %w", fmt.Errorf("warnings: %v", warnings))
+	if err := g.checkQueryResult(warnings, err); err != nil {
+		return err
 	}
 
-	vector := val.(model.Vector)
+	vector, ok := val.(model.Vector)
+	if !ok {
+		return fmt.Errorf("%w '%s' of the query %s, an instant vector is expected", errUnexpectedResultType, val.Type(), q.Name())
+	}
 	if len(vector) == 0 {
 		return fmt.Errorf("metrics value could not be retrieved for query %s", q.Name())
 	}
@@ -144,32 +327,107 @@ func (g *Gatherer) sample(q queries.Query) error {
 	}
 	datapoint := vectorSum / float64(len(vector))
 
-	r := g.results[q.Name()]
-	r.max = math.Max(r.max, datapoint)
-	r.sum += datapoint
-	r.sampleCount++
-	g.results[q.Name()] = r
+	g.record(q.Name(), func(r aggregateResult) aggregateResult {
+		return r.add(datapoint)
+	})
+	return nil
+}
+
+// record applies the given update to the aggregated results of the query over the whole run and in the current segment
+func (g *Gatherer) record(name string, update func(aggregateResult) aggregateResult) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.results[name] = update(g.results[name])
+	if len(g.segments) > 0 {
+		current := g.segments[len(g.segments)-1]
+		current.results[name] = update(current.results[name])
+	}
+}
+
+// checkQueryResult returns an error with some guidance if the query failed or had warnings
+func (g *Gatherer) checkQueryResult(warnings prometheus.Warnings, err error) error {
+	if err != nil {
+		if strings.Contains(err.Error(), "client error: 403") {
+			url, tokenErr := auth.GetTokenRequestURI(g.k8sClient)
+			if tokenErr != nil {
+				return fmt.Errorf("metrics query failed with 403 (Forbidden): %w", err)
+			}
+			return fmt.Errorf("metrics query failed with 403 (Forbidden) - retrieve a new token from %s: %w", url, err)
+		}
+		return fmt.Errorf("metrics query failed - check whether prometheus is still healthy in the cluster: %w", err)
+	} else if len(warnings) > 0 {
+		return fmt.Errorf("metrics query had unexpected warnings: %w", fmt.Errorf("warnings: %v", warnings))
+	}
 	return nil
 }
 
-// ComputeResults iterates through each query and aggregates the results
+// ComputeResults iterates through each query and aggregates the results over the whole run, then per segment
 func (g *Gatherer) ComputeResults() [][]string {
+	// the results are copied so that the lock isn't held while the rows are formatted, since an invalid query is a fatal error and
+	// the pre-fatal exit hook computes the results again
+	g.mu.Lock()
+	mqueries := append([]queries.Query{}, g.mqueries...)
+	runResults := make(map[string]aggregateResult, len(g.results))
+	for name, r := range g.results {
+		runResults[name] = r
+	}
+	segments := make([]segment, 0, len(g.segments))
+	for _, s := range g.segments {
+		copied := *s
+		copied.results = make(map[string]aggregateResult, len(s.results))
+		for name, r := range s.results {
+			copied.results[name] = r
+		}
+		segments = append(segments, copied)
+	}
+	g.mu.Unlock()
+
 	var tuples [][]string
-	for _, q := range g.mqueries {
-		result := g.results[q.Name()]
-		switch q.ResultType() {
-		case "percentage":
-			tuples = append(tuples, []string{fmt.Sprintf("Average %s (%%)", q.Name()), percentage(result.avg())})
-			tuples = append(tuples, []string{fmt.Sprintf("Max %s (%%)", q.Name()), percentage(result.max)})
-		case "memory":
-			tuples = append(tuples, []string{fmt.Sprintf("Average %s (MB)", q.Name()), bytesToMBString(result.avg())})
-			tuples = append(tuples, []string{fmt.Sprintf("Max %s (MB)", q.Name()), bytesToMBString(result.max)})
-		case "simple":
-			tuples = append(tuples, []string{fmt.Sprintf("Average %s", q.Name()), simple(result.avg())})
-			tuples = append(tuples, []string{fmt.Sprintf("Max %s", q.Name()), simple(result.max)})
-		default:
-			g.term.Fatalf(fmt.Errorf("query %s is missing a result type", q.Name()), "invalid query")
+	var degraded []string
+	for _, q := range mqueries {
+		result := runResults[q.Name()]
+		tuples = append(tuples, g.aggregateRows(q, q.Name(), result)...)
+		tuples = append(tuples, []string{fmt.Sprintf("Number of %s Samples", q.Name()), strconv.Itoa(result.sampleCount)})
+		tuples = append(tuples, []string{fmt.Sprintf("Number of %s Gaps", q.Name()), strconv.Itoa(result.gapCount)})
+		if result.degraded {
+			degraded = append(degraded, q.Name())
+		}
+	}
+	if len(degraded) > 0 {
+		tuples = append(tuples, []string{"Degraded Metrics Queries", strings.Join(degraded, ", ")})
+	}
+	for _, s := range segments {
+		tuples = append(tuples, []string{fmt.Sprintf("Duration - %s (m)", s.name), fmt.Sprintf("%.2f", s.duration().Minutes())})
+		for _, q := range mqueries {
+			tuples = append(tuples, g.aggregateRows(q, fmt.Sprintf("%s - %s", q.Name(), s.name), s.results[q.Name()])...)
 		}
 	}
 	return tuples
 }
+
+// aggregateRows returns the average and max rows of the given result of the query, with the given label
+func (g *Gatherer) aggregateRows(q queries.Query, label string, result aggregateResult) [][]string {
+	var avgValue, maxValue, unit string
+	switch q.ResultType() {
+	case "percentage":
+		avgValue, maxValue, unit = percentage(result.avg()), percentage(result.max), " (%)"
+	case "memory":
+		avgValue, maxValue, unit = bytesToMBString(result.avg()), bytesToMBString(result.max), " (MB)"
+	case "simple":
+		avgValue, maxValue = simple(result.avg()), simple(result.max)
+	case "seconds":
+		avgValue, maxValue, unit = simple(result.avg()), simple(result.max), " (s)"
+	case "rate":
+		avgValue, maxValue, unit = simple(result.avg()), simple(result.max), " (/s)"
+	default:
+		g.term.Fatalf(fmt.Errorf("query %s is missing a result type", q.Name()), "invalid query")
+	}
+	if result.sampleCount == 0 {
+		// no value at all rather than a misleading one
+		avgValue, maxValue = "n/a", "n/a"
+	}
+	return [][]string{
+		{fmt.Sprintf("Average %s%s", label, unit), avgValue},
+		{fmt.Sprintf("Max %s%s", label, unit), maxValue},
+	}
+}
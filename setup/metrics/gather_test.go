@@ -1,11 +1,15 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/codeready-toolchain/toolchain-e2e/setup/metrics/queries"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
@@ -108,6 +112,18 @@ func TestExecuteQueryAndProcessResult(t *testing.T) {
 				resultLen: 0,
 			},
 		},
+		{
+			query: testQuery{
+				name: "scalar result",
+				sample: queryResult{
+					val: &model.Scalar{Value: 40, Timestamp: testTime},
+				},
+			},
+			exp: expected{
+				err:       "unexpected result type 'scalar' of the query scalar result, an instant vector is expected",
+				resultLen: 0,
+			},
+		},
 		{
 			query: testQuery{
 				name: "query permission error",
@@ -162,6 +178,8 @@ type testQuery struct {
 	name        string
 	initResults aggregateResult
 	sample      queryResult
+	series      queryResult
+	resultType  string
 }
 
 type expected struct {
@@ -187,6 +205,140 @@ func (q testQuery) Execute() (model.Value, prometheus.Warnings, error) {
 	return result.val, result.warn, result.err
 }
 
+func (q testQuery) ExecuteRange(_ prometheus.Range) (model.Value, prometheus.Warnings, error) {
+	result := q.series
+	return result.val, result.warn, result.err
+}
+
 func (q testQuery) ResultType() string {
-	return "memory"
+	if q.resultType == "" {
+		return "memory"
+	}
+	return q.resultType
+}
+
+func TestComputeResults(t *testing.T) {
+	// given
+	g := &Gatherer{
+		mqueries: []queries.Query{
+			testQuery{name: "cpu", resultType: "percentage"},
+			testQuery{name: "memory"},
+			testQuery{name: "count", resultType: "simple"},
+			testQuery{name: "latency", resultType: "seconds"},
+			testQuery{name: "errors", resultType: "rate"},
+			testQuery{name: "down"},
+		},
+		results: map[string]aggregateResult{
+			"cpu":     {sampleCount: 2, sum: 0.5, max: 0.3},
+			"memory":  {sampleCount: 2, sum: 3 * MB, max: 2 * MB},
+			"count":   {sampleCount: 2, sum: 10, max: 6},
+			"latency": {sampleCount: 2, sum: 3, max: 2},
+			"errors":  {sampleCount: 2, sum: 0.2, max: 0.15, gapCount: 1},
+			"down":    {gapCount: 3, degraded: true},
+		},
+	}
+
+	// when
+	results := g.ComputeResults()
+
+	// then
+	require.Equal(t, [][]string{
+		{"Average cpu (%)", "25.00"},
+		{"Max cpu (%)", "30.00"},
+		{"Number of cpu Samples", "2"},
+		{"Number of cpu Gaps", "0"},
+		{"Average memory (MB)", "1.50"},
+		{"Max memory (MB)", "2.00"},
+		{"Number of memory Samples", "2"},
+		{"Number of memory Gaps", "0"},
+		{"Average count", "5.0000"},
+		{"Max count", "6.0000"},
+		{"Number of count Samples", "2"},
+		{"Number of count Gaps", "0"},
+		{"Average latency (s)", "1.5000"},
+		{"Max latency (s)", "2.0000"},
+		{"Number of latency Samples", "2"},
+		{"Number of latency Gaps", "0"},
+		{"Average errors (/s)", "0.1000"},
+		{"Max errors (/s)", "0.1500"},
+		{"Number of errors Samples", "2"},
+		{"Number of errors Gaps", "1"},
+		{"Average down (MB)", "n/a"},
+		{"Max down (MB)", "n/a"},
+		{"Number of down Samples", "0"},
+		{"Number of down Gaps", "3"},
+		{"Degraded Metrics Queries", "down"},
+	}, results)
+}
+
+func TestSegments(t *testing.T) {
+	// given
+	sampleOf := func(value model.SampleValue) testQuery {
+		return testQuery{name: "memory", sample: queryResult{val: model.Vector{&model.Sample{Value: value}}}}
+	}
+	g := NewEmpty(nil, test.NewFakeClient(t), time.Minute)
+	g.AddQueries(testQuery{name: "memory"})
+
+	// when
+	require.NoError(t, g.sample(sampleOf(MB))) // before the first segment, only part of the whole run
+	g.StartSegment("signups")
+	require.NoError(t, g.sample(sampleOf(3*MB)))
+	require.NoError(t, g.sample(sampleOf(5*MB)))
+	g.StartSegment("settle")
+	require.NoError(t, g.sample(sampleOf(2*MB)))
+	g.StartSegment("empty")
+	results := g.ComputeResults()
+
+	// then
+	require.Len(t, results, 13)
+	assert.Equal(t, [][]string{
+		{"Average memory (MB)", "2.75"},
+		{"Max memory (MB)", "5.00"},
+		{"Number of memory Samples", "4"},
+		{"Number of memory Gaps", "0"},
+	}, results[:4])
+	assert.Equal(t, "Duration - signups (m)", results[4][0])
+	assert.Equal(t, [][]string{
+		{"Average memory - signups (MB)", "4.00"},
+		{"Max memory - signups (MB)", "5.00"},
+	}, results[5:7])
+	assert.Equal(t, "Duration - settle (m)", results[7][0])
+	assert.Equal(t, [][]string{
+		{"Average memory - settle (MB)", "2.00"},
+		{"Max memory - settle (MB)", "2.00"},
+	}, results[8:10])
+	assert.Equal(t, "Duration - empty (m)", results[10][0])
+	assert.Equal(t, [][]string{
+		{"Average memory - empty (MB)", "n/a"},
+		{"Max memory - empty (MB)", "n/a"},
+	}, results[11:13])
+}
+
+func TestConcurrentGathering(t *testing.T) {
+	// given
+	g := NewEmpty(nil, test.NewFakeClient(t), time.Millisecond)
+	g.AddQueries(testQuery{name: "memory", sample: queryResult{val: model.Vector{&model.Sample{Value: MB}}}})
+
+	// when
+	ctx, stop := context.WithCancel(context.TODO())
+	g.StartGathering(ctx)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				g.StartSegment(fmt.Sprintf("segment-%d-%d", i, j))
+				g.ComputeResults()
+				time.Sleep(time.Millisecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+	stop()
+
+	// then
+	results := g.ComputeResults()
+	assert.Contains(t, results, []string{"Max memory (MB)", "1.00"})
+	assert.Len(t, results, 4+100*3)
 }
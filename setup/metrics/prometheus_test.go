@@ -0,0 +1,391 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/metrics/queries"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/test"
+
+	routev1 "github.com/openshift/api/route/v1"
+	prometheus "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sutil "k8s.io/apimachinery/pkg/util/wait"
+)
+
+func newFakePrometheusClient(t *testing.T, p *test.FakePrometheus) prometheus.API {
+	apiClient, err := Client(p.URL, "token")
+	require.NoError(t, err)
+	return prometheus.NewAPI(apiClient)
+}
+
+func TestGathererWithFakePrometheus(t *testing.T) {
+	t.Run("default queries", func(t *testing.T) {
+		// given
+		p := test.NewFakePrometheus(t)
+		p.RecordDefault(test.VectorResponse(MB, 3*MB))
+		g := NewWithPrometheusClient(nil, test.NewFakeClient(t), newFakePrometheusClient(t, p), queries.DefaultClusterSelector, time.Minute)
+		g.AddQueries(OpenShiftQueries(newFakePrometheusClient(t, p), queries.DefaultClusterSelector)...)
+
+		// when
+		for _, q := range g.mqueries {
+			require.NoError(t, g.sample(q))
+		}
+		results := g.ComputeResults()
+
+		// then
+		require.Len(t, results, 4*len(g.mqueries))
+		assert.Contains(t, results, []string{"Average etcd Instance Memory Usage (MB)", "2.00"})
+		assert.Contains(t, results, []string{"Max etcd Instance Memory Usage (MB)", "2.00"})
+		assert.Contains(t, results, []string{"Average openshift-kube-apiserver (MB)", "2.00"})
+	})
+
+	t.Run("default queries with cluster selector", func(t *testing.T) {
+		// given
+		p := test.NewFakePrometheus(t)
+		p.Record(`1 - avg(rate(node_cpu_seconds_total{cluster="sandbox", mode="idle"}[5m]))`, test.VectorResponse(0.5))
+		g := NewWithPrometheusClient(nil, test.NewFakeClient(t), newFakePrometheusClient(t, p), `cluster="sandbox"`, time.Minute)
+
+		// when
+		err := g.sample(g.mqueries[0])
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, p.Calls(`1 - avg(rate(node_cpu_seconds_total{cluster="sandbox", mode="idle"}[5m]))`))
+	})
+
+	t.Run("recorded responses", func(t *testing.T) {
+		// given
+		p := test.NewFakePrometheus(t)
+		p.LoadRecording(t, "testdata/recording.json")
+		q := queries.QueryFromDefinition(newFakePrometheusClient(t, p), queries.Definition{
+			Name:       "host-operator workqueue depth",
+			Query:      `sum(workqueue_depth{namespace="toolchain-host-operator"})`,
+			ResultType: queries.Simple,
+		})
+		g := NewEmpty(nil, test.NewFakeClient(t), time.Minute)
+		g.AddQueries(q)
+
+		// when
+		first := g.sample(q)
+		second := g.sample(q) // average of the 2 samples of the vector
+		empty := g.sample(q)
+		warnings := g.sample(q)
+		forbidden := g.sample(q)
+
+		// then
+		require.NoError(t, first)
+		require.NoError(t, second)
+		require.EqualError(t, empty, "metrics value could not be retrieved for query host-operator workqueue depth")
+		require.EqualError(t, warnings, "metrics query had unexpected warnings: warnings: [partial data]")
+		require.EqualError(t, forbidden, "metrics query failed with 403 (Forbidden): client_error: client error: 403")
+		assert.Equal(t, 5, p.Calls(`sum(workqueue_depth{namespace="toolchain-host-operator"})`))
+		assert.Equal(t, [][]string{
+			{"Average host-operator workqueue depth", "20.0000"},
+			{"Max host-operator workqueue depth", "30.0000"},
+			{"Number of host-operator workqueue depth Samples", "2"},
+			{"Number of host-operator workqueue depth Gaps", "0"},
+		}, g.ComputeResults())
+	})
+
+	t.Run("retries and gaps", func(t *testing.T) {
+		// given
+		p := test.NewFakePrometheus(t)
+		p.Record("up",
+			test.ErrorResponse(http.StatusServiceUnavailable, "restarting"), // retried
+			test.VectorResponse(1),
+			test.ErrorResponse(http.StatusServiceUnavailable, "restarting"), // gap
+			test.ErrorResponse(http.StatusServiceUnavailable, "restarting"),
+			test.VectorResponse(3),
+		)
+		q := queries.QueryFromDefinition(newFakePrometheusClient(t, p), queries.Definition{Name: "up", Query: "up", ResultType: queries.Simple})
+		out := &bytes.Buffer{}
+		term := terminal.New(func() io.Reader { return nil }, func() io.Writer { return out }, false)
+		g := NewEmpty(term, test.NewFakeClient(t), time.Minute)
+		g.backoff = k8sutil.Backoff{Duration: time.Millisecond, Steps: 2}
+		g.AddQueries(q)
+
+		t.Run("retried sample", func(t *testing.T) {
+			// when
+			g.sampleWithRetry(context.TODO(), q)
+
+			// then
+			assert.Equal(t, aggregateResult{sampleCount: 1, sum: 1, max: 1}, g.results["up"])
+		})
+
+		t.Run("gaps", func(t *testing.T) {
+			// given the previous sample was 3 intervals ago
+			g.lastSampled["up"] = time.Now().Add(-3 * time.Minute)
+
+			// when
+			g.sampleWithRetry(context.TODO(), q)
+
+			// then
+			assert.Equal(t, aggregateResult{sampleCount: 1, sum: 1, max: 1, gapCount: 3, degraded: true}, g.results["up"])
+			assert.Contains(t, out.String(), "failed to sample the metrics query 'up', 3 sampling interval(s) missed, the query is degraded")
+			assert.Contains(t, g.ComputeResults(), []string{"Degraded Metrics Queries", "up"})
+		})
+
+		t.Run("recovered", func(t *testing.T) {
+			// when
+			g.sampleWithRetry(context.TODO(), q)
+
+			// then
+			assert.Equal(t, aggregateResult{sampleCount: 2, sum: 4, max: 3, gapCount: 3}, g.results["up"])
+			assert.Equal(t, [][]string{
+				{"Average up", "2.0000"},
+				{"Max up", "3.0000"},
+				{"Number of up Samples", "2"},
+				{"Number of up Gaps", "3"},
+			}, g.ComputeResults())
+		})
+
+		t.Run("sampling interval over", func(t *testing.T) {
+			// given
+			ctx, cancel := context.WithTimeout(context.TODO(), 0)
+			defer cancel()
+
+			// when
+			g.sampleWithRetry(ctx, q)
+
+			// then
+			assert.Equal(t, aggregateResult{sampleCount: 2, sum: 4, max: 3, gapCount: 4, degraded: true}, g.results["up"])
+			assert.Contains(t, out.String(), "the sampling interval of 1m0s is over")
+		})
+
+		t.Run("gathering stopped", func(t *testing.T) {
+			// given
+			ctx, cancel := context.WithCancel(context.TODO())
+			cancel()
+
+			// when
+			g.sampleWithRetry(ctx, q)
+
+			// then
+			assert.Equal(t, aggregateResult{sampleCount: 2, sum: 4, max: 3, gapCount: 4, degraded: true}, g.results["up"])
+		})
+	})
+
+	t.Run("failing query", func(t *testing.T) {
+		// given
+		p := test.NewFakePrometheus(t)
+		p.Record("unavailable", test.ErrorResponse(http.StatusServiceUnavailable, "restarting"))
+		p.Record("up", test.VectorResponse(1))
+		out := &bytes.Buffer{}
+		term := terminal.New(func() io.Reader { return nil }, func() io.Writer { return out }, false)
+		g := NewEmpty(term, test.NewFakeClient(t), 200*time.Millisecond)
+		g.backoff = k8sutil.Backoff{Duration: 10 * time.Millisecond, Steps: 1000}
+		for _, name := range []string{"unavailable", "up"} {
+			g.AddQueries(queries.QueryFromDefinition(newFakePrometheusClient(t, p), queries.Definition{Name: name, Query: name, ResultType: queries.Simple}))
+		}
+
+		// when
+		g.sampleAll(context.TODO())
+
+		// then the retries of the failing query don't use up the sampling interval of the other query
+		assert.Equal(t, aggregateResult{gapCount: 1, degraded: true}, g.results["unavailable"])
+		assert.Equal(t, aggregateResult{sampleCount: 1, sum: 1, max: 1}, g.results["up"])
+		assert.Contains(t, out.String(), "failed to sample the metrics query 'unavailable'")
+		assert.NotContains(t, out.String(), "failed to sample the metrics query 'up'")
+	})
+
+	t.Run("unknown query", func(t *testing.T) {
+		// given
+		p := test.NewFakePrometheus(t)
+		q := queries.QueryFromDefinition(newFakePrometheusClient(t, p), queries.Definition{Name: "unknown", Query: "up", ResultType: queries.Simple})
+		g := NewEmpty(nil, test.NewFakeClient(t), time.Minute)
+
+		// when
+		err := g.sample(q)
+
+		// then
+		require.EqualError(t, err, "metrics query failed - check whether prometheus is still healthy in the cluster: bad_data: no recorded response for query 'up'")
+	})
+
+	t.Run("range query", func(t *testing.T) {
+		// given
+		p := test.NewFakePrometheus(t)
+		start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+		p.Record("up", test.MatrixResponse(start, time.Minute, 1, 2, 3))
+		q := queries.QueryFromDefinition(newFakePrometheusClient(t, p), queries.Definition{Name: "up", Query: "up", ResultType: queries.Simple})
+		g := NewEmpty(nil, test.NewFakeClient(t), time.Minute)
+		g.AddQueries(q)
+		g.startTime = start
+
+		// when
+		err := g.CaptureSeries(time.Minute)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, g.series["up"], 1)
+		require.Len(t, g.series["up"][0].Values, 3)
+		assert.InDelta(t, 3, float64(g.series["up"][0].Values[2].Value), 0.01)
+		assert.Equal(t, start.Add(2*time.Minute), g.series["up"][0].Values[2].Timestamp.Time().UTC())
+	})
+
+	t.Run("range queries with failures", func(t *testing.T) {
+		// given
+		p := test.NewFakePrometheus(t)
+		start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+		p.Record("invalid", test.ErrorResponse(http.StatusBadRequest, "parse error"))
+		p.Record("unavailable", test.ErrorResponse(http.StatusServiceUnavailable, "unavailable"))
+		p.Record("flaky", test.ErrorResponse(http.StatusServiceUnavailable, "unavailable"), test.MatrixResponse(start, time.Minute, 1, 2))
+		p.Record("up", test.MatrixResponse(start, time.Minute, 1, 2, 3))
+		g := NewEmpty(nil, test.NewFakeClient(t), time.Minute)
+		for _, name := range []string{"invalid", "unavailable", "flaky", "up"} {
+			g.AddQueries(queries.QueryFromDefinition(newFakePrometheusClient(t, p), queries.Definition{Name: name, Query: name, ResultType: queries.Simple}))
+		}
+		g.startTime = start
+
+		// when
+		err := g.CaptureSeries(time.Minute)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to capture the series of the query 'invalid'")
+		assert.Contains(t, err.Error(), "failed to capture the series of the query 'unavailable'")
+		assert.NotContains(t, err.Error(), "'flaky'")
+		assert.NotContains(t, err.Error(), "'up'")
+		assert.Equal(t, 1, p.Calls("invalid")) // not retried
+		assert.Equal(t, seriesBackoff.Steps, p.Calls("unavailable"))
+		assert.Len(t, g.series["flaky"], 1)
+		assert.Len(t, g.series["up"], 1)
+		assert.NotContains(t, g.series, "invalid")
+		assert.NotContains(t, g.series, "unavailable")
+	})
+}
+
+func TestAddDefinitions(t *testing.T) {
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		p := test.NewFakePrometheus(t)
+		p.Record("sum(workqueue_depth)", test.VectorResponse(3))
+		p.Record("sum(rest_client_requests_total)", test.ErrorResponse(http.StatusServiceUnavailable, "unavailable"))
+		g := NewEmpty(nil, test.NewFakeClient(t), time.Minute)
+		g.AddQueries(testQuery{name: "memory"})
+
+		// when
+		err := g.AddDefinitions(newFakePrometheusClient(t, p), []queries.Definition{
+			{Name: "workqueue depth", Query: "sum(workqueue_depth)", ResultType: queries.Simple},
+			// prometheus being unavailable when the queries are added doesn't tell whether the query is valid
+			{Name: "rest client requests", Query: "sum(rest_client_requests_total)", ResultType: queries.Simple},
+		})
+
+		// then
+		require.NoError(t, err)
+		names := []string{}
+		for _, q := range g.queries() {
+			names = append(names, q.Name())
+		}
+		assert.Equal(t, []string{"memory", "workqueue depth", "rest client requests"}, names)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("name already used", func(t *testing.T) {
+			// given
+			p := test.NewFakePrometheus(t)
+			p.RecordDefault(test.VectorResponse(3))
+			g := NewEmpty(nil, test.NewFakeClient(t), time.Minute)
+			g.AddQueries(testQuery{name: "memory"})
+
+			// when
+			err := g.AddDefinitions(newFakePrometheusClient(t, p), []queries.Definition{
+				{Name: "workqueue depth", Query: "sum(workqueue_depth)", ResultType: queries.Simple},
+				{Name: "memory", Query: "sum(container_memory_working_set_bytes)", ResultType: queries.Memory},
+			})
+
+			// then
+			require.EqualError(t, err, "the name 'memory' is already used by a built-in query")
+			assert.Len(t, g.queries(), 1)
+		})
+
+		t.Run("invalid query", func(t *testing.T) {
+			// given
+			p := test.NewFakePrometheus(t)
+			p.Record("sum(workqueue_depth)", test.VectorResponse(3))
+			p.Record("sum(", test.ErrorResponse(http.StatusBadRequest, "parse error"))
+			g := NewEmpty(nil, test.NewFakeClient(t), time.Minute)
+
+			// when
+			err := g.AddDefinitions(newFakePrometheusClient(t, p), []queries.Definition{
+				{Name: "workqueue depth", Query: "sum(workqueue_depth)", ResultType: queries.Simple},
+				{Name: "invalid", Query: "sum(", ResultType: queries.Simple},
+			})
+
+			// then
+			require.EqualError(t, err, "the query 'invalid' is invalid: bad_data: parse error")
+			assert.Empty(t, g.queries())
+		})
+
+		t.Run("scalar result", func(t *testing.T) {
+			// given
+			p := test.NewFakePrometheus(t)
+			p.Record("scalar(sum(workqueue_depth))", test.ScalarResponse(3))
+			g := NewEmpty(nil, test.NewFakeClient(t), time.Minute)
+
+			// when
+			err := g.AddDefinitions(newFakePrometheusClient(t, p), []queries.Definition{
+				{Name: "workqueue depth", Query: "scalar(sum(workqueue_depth))", ResultType: queries.Simple},
+			})
+
+			// then
+			require.EqualError(t, err, "the query 'workqueue depth' must return an instant vector: unexpected result type 'scalar'")
+			assert.Empty(t, g.queries())
+		})
+	})
+}
+
+func TestPrometheusEndpoint(t *testing.T) {
+	route := &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{Namespace: OpenshiftMonitoringNS, Name: ThanosQuerierRouteName},
+		Spec:       routev1.RouteSpec{Host: "thanos-querier.apps.example.com"},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		t.Run("url", func(t *testing.T) {
+			// when
+			endpoint, err := getPrometheusEndpoint(test.NewFakeClient(t), Backend{URL: "http://prometheus.monitoring.svc:9090", Route: ThanosQuerierRoute})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "http://prometheus.monitoring.svc:9090", endpoint)
+		})
+
+		t.Run("route", func(t *testing.T) {
+			// when
+			endpoint, err := getPrometheusEndpoint(test.NewFakeClient(t, route), Backend{Route: ThanosQuerierRoute})
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "https://thanos-querier.apps.example.com", endpoint)
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("invalid route", func(t *testing.T) {
+			// when
+			_, err := getPrometheusEndpoint(test.NewFakeClient(t, route), Backend{Route: "thanos-querier"})
+
+			// then
+			require.EqualError(t, err, "invalid prometheus route 'thanos-querier': must be '<namespace>/<name>'")
+		})
+
+		t.Run("route not found", func(t *testing.T) {
+			// when
+			_, err := getPrometheusEndpoint(test.NewFakeClient(t, route), DefaultBackend)
+
+			// then
+			require.Error(t, err)
+			assert.True(t, apierrors.IsNotFound(err))
+		})
+	})
+}
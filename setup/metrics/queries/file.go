@@ -0,0 +1,72 @@
+package queries
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	prometheus "github.com/prometheus/client_golang/api/prometheus/v1"
+
+	"github.com/ghodss/yaml"
+)
+
+// Definition is a query defined in a queries file
+type Definition struct {
+	Name       string     `json:"name"`
+	Query      string     `json:"query"`
+	ResultType ResultType `json:"resultType"`
+}
+
+// LoadDefinitions reads the query definitions from the YAML file at the given path, which contains a list of entries
+// with the name, the PromQL query and the result type of each query
+func LoadDefinitions(path string) ([]Definition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid queries file '%s': %w", path, err)
+	}
+	var definitions []Definition
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&definitions); err != nil {
+		return nil, fmt.Errorf("invalid queries file '%s': %w", path, err)
+	}
+
+	names := map[string]bool{}
+	for i, d := range definitions {
+		if d.Name == "" || d.Query == "" {
+			return nil, fmt.Errorf("invalid queries file '%s': the name and the query of the query #%d must be set", path, i+1)
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("invalid queries file '%s': the name '%s' is used by several queries", path, d.Name)
+		}
+		names[d.Name] = true
+		if !isResultType(d.ResultType) {
+			return nil, fmt.Errorf("invalid queries file '%s': invalid result type '%s' of the query '%s': must be one of %v", path, d.ResultType, d.Name, ResultTypes)
+		}
+	}
+	return definitions, nil
+}
+
+func isResultType(t ResultType) bool {
+	for _, rt := range ResultTypes {
+		if t == rt {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryFromDefinition returns the query of the given definition
+func QueryFromDefinition(apiClient prometheus.API, d Definition) *BaseQuery {
+	return &BaseQuery{
+		apiClient:  apiClient,
+		name:       d.Name,
+		query:      d.Query,
+		resultType: d.ResultType,
+	}
+}
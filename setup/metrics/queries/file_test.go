@@ -0,0 +1,93 @@
+package queries
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefinitions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// given
+		path := writeQueries(t, `
+- name: host-operator reconcile errors
+  query: sum(rate(controller_runtime_reconcile_errors_total{namespace="toolchain-host-operator"}[5m]))
+  resultType: rate
+- name: host-operator workqueue depth
+  query: sum(workqueue_depth{namespace="toolchain-host-operator"})
+  resultType: simple
+`)
+
+		// when
+		definitions, err := LoadDefinitions(path)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []Definition{
+			{Name: "host-operator reconcile errors", Query: `sum(rate(controller_runtime_reconcile_errors_total{namespace="toolchain-host-operator"}[5m]))`, ResultType: Rate},
+			{Name: "host-operator workqueue depth", Query: `sum(workqueue_depth{namespace="toolchain-host-operator"})`, ResultType: Simple},
+		}, definitions)
+		q := QueryFromDefinition(nil, definitions[0])
+		assert.Equal(t, "host-operator reconcile errors", q.Name())
+		assert.Equal(t, "rate", q.ResultType())
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("file not found", func(t *testing.T) {
+			_, err := LoadDefinitions(filepath.Join(t.TempDir(), "not-found.yaml"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+
+		for name, tc := range map[string]struct {
+			content string
+			err     string
+		}{
+			"unknown field": {
+				content: `- name: q
+  expr: up
+  resultType: simple`,
+				err: `json: unknown field "expr"`,
+			},
+			"missing query": {
+				content: `- name: q
+  resultType: simple`,
+				err: "the name and the query of the query #1 must be set",
+			},
+			"duplicate name": {
+				content: `- name: q
+  query: up
+  resultType: simple
+- name: q
+  query: down
+  resultType: simple`,
+				err: "the name 'q' is used by several queries",
+			},
+			"invalid result type": {
+				content: `- name: q
+  query: up
+  resultType: bytes`,
+				err: "invalid result type 'bytes' of the query 'q': must be one of [percentage memory simple seconds rate]",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				path := writeQueries(t, tc.content)
+
+				// when
+				_, err := LoadDefinitions(path)
+
+				// then
+				require.EqualError(t, err, "invalid queries file '"+path+"': "+tc.err)
+			})
+		}
+	})
+}
+
+func writeQueries(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "queries.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
@@ -3,6 +3,7 @@ package queries
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	prometheus "github.com/prometheus/client_golang/api/prometheus/v1"
@@ -15,11 +16,17 @@ const (
 	Percentage ResultType = "percentage"
 	Memory     ResultType = "memory"
 	Simple     ResultType = "simple"
+	Seconds    ResultType = "seconds"
+	Rate       ResultType = "rate"
 )
 
+// ResultTypes lists all the supported result types
+var ResultTypes = []ResultType{Percentage, Memory, Simple, Seconds, Rate}
+
 type Query interface {
 	Name() string
 	Execute() (model.Value, prometheus.Warnings, error)
+	ExecuteRange(r prometheus.Range) (model.Value, prometheus.Warnings, error)
 	ResultType() string
 }
 
@@ -38,52 +45,69 @@ func (b *BaseQuery) Execute() (model.Value, prometheus.Warnings, error) {
 	return b.apiClient.Query(context.TODO(), b.query, time.Now())
 }
 
+func (b *BaseQuery) ExecuteRange(r prometheus.Range) (model.Value, prometheus.Warnings, error) {
+	return b.apiClient.QueryRange(context.TODO(), b.query, r)
+}
+
 func (b *BaseQuery) ResultType() string {
 	return string(b.resultType)
 }
 
-func QueryOpenshiftKubeAPIMemoryUtilisation(apiClient prometheus.API) *BaseQuery {
+// DefaultClusterSelector selects the series of the cluster in the monitoring stack of an OpenShift cluster, which have no cluster label.
+// It also matches all the series of a Prometheus instance that only monitors the cluster under test, eg. on vanilla Kubernetes.
+const DefaultClusterSelector = `cluster=""`
+
+// selector returns the label matchers of a series selector made of the given cluster selector, if any, and the given matchers
+func selector(clusterSelector string, matchers ...string) string {
+	if clusterSelector != "" {
+		matchers = append([]string{clusterSelector}, matchers...)
+	}
+	return strings.Join(matchers, ", ")
+}
+
+func QueryOpenshiftKubeAPIMemoryUtilisation(apiClient prometheus.API, clusterSelector string) *BaseQuery {
 	return &BaseQuery{
 		apiClient:  apiClient,
 		name:       "openshift-kube-apiserver",
-		query:      `sum(container_memory_working_set_bytes{job="kubelet", metrics_path="/metrics/cadvisor", cluster="", namespace="openshift-kube-apiserver", container!="", image!=""})`,
+		query:      fmt.Sprintf(`sum(container_memory_working_set_bytes{%s})`, selector(clusterSelector, `job="kubelet"`, `metrics_path="/metrics/cadvisor"`, `namespace="openshift-kube-apiserver"`, `container!=""`, `image!=""`)),
 		resultType: Memory,
 	}
 }
 
-func QueryEtcdMemoryUsage(apiClient prometheus.API) *BaseQuery {
+func QueryEtcdMemoryUsage(apiClient prometheus.API, clusterSelector string) *BaseQuery {
 	return &BaseQuery{
 		apiClient:  apiClient,
 		name:       "etcd Instance Memory Usage",
-		query:      `process_resident_memory_bytes{job="etcd"}`,
+		query:      fmt.Sprintf(`process_resident_memory_bytes{%s}`, selector(clusterSelector, `job="etcd"`)),
 		resultType: Memory,
 	}
 }
 
-func QueryClusterCPUUtilisation(apiClient prometheus.API) *BaseQuery {
+func QueryClusterCPUUtilisation(apiClient prometheus.API, clusterSelector string) *BaseQuery {
 	return &BaseQuery{
 		apiClient:  apiClient,
 		name:       "Cluster CPU Utilisation",
-		query:      `1 - avg(rate(node_cpu_seconds_total{mode="idle", cluster=""}[5m]))`,
+		query:      fmt.Sprintf(`1 - avg(rate(node_cpu_seconds_total{%s}[5m]))`, selector(clusterSelector, `mode="idle"`)),
 		resultType: Percentage,
 	}
 }
 
-func QueryClusterMemoryUtilisation(apiClient prometheus.API) *BaseQuery {
+func QueryClusterMemoryUtilisation(apiClient prometheus.API, clusterSelector string) *BaseQuery {
 	return &BaseQuery{
 		apiClient:  apiClient,
 		name:       "Cluster Memory Utilisation",
-		query:      `1 - sum(:node_memory_MemAvailable_bytes:sum{cluster=""}) / sum(node_memory_MemTotal_bytes{cluster=""})`,
+		query:      fmt.Sprintf(`1 - sum(:node_memory_MemAvailable_bytes:sum{%[1]s}) / sum(node_memory_MemTotal_bytes{%[1]s})`, selector(clusterSelector)),
 		resultType: Percentage,
 	}
 }
 
-func QueryWorkloadCPUUsage(apiClient prometheus.API, namespace, name string) *BaseQuery {
+func QueryWorkloadCPUUsage(apiClient prometheus.API, clusterSelector, namespace, name string) *BaseQuery {
 	query := fmt.Sprintf(`sum(
-		node_namespace_pod_container:container_cpu_usage_seconds_total:sum_irate{cluster="", namespace="%[1]s"}
+		node_namespace_pod_container:container_cpu_usage_seconds_total:sum_irate{%[1]s}
 	  * on(namespace,pod)
-		group_left(workload, workload_type) namespace_workload_pod:kube_pod_owner:relabel{cluster="", namespace="%[1]s", workload="%[2]s", workload_type="deployment"}
-	) by (pod)`, namespace, name)
+		group_left(workload, workload_type) namespace_workload_pod:kube_pod_owner:relabel{%[2]s}
+	) by (pod)`, selector(clusterSelector, fmt.Sprintf(`namespace="%s"`, namespace)),
+		selector(clusterSelector, fmt.Sprintf(`namespace="%s"`, namespace), fmt.Sprintf(`workload="%s"`, name), `workload_type="deployment"`))
 	return &BaseQuery{
 		apiClient:  apiClient,
 		name:       fmt.Sprintf("%s CPU Usage", name),
@@ -92,12 +116,13 @@ func QueryWorkloadCPUUsage(apiClient prometheus.API, namespace, name string) *Ba
 	}
 }
 
-func QueryWorkloadMemoryUsage(apiClient prometheus.API, namespace, name string) *BaseQuery {
+func QueryWorkloadMemoryUsage(apiClient prometheus.API, clusterSelector, namespace, name string) *BaseQuery {
 	query := fmt.Sprintf(`sum(
-		container_memory_working_set_bytes{cluster="", namespace="%[1]s", container!="", image!=""}
+		container_memory_working_set_bytes{%[1]s}
 	  * on(namespace,pod)
-		group_left(workload, workload_type) namespace_workload_pod:kube_pod_owner:relabel{cluster="", namespace="%[1]s", workload="%[2]s", workload_type="deployment"}
-	) by (pod)`, namespace, name)
+		group_left(workload, workload_type) namespace_workload_pod:kube_pod_owner:relabel{%[2]s}
+	) by (pod)`, selector(clusterSelector, fmt.Sprintf(`namespace="%s"`, namespace), `container!=""`, `image!=""`),
+		selector(clusterSelector, fmt.Sprintf(`namespace="%s"`, namespace), fmt.Sprintf(`workload="%s"`, name), `workload_type="deployment"`))
 	return &BaseQuery{
 		apiClient:  apiClient,
 		name:       fmt.Sprintf("%s Memory Usage", name),
@@ -106,9 +131,10 @@ func QueryWorkloadMemoryUsage(apiClient prometheus.API, namespace, name string)
 	}
 }
 
-func QueryNodeMemoryUtilisation(apiClient prometheus.API) *BaseQuery {
-	query := `1 - sum (node_memory_MemAvailable_bytes * on(instance) (group by(instance)(label_replace(kube_node_role{role="master"}, "instance", "$1", "node", "(.*)"))))/
-	sum (node_memory_MemTotal_bytes * on(instance) (group by(instance)(label_replace(kube_node_role{role="master"}, "instance", "$1", "node", "(.*)"))))`
+func QueryNodeMemoryUtilisation(apiClient prometheus.API, clusterSelector string) *BaseQuery {
+	query := fmt.Sprintf(`1 - sum (node_memory_MemAvailable_bytes{%[1]s} * on(instance) (group by(instance)(label_replace(kube_node_role{%[2]s}, "instance", "$1", "node", "(.*)"))))/
+	sum (node_memory_MemTotal_bytes{%[1]s} * on(instance) (group by(instance)(label_replace(kube_node_role{%[2]s}, "instance", "$1", "node", "(.*)"))))`,
+		selector(clusterSelector), selector(clusterSelector, `role="master"`))
 	return &BaseQuery{
 		apiClient:  apiClient,
 		name:       "Node Memory Usage",
@@ -116,3 +142,19 @@ func QueryNodeMemoryUtilisation(apiClient prometheus.API) *BaseQuery {
 		resultType: Percentage,
 	}
 }
+
+// QueryMemberOperatorCPUUsage returns the CPU usage of the operator of the given member cluster, named after the member cluster
+// so that the operators of several member clusters can be told apart
+func QueryMemberOperatorCPUUsage(apiClient prometheus.API, clusterSelector, memberCluster, namespace, name string) *BaseQuery {
+	q := QueryWorkloadCPUUsage(apiClient, clusterSelector, namespace, name)
+	q.name = fmt.Sprintf("%s CPU Usage - %s", name, memberCluster)
+	return q
+}
+
+// QueryMemberOperatorMemoryUsage returns the memory usage of the operator of the given member cluster, named after the member cluster
+// so that the operators of several member clusters can be told apart
+func QueryMemberOperatorMemoryUsage(apiClient prometheus.API, clusterSelector, memberCluster, namespace, name string) *BaseQuery {
+	q := QueryWorkloadMemoryUsage(apiClient, clusterSelector, namespace, name)
+	q.name = fmt.Sprintf("%s Memory Usage - %s", name, memberCluster)
+	return q
+}
@@ -0,0 +1,36 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterSelector(t *testing.T) {
+
+	t.Run("default selector", func(t *testing.T) {
+		// when
+		q := QueryClusterCPUUtilisation(nil, DefaultClusterSelector)
+
+		// then
+		assert.Equal(t, `1 - avg(rate(node_cpu_seconds_total{cluster="", mode="idle"}[5m]))`, q.query)
+	})
+
+	t.Run("custom selector", func(t *testing.T) {
+		// when
+		q := QueryEtcdMemoryUsage(nil, `cluster_id="sandbox"`)
+
+		// then
+		assert.Equal(t, `process_resident_memory_bytes{cluster_id="sandbox", job="etcd"}`, q.query)
+	})
+
+	t.Run("no selector", func(t *testing.T) {
+		// when
+		cpu := QueryClusterCPUUtilisation(nil, "")
+		memory := QueryClusterMemoryUtilisation(nil, "")
+
+		// then
+		assert.Equal(t, `1 - avg(rate(node_cpu_seconds_total{mode="idle"}[5m]))`, cpu.query)
+		assert.Equal(t, `1 - sum(:node_memory_MemAvailable_bytes:sum{}) / sum(node_memory_MemTotal_bytes{})`, memory.query)
+	})
+}
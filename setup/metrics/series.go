@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/metrics/queries"
+
+	prometheus "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	k8sutil "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// maxPointsPerSeries is the maximum number of points per series that prometheus returns for a range query
+const maxPointsPerSeries = 11000
+
+// seriesBackoff is how failed range queries are retried: the series are captured when the tool exits, so the retries are limited to a few
+// seconds per query
+var seriesBackoff = k8sutil.Backoff{
+	Duration: cfg.DefaultRetryInterval,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+	Cap:      5 * time.Second,
+}
+
+// CaptureSeries runs a range query for each query over the time window since the gathering started, at the given step, and keeps
+// the raw series returned by each query. The step is increased if needed so that the series don't exceed the prometheus limit of points.
+// A query that fails doesn't prevent the other series from being captured, the returned error has the failure of each such query.
+func (g *Gatherer) CaptureSeries(step time.Duration) error {
+	g.mu.Lock()
+	start := g.startTime
+	g.mu.Unlock()
+	end := time.Now()
+	if minStep := end.Sub(start) / maxPointsPerSeries; step < minStep {
+		step = minStep
+	}
+	r := prometheus.Range{
+		Start: start,
+		End:   end,
+		Step:  step,
+	}
+	var errs []error
+	for _, q := range g.queries() {
+		var queryErr error
+		// temporary metrics errors have been observed, but an invalid query or a client error won't succeed on a retry
+		err := k8sutil.ExponentialBackoff(seriesBackoff, func() (bool, error) {
+			queryErr = g.querySeries(q, r)
+			if queryErr != nil && !isTransient(queryErr) {
+				return false, queryErr
+			}
+			return queryErr == nil, nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to capture the series of the query '%s': %w", q.Name(), queryErr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isTransient returns true unless the query error is due to the query itself or to the client, which would fail again the same way
+func isTransient(err error) bool {
+	if errors.Is(err, errUnexpectedResultType) {
+		return false
+	}
+	var apiErr *prometheus.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Type {
+		case prometheus.ErrBadData, prometheus.ErrClient, prometheus.ErrExec:
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Gatherer) querySeries(q queries.Query, r prometheus.Range) error {
+	val, warnings, err := q.ExecuteRange(r)
+	if err := g.checkQueryResult(warnings, err); err != nil {
+		return err
+	}
+	matrix, ok := val.(model.Matrix)
+	if !ok {
+		return fmt.Errorf("%w '%s' of the range query %s", errUnexpectedResultType, val.Type(), q.Name())
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.series[q.Name()] = matrix
+	return nil
+}
+
+// seriesJSON is a series of a query as written in the json file
+type seriesJSON struct {
+	Query      string            `json:"query"`
+	ResultType string            `json:"resultType"`
+	Labels     map[string]string `json:"labels"`
+	Values     []pointJSON       `json:"values"`
+}
+
+type pointJSON struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// WriteSeries writes the series captured by CaptureSeries to a csv file with a line per point and to a json file with an object per series
+func (g *Gatherer) WriteSeries(csvPath, jsonPath string) error {
+	lines := [][]string{{"Query", "Labels", "Timestamp", "Value"}}
+	allSeries := []seriesJSON{}
+	g.mu.Lock()
+	for _, q := range g.mqueries {
+		for _, stream := range g.series[q.Name()] {
+			s := seriesJSON{
+				Query:      q.Name(),
+				ResultType: q.ResultType(),
+				Labels:     map[string]string{},
+				Values:     []pointJSON{},
+			}
+			for name, value := range stream.Metric {
+				s.Labels[string(name)] = string(value)
+			}
+			for _, p := range stream.Values {
+				ts := p.Timestamp.Time().UTC()
+				s.Values = append(s.Values, pointJSON{Timestamp: ts, Value: float64(p.Value)})
+				lines = append(lines, []string{q.Name(), stream.Metric.String(), ts.Format(time.RFC3339), strconv.FormatFloat(float64(p.Value), 'f', -1, 64)})
+			}
+			allSeries = append(allSeries, s)
+		}
+	}
+	g.mu.Unlock()
+
+	f, err := os.Create(csvPath)
+	if err != nil {
+		return err
+	}
+	if err := csv.NewWriter(f).WriteAll(lines); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write the metrics series to '%s': %w", csvPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(allSeries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(jsonPath, data, 0600)
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/metrics/queries"
+
+	"github.com/codeready-toolchain/toolchain-common/pkg/test"
+	prometheus "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureAndWriteSeries(t *testing.T) {
+	start := model.TimeFromUnix(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC).Unix())
+	matrix := model.Matrix{
+		&model.SampleStream{
+			Metric: model.Metric{"pod": "host-operator-1"},
+			Values: []model.SamplePair{
+				{Timestamp: start, Value: 100},
+				{Timestamp: start.Add(30 * time.Second), Value: 250.5},
+			},
+		},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		q := testQuery{
+			name:   "host-operator Memory Usage",
+			series: queryResult{val: matrix},
+		}
+		g := &Gatherer{
+			k8sClient: test.NewFakeClient(t),
+			mqueries:  []queries.Query{q},
+			series:    map[string]model.Matrix{},
+			startTime: time.Now().Add(-time.Minute),
+		}
+		dir := t.TempDir()
+		csvPath, jsonPath := filepath.Join(dir, "series.csv"), filepath.Join(dir, "series.json")
+
+		// when
+		err := g.CaptureSeries(30 * time.Second)
+		require.NoError(t, err)
+		err = g.WriteSeries(csvPath, jsonPath)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, matrix, g.series[q.name])
+		csvContent, err := os.ReadFile(csvPath)
+		require.NoError(t, err)
+		assert.Equal(t, `Query,Labels,Timestamp,Value
+host-operator Memory Usage,"{pod=""host-operator-1""}",2024-01-01T10:00:00Z,100
+host-operator Memory Usage,"{pod=""host-operator-1""}",2024-01-01T10:00:30Z,250.5
+`, string(csvContent))
+		jsonContent, err := os.ReadFile(jsonPath)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{
+			"query": "host-operator Memory Usage",
+			"resultType": "memory",
+			"labels": {"pod": "host-operator-1"},
+			"values": [
+				{"timestamp": "2024-01-01T10:00:00Z", "value": 100},
+				{"timestamp": "2024-01-01T10:00:30Z", "value": 250.5}
+			]
+		}]`, string(jsonContent))
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("query error", func(t *testing.T) {
+			// given
+			g := &Gatherer{
+				k8sClient: test.NewFakeClient(t),
+				mqueries: []queries.Query{testQuery{
+					name:   "failing",
+					series: queryResult{err: fmt.Errorf("failure caused by: client error: 403")},
+				}},
+				series: map[string]model.Matrix{},
+			}
+
+			// when
+			err := g.querySeries(g.mqueries[0], prometheus.Range{})
+
+			// then
+			require.EqualError(t, err, "metrics query failed with 403 (Forbidden): failure caused by: client error: 403")
+		})
+
+		t.Run("unexpected result type", func(t *testing.T) {
+			// given
+			g := &Gatherer{
+				k8sClient: test.NewFakeClient(t),
+				mqueries: []queries.Query{testQuery{
+					name:   "vector",
+					series: queryResult{val: model.Vector{}},
+				}},
+				series: map[string]model.Matrix{},
+			}
+
+			// when
+			err := g.querySeries(g.mqueries[0], prometheus.Range{})
+
+			// then
+			require.EqualError(t, err, "unexpected result type 'vector' of the range query vector")
+			assert.False(t, isTransient(err))
+		})
+	})
+}
@@ -2,17 +2,23 @@ package operators
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/templates"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/wait"
 
 	ctemplate "github.com/codeready-toolchain/toolchain-common/pkg/template"
 	"github.com/operator-framework/api/pkg/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -40,7 +46,11 @@ var Templates = []string{
 	"kiali.yaml", // OSD comes with an operator that creates CSVs in all namespaces so kiali is being used in this case to mimic the behaviour on OCP clusters
 }
 
-var csvTimeout = 10 * time.Second
+var (
+	csvTimeout = 10 * time.Second
+	// csvSettleTime is how long to wait once a CSV has succeeded before checking that the subscription doesn't upgrade to another CSV
+	csvSettleTime = 5 * time.Second
+)
 
 func VerifySandboxOperatorsInstalled(cl client.Client) error {
 	subs := &v1alpha1.SubscriptionList{}
@@ -63,85 +73,248 @@ func VerifySandboxOperatorsInstalled(cl client.Client) error {
 	return fmt.Errorf("the sandbox host and/or member operators were not found")
 }
 
-func EnsureOperatorsInstalled(ctx context.Context, cl client.Client, s *runtime.Scheme, templatePaths []string) error {
-	for _, templatePath := range templatePaths {
-		tmpl, err := templates.GetTemplateFromFile(templatePath)
-		if err != nil {
-			return fmt.Errorf("invalid template file: '%s': %w", templatePath, err)
+// VerifySandboxOperatorsDeployed checks that the deployments of the sandbox host and member operators exist, for the clusters
+// without OLM in which the operators are deployed without subscriptions, eg. a local kind cluster
+func VerifySandboxOperatorsDeployed(cl client.Client) error {
+	for _, d := range []types.NamespacedName{
+		{Namespace: configuration.HostOperatorNamespace, Name: configuration.HostOperatorWorkload},
+		{Namespace: configuration.MemberOperatorNamespace, Name: configuration.MemberOperatorWorkload},
+	} {
+		if err := cl.Get(context.TODO(), d, &appsv1.Deployment{}); err != nil {
+			return fmt.Errorf("the deployment '%s' of the sandbox operators was not found: %w", d, err)
 		}
+	}
+	return nil
+}
 
-		processor := ctemplate.NewProcessor(s)
-		objsToProcess, err := processor.Process(tmpl.DeepCopy(), map[string]string{})
-		if err != nil {
-			return err
-		}
+// Install is the outcome of the installation of an operator
+type Install struct {
+	Template     string
+	Subscription string
+	Namespace    string
+	// Skipped is true when the current CSV of the subscription had already succeeded, eg. when the operator was installed by a previous run
+	Skipped bool
+	// Duration is the time until the last CSV of the subscription succeeded
+	Duration time.Duration
+	// CSVs are the successive current CSVs of the subscription during the installation, there are more than one when the CSV was upgraded
+	CSVs []string
+}
 
-		// find the subscription resource
-		var subscriptionResource client.Object
-		foundSub := false
-		for _, obj := range objsToProcess {
-			if obj.GetObjectKind().GroupVersionKind().Kind == "Subscription" {
-				subscriptionResource = obj
-				foundSub = true
-			}
+// Upgraded returns true when the CSV of the subscription was upgraded during the installation,
+// in which case the StartingCSV of the subscription should be updated to the last CSV to speed up future installations
+func (i Install) Upgraded() bool {
+	return len(i.CSVs) > 1
+}
+
+// EnsureOperatorsInstalled installs the operators of the given templates, with up to the given number of installations in parallel.
+// The operators whose subscription has a succeeded current CSV are skipped. The installations are returned in the order of the templates,
+// along with the errors of the installations that failed. When the context is done, the installations in progress are completed but no
+// other installation is started.
+func EnsureOperatorsInstalled(ctx context.Context, term terminal.Terminal, cl client.Client, s *runtime.Scheme, templatePaths []string, parallelism int) ([]Install, error) {
+	installs := make([]Install, len(templatePaths))
+	errs := make([]error, len(templatePaths))
+	sem := make(chan struct{}, max(parallelism, 1))
+	var wg sync.WaitGroup
+	for i, templatePath := range templatePaths {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
 		}
-		if !foundSub {
-			return fmt.Errorf("a subscription was not found in template file '%s'", templatePath)
+		if ctx.Err() != nil {
+			errs[i] = fmt.Errorf("the installation of the operator of template '%s' was not started: %w", templatePath, ctx.Err())
+			continue
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			installs[i], errs[i] = ensureOperatorInstalled(ctx, term, cl, s, templatePath)
+		}()
+	}
+	wg.Wait()
+	return installs, errors.Join(errs...)
+}
 
-		if err := templates.ApplyObjects(ctx, cl, objsToProcess); err != nil {
-			return err
+func ensureOperatorInstalled(ctx context.Context, term terminal.Terminal, cl client.Client, s *runtime.Scheme, templatePath string) (Install, error) {
+	install := Install{Template: templatePath}
+	tmpl, err := templates.GetTemplateFromFile(templatePath)
+	if err != nil {
+		return install, fmt.Errorf("invalid template file: '%s': %w", templatePath, err)
+	}
+
+	processor := ctemplate.NewProcessor(s)
+	objsToProcess, err := processor.Process(tmpl.DeepCopy(), map[string]string{})
+	if err != nil {
+		return install, err
+	}
+
+	// find the subscription resource
+	var subscriptionResource client.Object
+	foundSub := false
+	for _, obj := range objsToProcess {
+		if obj.GetObjectKind().GroupVersionKind().Kind == "Subscription" {
+			subscriptionResource = obj
+			foundSub = true
 		}
+	}
+	if !foundSub {
+		return install, fmt.Errorf("a subscription was not found in template file '%s'", templatePath)
+	}
+	install.Subscription = subscriptionResource.GetName()
+	install.Namespace = subscriptionResource.GetNamespace()
 
-		startTime := time.Now()
+	// skip the operators that are already installed so that an interrupted run can be resumed quickly
+	installedCSV, err := succeededCSV(cl, install.Subscription, install.Namespace)
+	if err != nil {
+		return install, err
+	}
+	if installedCSV != "" {
+		install.Skipped = true
+		install.CSVs = []string{installedCSV}
+		term.Infow("operator is already installed, skipping", "subscription", install.Subscription, "csv", installedCSV)
+		return install, nil
+	}
 
-		// wait for operator installation to succeed
-		var csverr error
-		var currentCSV string
-		var lastCSVs []string
-		timeout := configuration.DefaultTimeout
+	if err := templates.ApplyObjects(ctx, term, cl, objsToProcess); err != nil {
+		return install, err
+	}
 
-		// longer timeout just for subscriptions in the redhat-ods-operator namespace since installation can take significantly longer than other operators
-		if subscriptionResource.GetNamespace() == "redhat-ods-operator" {
-			timeout = 15 * time.Minute
-		}
+	startTime := time.Now()
 
-		err = wait.ForSubscriptionWithCriteria(cl, subscriptionResource.GetName(), subscriptionResource.GetNamespace(), timeout, func(subscription *v1alpha1.Subscription) bool {
-			currentCSV = subscription.Status.CurrentCSV
-			if currentCSV == "" {
-				return false
-			}
+	// wait for operator installation to succeed
+	var csverr error
+	var currentCSV string
+	var lastCSVs []string
+	timeout := configuration.DefaultTimeout
 
-			if len(lastCSVs) == 0 || currentCSV != lastCSVs[len(lastCSVs)-1] { // subscription's current CSV has changed
-				lastCSVs = append(lastCSVs, currentCSV)
-				fmt.Printf("CurrentCSV of subscription: '%s'\n", currentCSV)
-			}
+	// longer timeout just for subscriptions in the redhat-ods-operator namespace since installation can take significantly longer than other operators
+	if subscriptionResource.GetNamespace() == "redhat-ods-operator" {
+		timeout = 15 * time.Minute
+	}
 
-			// wait for the CurrentCSV to reach Succeeded status
-			csverr = wait.ForCSVWithCriteria(cl, currentCSV, subscriptionResource.GetNamespace(), csvTimeout, func(csv *v1alpha1.ClusterServiceVersion) bool {
-				return csv.Status.Phase == "Succeeded"
-			})
-			if csverr != nil {
-				return false
-			}
+	err = wait.ForSubscriptionWithCriteria(cl, subscriptionResource.GetName(), subscriptionResource.GetNamespace(), timeout, func(subscription *v1alpha1.Subscription) bool {
+		currentCSV = subscription.Status.CurrentCSV
+		if currentCSV == "" {
+			return false
+		}
 
-			time.Sleep(5 * time.Second) // wait a few seconds and then check if there's another CSV to wait for
-			currentCSV = subscription.Status.CurrentCSV
-			return currentCSV == lastCSVs[len(lastCSVs)-1] // return true only if the CurrentCSV has not changed. ie. no upgrade needed
-		})
-		if len(lastCSVs) > 1 {
-			fmt.Printf("\nATTENTION! Update subscription '%s' StartingCSV to %s to speed up future installations\n\n", subscriptionResource.GetName(), lastCSVs[len(lastCSVs)-1])
+		if len(lastCSVs) == 0 || currentCSV != lastCSVs[len(lastCSVs)-1] { // subscription's current CSV has changed
+			lastCSVs = append(lastCSVs, currentCSV)
+			term.Infow("current CSV of subscription changed", "subscription", subscriptionResource.GetName(), "csv", currentCSV)
 		}
-		installDuration := time.Since(startTime)
+
+		// wait for the CurrentCSV to reach Succeeded status
+		csverr = wait.ForCSVWithCriteria(cl, currentCSV, subscriptionResource.GetNamespace(), csvTimeout, func(csv *v1alpha1.ClusterServiceVersion) bool {
+			return csv.Status.Phase == "Succeeded"
+		})
 		if csverr != nil {
-			return fmt.Errorf("failed to find CSV '%s' with Phase 'Succeeded': %w", currentCSV, csverr)
+			return false
 		}
-		if err != nil {
-			return fmt.Errorf("failed to verify installation of operator with subscription '%s' after %s: %w", subscriptionResource.GetName(), installDuration.String(), err)
+
+		time.Sleep(csvSettleTime) // wait a few seconds and then check if there's another CSV to wait for
+		latest := &v1alpha1.Subscription{}
+		if err := cl.Get(ctx, types.NamespacedName{Namespace: subscription.Namespace, Name: subscription.Name}, latest); err != nil {
+			return false
 		}
+		currentCSV = latest.Status.CurrentCSV
+		return currentCSV == lastCSVs[len(lastCSVs)-1] // return true only if the CurrentCSV has not changed. ie. no upgrade needed
+	})
+	install.CSVs = lastCSVs
+	if install.Upgraded() {
+		term.Infow("ATTENTION! Update the StartingCSV of the subscription to speed up future installations", "subscription", subscriptionResource.GetName(), "startingCSV", lastCSVs[len(lastCSVs)-1])
+	}
+	install.Duration = time.Since(startTime)
+	if csverr != nil {
+		return install, fmt.Errorf("failed to find CSV '%s' with Phase 'Succeeded': %w", currentCSV, csverr)
+	}
+	if err != nil {
+		return install, fmt.Errorf("failed to verify installation of operator with subscription '%s' after %s: %w", subscriptionResource.GetName(), install.Duration.String(), err)
+	}
+
+	term.Infow("verified installation of operator", "subscription", subscriptionResource.GetName(), "duration", install.Duration.String())
+	return install, nil
+}
 
-		fmt.Printf("Verified installation of operator with subscription '%s' completed in %s\n\n", subscriptionResource.GetName(), installDuration.String())
+// succeededCSV returns the current CSV of the given subscription when it has succeeded, or an empty string
+func succeededCSV(cl client.Client, subscription, namespace string) (string, error) {
+	var currentCSV string
+	found, err := wait.HasSubscriptionWithCriteria(cl, subscription, namespace, func(sub *v1alpha1.Subscription) bool {
+		currentCSV = sub.Status.CurrentCSV
+		return currentCSV != ""
+	})
+	if err != nil || !found {
+		return "", err
+	}
+	succeeded, err := wait.HasCSVWithCriteria(cl, currentCSV, namespace, func(csv *v1alpha1.ClusterServiceVersion) bool {
+		return csv.Status.Phase == v1alpha1.CSVPhaseSucceeded
+	})
+	if err != nil || !succeeded {
+		return "", err
 	}
+	return currentCSV, nil
+}
+
+// InstallResults returns the install time of each operator that was installed, the operators that were skipped
+// and the CSV upgrades that were detected, with the CSV to set as the StartingCSV of the subscription
+func InstallResults(installs []Install) [][]string {
+	var results [][]string
+	for _, i := range installs {
+		if i.Subscription == "" {
+			continue
+		}
+		if i.Skipped {
+			results = append(results, []string{fmt.Sprintf("Operator Install Skipped - %s", i.Subscription), i.CSVs[0]})
+			continue
+		}
+		results = append(results, []string{fmt.Sprintf("Operator Install Time - %s (s)", i.Subscription), fmt.Sprintf("%.2f", i.Duration.Seconds())})
+		if i.Upgraded() {
+			results = append(results,
+				[]string{fmt.Sprintf("Operator CSV Upgrades - %s", i.Subscription), strings.Join(i.CSVs, " -> ")},
+				[]string{fmt.Sprintf("Operator StartingCSV To Set - %s", i.Subscription), i.CSVs[len(i.CSVs)-1]},
+			)
+		}
+	}
+	return results
+}
 
+// EnsureOperatorsUninstalled deletes the objects of the given operator install templates along with the CSVs that were installed by their subscriptions
+func EnsureOperatorsUninstalled(ctx context.Context, cl client.Client, s *runtime.Scheme, templatePaths []string) error {
+	for _, templatePath := range templatePaths {
+		tmpl, err := templates.GetTemplateFromFile(templatePath)
+		if err != nil {
+			return fmt.Errorf("invalid template file: '%s': %w", templatePath, err)
+		}
+
+		processor := ctemplate.NewProcessor(s)
+		objsToDelete, err := processor.Process(tmpl.DeepCopy(), map[string]string{})
+		if err != nil {
+			return err
+		}
+
+		// delete the objects in the reverse order of their creation so that the namespace goes last
+		for i := len(objsToDelete) - 1; i >= 0; i-- {
+			obj := objsToDelete[i]
+			installedCSV := ""
+			if obj.GetObjectKind().GroupVersionKind().Kind == "Subscription" {
+				sub := &v1alpha1.Subscription{}
+				if err := cl.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, sub); err != nil && !k8serrors.IsNotFound(err) {
+					return err
+				}
+				installedCSV = sub.Status.InstalledCSV
+			}
+			if err := cl.Delete(ctx, obj); err != nil && !k8serrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete %s '%s' from template file '%s': %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), templatePath, err)
+			}
+			// the CSV is not deleted by OLM when the subscription is removed
+			if installedCSV != "" {
+				csv := &v1alpha1.ClusterServiceVersion{}
+				csv.SetNamespace(obj.GetNamespace())
+				csv.SetName(installedCSV)
+				if err := cl.Delete(ctx, csv); err != nil && !k8serrors.IsNotFound(err) {
+					return fmt.Errorf("failed to delete CSV '%s' of subscription '%s': %w", installedCSV, obj.GetName(), err)
+				}
+			}
+		}
+	}
 	return nil
 }
@@ -3,6 +3,7 @@ package operators
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,7 +11,10 @@ import (
 	"github.com/codeready-toolchain/toolchain-e2e/setup/test"
 	"github.com/operator-framework/api/pkg/operators/v1alpha1"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -18,6 +22,7 @@ import (
 
 func TestEnsureOperatorsInstalled(t *testing.T) {
 	csvTimeout = time.Millisecond
+	csvSettleTime = time.Millisecond
 	scheme, err := configuration.NewScheme()
 	require.NoError(t, err)
 
@@ -27,6 +32,9 @@ func TestEnsureOperatorsInstalled(t *testing.T) {
 			cl := test.NewFakeClient(t)
 			cl.MockGet = func(ctx context.Context, key types.NamespacedName, obj client.Object, opts ...client.GetOption) error {
 				if sub, ok := obj.(*v1alpha1.Subscription); ok {
+					if err := cl.Client.Get(ctx, key, obj, opts...); err != nil {
+						return err // the subscription is not created yet
+					}
 					sub.Status.CurrentCSV = "kiali-operator.v1.24.7" // set CurrentCSV to simulate a good subscription
 					return nil
 				}
@@ -40,10 +48,123 @@ func TestEnsureOperatorsInstalled(t *testing.T) {
 			}
 
 			// when
-			err = EnsureOperatorsInstalled(context.TODO(), cl, scheme, []string{"installtemplates/kiali.yaml"})
+			installs, err := EnsureOperatorsInstalled(context.TODO(), test.NewTerminal(), cl, scheme, []string{"installtemplates/kiali.yaml"}, 1)
+
+			// then
+			require.NoError(t, err)
+			require.Len(t, installs, 1)
+			assert.Equal(t, "kiali-ossm", installs[0].Subscription)
+			assert.False(t, installs[0].Skipped)
+			assert.Equal(t, []string{"kiali-operator.v1.24.7"}, installs[0].CSVs)
+			assert.False(t, installs[0].Upgraded())
+		})
+
+		t.Run("operator already installed", func(t *testing.T) {
+			// given
+			sub := &v1alpha1.Subscription{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "kiali-ossm",
+					Namespace: "openshift-operators",
+				},
+				Status: v1alpha1.SubscriptionStatus{
+					CurrentCSV: "kiali-operator.v1.24.7",
+				},
+			}
+			cl := test.NewFakeClient(t, sub, kialiCSV(v1alpha1.CSVPhaseSucceeded))
+			cl.MockPatch = func(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				return fmt.Errorf("the objects of the template should not be applied")
+			}
+
+			// when
+			installs, err := EnsureOperatorsInstalled(context.TODO(), test.NewTerminal(), cl, scheme, []string{"installtemplates/kiali.yaml"}, 1)
+
+			// then
+			require.NoError(t, err)
+			require.Len(t, installs, 1)
+			assert.True(t, installs[0].Skipped)
+			assert.Equal(t, [][]string{{"Operator Install Skipped - kiali-ossm", "kiali-operator.v1.24.7"}}, InstallResults(installs))
+		})
+
+		t.Run("operators installed in parallel with a csv upgrade", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			var mu sync.Mutex
+			subscriptionGets := map[string]int{}
+			cl.MockGet = func(ctx context.Context, key types.NamespacedName, obj client.Object, opts ...client.GetOption) error {
+				if sub, ok := obj.(*v1alpha1.Subscription); ok {
+					if err := cl.Client.Get(ctx, key, obj, opts...); err != nil {
+						return err
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					subscriptionGets[key.Name]++
+					sub.Status.CurrentCSV = key.Name + ".v1"
+					// the CSV of the kiali subscription is upgraded before the first one succeeded
+					if key.Name == "kiali-ossm" && subscriptionGets[key.Name] > 1 {
+						sub.Status.CurrentCSV = key.Name + ".v2"
+					}
+					return nil
+				}
+				if csv, ok := obj.(*v1alpha1.ClusterServiceVersion); ok {
+					csv.Name = key.Name
+					csv.Namespace = key.Namespace
+					csv.Status.Phase = v1alpha1.CSVPhaseSucceeded
+					if key.Name == "kiali-ossm.v1" {
+						csv.Status.Phase = v1alpha1.CSVPhaseInstalling
+					}
+					return nil
+				}
+				return cl.Client.Get(ctx, key, obj, opts...)
+			}
+
+			// when
+			installs, err := EnsureOperatorsInstalled(context.TODO(), test.NewTerminal(), cl, scheme, []string{"installtemplates/kiali.yaml", "installtemplates/web-terminal-operator.yaml"}, 2)
+
+			// then
+			require.NoError(t, err)
+			require.Len(t, installs, 2)
+			assert.Equal(t, []string{"kiali-ossm.v1", "kiali-ossm.v2"}, installs[0].CSVs)
+			assert.True(t, installs[0].Upgraded())
+			assert.False(t, installs[1].Upgraded())
+			results := InstallResults(installs)
+			assert.Contains(t, results, []string{"Operator CSV Upgrades - kiali-ossm", "kiali-ossm.v1 -> kiali-ossm.v2"})
+			assert.Contains(t, results, []string{"Operator StartingCSV To Set - kiali-ossm", "kiali-ossm.v2"})
+			assert.Len(t, results, 4)
+		})
+
+		t.Run("csv upgraded while settling", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			csvSucceeded := false
+			cl.MockGet = func(ctx context.Context, key types.NamespacedName, obj client.Object, opts ...client.GetOption) error {
+				if sub, ok := obj.(*v1alpha1.Subscription); ok {
+					if err := cl.Client.Get(ctx, key, obj, opts...); err != nil {
+						return err
+					}
+					sub.Status.CurrentCSV = key.Name + ".v1"
+					// the CSV is upgraded once the first one succeeded, ie. while the installation settles
+					if csvSucceeded {
+						sub.Status.CurrentCSV = key.Name + ".v2"
+					}
+					return nil
+				}
+				if csv, ok := obj.(*v1alpha1.ClusterServiceVersion); ok {
+					csvSucceeded = true
+					csv.Name = key.Name
+					csv.Namespace = key.Namespace
+					csv.Status.Phase = v1alpha1.CSVPhaseSucceeded
+					return nil
+				}
+				return cl.Client.Get(ctx, key, obj, opts...)
+			}
+
+			// when
+			installs, err := EnsureOperatorsInstalled(context.TODO(), test.NewTerminal(), cl, scheme, []string{"installtemplates/web-terminal-operator.yaml"}, 1)
 
 			// then
 			require.NoError(t, err)
+			require.Len(t, installs, 1)
+			assert.Equal(t, []string{"web-terminal.v1", "web-terminal.v2"}, installs[0].CSVs)
 		})
 	})
 
@@ -51,6 +172,23 @@ func TestEnsureOperatorsInstalled(t *testing.T) {
 		configuration.DefaultTimeout = 1 * time.Millisecond
 		configuration.DefaultRetryInterval = 1 * time.Millisecond
 
+		t.Run("interrupted", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			ctx, cancel := context.WithCancel(context.TODO())
+			cancel()
+
+			// when
+			installs, err := EnsureOperatorsInstalled(ctx, test.NewTerminal(), cl, scheme, []string{"installtemplates/kiali.yaml"}, 1)
+
+			// then
+			require.ErrorIs(t, err, context.Canceled)
+			assert.Empty(t, InstallResults(installs))
+			subs := &v1alpha1.SubscriptionList{}
+			require.NoError(t, cl.List(context.TODO(), subs))
+			assert.Empty(t, subs.Items)
+		})
+
 		t.Run("error when creating subscription", func(t *testing.T) {
 			// given
 			cl := test.NewFakeClient(t)
@@ -62,7 +200,7 @@ func TestEnsureOperatorsInstalled(t *testing.T) {
 			}
 
 			// when
-			err := EnsureOperatorsInstalled(context.TODO(), cl, scheme, []string{"installtemplates/kiali.yaml"})
+			_, err := EnsureOperatorsInstalled(context.TODO(), test.NewTerminal(), cl, scheme, []string{"installtemplates/kiali.yaml"}, 1)
 
 			// then
 			require.EqualError(t, err, "could not apply resource 'kiali-ossm' in namespace 'openshift-operators': unable to patch 'operators.coreos.com/v1alpha1, Kind=Subscription' called 'kiali-ossm' in namespace 'openshift-operators': Test client error")
@@ -82,7 +220,7 @@ func TestEnsureOperatorsInstalled(t *testing.T) {
 			}
 
 			// when
-			err := EnsureOperatorsInstalled(context.TODO(), cl, scheme, []string{"installtemplates/kiali.yaml"})
+			_, err := EnsureOperatorsInstalled(context.TODO(), test.NewTerminal(), cl, scheme, []string{"installtemplates/kiali.yaml"}, 1)
 
 			// then
 			require.ErrorContains(t, err, "could not find a Subscription with name 'kiali-ossm' in namespace 'openshift-operators' that meets the expected criteria: context deadline exceeded")
@@ -104,7 +242,7 @@ func TestEnsureOperatorsInstalled(t *testing.T) {
 			}
 
 			// when
-			err := EnsureOperatorsInstalled(context.TODO(), cl, scheme, []string{"installtemplates/kiali.yaml"})
+			_, err := EnsureOperatorsInstalled(context.TODO(), test.NewTerminal(), cl, scheme, []string{"installtemplates/kiali.yaml"}, 1)
 
 			// then
 			require.EqualError(t, err, "failed to find CSV 'kiali-operator.v1.24.7' with Phase 'Succeeded': could not find a CSV with name 'kiali-operator.v1.24.7' in namespace 'openshift-operators' that meets the expected criteria: context deadline exceeded")
@@ -128,7 +266,7 @@ func TestEnsureOperatorsInstalled(t *testing.T) {
 			}
 
 			// when
-			err = EnsureOperatorsInstalled(context.TODO(), cl, scheme, []string{"installtemplates/kiali.yaml"})
+			_, err = EnsureOperatorsInstalled(context.TODO(), test.NewTerminal(), cl, scheme, []string{"installtemplates/kiali.yaml"}, 1)
 
 			// then
 			require.EqualError(t, err, "failed to find CSV 'kiali-operator.v1.24.7' with Phase 'Succeeded': could not find a CSV with name 'kiali-operator.v1.24.7' in namespace 'openshift-operators' that meets the expected criteria: context deadline exceeded")
@@ -138,7 +276,7 @@ func TestEnsureOperatorsInstalled(t *testing.T) {
 			cl := test.NewFakeClient(t)
 
 			// when
-			err := EnsureOperatorsInstalled(context.TODO(), cl, scheme, []string{"../test/installtemplates/badoperator.yaml"})
+			_, err := EnsureOperatorsInstalled(context.TODO(), test.NewTerminal(), cl, scheme, []string{"../test/installtemplates/badoperator.yaml"}, 1)
 
 			// then
 			require.EqualError(t, err, "a subscription was not found in template file '../test/installtemplates/badoperator.yaml'")
@@ -146,6 +284,67 @@ func TestEnsureOperatorsInstalled(t *testing.T) {
 	})
 }
 
+func TestEnsureOperatorsUninstalled(t *testing.T) {
+	scheme, err := configuration.NewScheme()
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		t.Run("operator installed", func(t *testing.T) {
+			// given
+			sub := &v1alpha1.Subscription{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "kiali-ossm",
+					Namespace: "openshift-operators",
+				},
+				Status: v1alpha1.SubscriptionStatus{
+					InstalledCSV: "kiali-operator.v1.24.7",
+				},
+			}
+			cl := test.NewFakeClient(t, sub, kialiCSV(v1alpha1.CSVPhaseSucceeded))
+
+			// when
+			err := EnsureOperatorsUninstalled(context.TODO(), cl, scheme, []string{"installtemplates/kiali.yaml"})
+
+			// then
+			require.NoError(t, err)
+			err = cl.Get(context.TODO(), types.NamespacedName{Namespace: "openshift-operators", Name: "kiali-ossm"}, &v1alpha1.Subscription{})
+			require.True(t, errors.IsNotFound(err))
+			err = cl.Get(context.TODO(), types.NamespacedName{Namespace: "openshift-operators", Name: "kiali-operator.v1.24.7"}, &v1alpha1.ClusterServiceVersion{})
+			require.True(t, errors.IsNotFound(err))
+		})
+
+		t.Run("operator not installed", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+
+			// when
+			err := EnsureOperatorsUninstalled(context.TODO(), cl, scheme, []string{"installtemplates/kiali.yaml"})
+
+			// then
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("error when deleting subscription", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			cl.MockDelete = func(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+				if obj.GetObjectKind().GroupVersionKind().Kind == "Subscription" {
+					return fmt.Errorf("Test client error")
+				}
+				return cl.Client.Delete(ctx, obj, opts...)
+			}
+
+			// when
+			err := EnsureOperatorsUninstalled(context.TODO(), cl, scheme, []string{"installtemplates/kiali.yaml"})
+
+			// then
+			require.EqualError(t, err, "failed to delete Subscription 'kiali-ossm' from template file 'installtemplates/kiali.yaml': Test client error")
+		})
+	})
+}
+
 func kialiCSV(phase v1alpha1.ClusterServiceVersionPhase) *v1alpha1.ClusterServiceVersion {
 	return &v1alpha1.ClusterServiceVersion{
 		ObjectMeta: metav1.ObjectMeta{
@@ -158,3 +357,34 @@ func kialiCSV(phase v1alpha1.ClusterServiceVersionPhase) *v1alpha1.ClusterServic
 		},
 	}
 }
+
+func TestVerifySandboxOperatorsDeployed(t *testing.T) {
+	// given
+	configuration.HostOperatorNamespace = "toolchain-host-operator"
+	configuration.MemberOperatorNamespace = "toolchain-member-operator"
+	hostDeployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "toolchain-host-operator", Name: configuration.HostOperatorWorkload}}
+	memberDeployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "toolchain-member-operator", Name: configuration.MemberOperatorWorkload}}
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, hostDeployment, memberDeployment)
+
+		// when
+		err := VerifySandboxOperatorsDeployed(cl)
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("member operator not deployed", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t, hostDeployment)
+
+		// when
+		err := VerifySandboxOperatorsDeployed(cl)
+
+		// then
+		require.ErrorContains(t, err, "the deployment 'toolchain-member-operator/member-operator-controller-manager' of the sandbox operators was not found")
+		assert.True(t, errors.IsNotFound(err))
+	})
+}
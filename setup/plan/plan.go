@@ -0,0 +1,149 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/platform"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/resources"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/templates"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FallbackNamespace is the namespace in which the objects are applied in dry-run mode when the namespace of the sample user doesn't exist yet
+const FallbackNamespace = "default"
+
+// Usage is a template that is applied for some users of the run
+type Usage struct {
+	Template string
+	// Username is the user whose parameters are used to process the template
+	Username string
+	// Users is the number of users the template is applied for
+	Users int
+	// Params are the values of the template parameters of the sample user
+	Params map[string]string
+}
+
+// Entry is what a template would create for each of its users
+type Entry struct {
+	Usage
+	// Namespace is where the objects of the template were applied in dry-run mode
+	Namespace string
+	// Objects is the number of objects of each kind created for each user
+	Objects map[string]int
+}
+
+// Plan is what the setup would create for the users of the run, verified against the cluster without mutating it
+type Plan struct {
+	Entries []Entry
+	// MissingAPIs are the group/version kinds of the templates that are not served by the cluster, eg. because a CRD is not installed
+	MissingAPIs []string
+	// Errors are the errors of the dry-run apply of the objects
+	Errors []error
+}
+
+// Make processes the templates of the given usages for their sample user, checks that the kinds of their objects are served by the cluster
+// and applies the objects with server-side dry-run calls, so that nothing is created
+func Make(ctx context.Context, term terminal.Terminal, cl client.Client, s *runtime.Scheme, usages []Usage) (*Plan, error) {
+	p := &Plan{}
+	missing := map[string]bool{}
+	dryRunClient := client.NewDryRunClient(cl)
+	for _, u := range usages {
+		objs, err := resources.ProcessTemplateFiles(s, u.Username, []string{u.Template}, u.Params)
+		if err != nil {
+			return nil, err
+		}
+		namespace, err := namespaceOf(ctx, cl, u.Username)
+		if err != nil {
+			return nil, err
+		}
+		entry := Entry{Usage: u, Namespace: namespace, Objects: map[string]int{}}
+		var served []client.Object
+		for _, obj := range objs {
+			gvk := obj.GetObjectKind().GroupVersionKind()
+			entry.Objects[gvk.Kind]++
+			if ok, err := platform.Serves(cl, gvk); err != nil {
+				return nil, fmt.Errorf("template '%s': %w", u.Template, err)
+			} else if !ok {
+				if apiVersion, kind := gvk.ToAPIVersionAndKind(); !missing[apiVersion+", "+kind] {
+					missing[apiVersion+", "+kind] = true
+					p.MissingAPIs = append(p.MissingAPIs, apiVersion+", "+kind)
+				}
+				continue
+			}
+			served = append(served, obj)
+		}
+		for _, obj := range served {
+			if err := templates.ApplyObjects(ctx, term, dryRunClient, []client.Object{obj}, templates.NamespaceModifier(namespace)); err != nil {
+				p.Errors = append(p.Errors, fmt.Errorf("dry-run of template '%s' failed: %w", u.Template, err))
+			}
+		}
+		p.Entries = append(p.Entries, entry)
+	}
+	sort.Strings(p.MissingAPIs)
+	return p, nil
+}
+
+// namespaceOf returns the namespace of the given user if it exists, eg. when resuming a run, otherwise the fallback namespace
+func namespaceOf(ctx context.Context, cl client.Client, username string) (string, error) {
+	name := resources.UserNamespace(username)
+	if err := cl.Get(ctx, types.NamespacedName{Name: name}, &corev1.Namespace{}); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return "", err
+		}
+		return FallbackNamespace, nil
+	}
+	return name, nil
+}
+
+// Totals returns the number of objects of each kind that would be created for all the users
+func (p *Plan) Totals() map[string]int {
+	totals := map[string]int{}
+	for _, e := range p.Entries {
+		for kind, count := range e.Objects {
+			totals[kind] += count * e.Users
+		}
+	}
+	return totals
+}
+
+// Results returns the number of objects of each kind per user of each template, the total number of objects of each kind
+// and the number of objects of all kinds that would be created, along with the missing APIs and the number of dry-run errors
+func (p *Plan) Results() [][]string {
+	var results [][]string
+	for _, e := range p.Entries {
+		results = append(results,
+			[]string{fmt.Sprintf("Template Users - %s", e.Template), fmt.Sprintf("%d", e.Users)},
+			[]string{fmt.Sprintf("Dry Run Namespace - %s", e.Template), e.Namespace},
+		)
+		for _, kind := range sortedKinds(e.Objects) {
+			results = append(results, []string{fmt.Sprintf("Objects Per User - %s - %s", e.Template, kind), fmt.Sprintf("%d", e.Objects[kind])})
+		}
+	}
+	totals := p.Totals()
+	total := 0
+	for _, kind := range sortedKinds(totals) {
+		results = append(results, []string{fmt.Sprintf("Total Objects - %s", kind), fmt.Sprintf("%d", totals[kind])})
+		total += totals[kind]
+	}
+	results = append(results, []string{"Total Objects", fmt.Sprintf("%d", total)})
+	for _, api := range p.MissingAPIs {
+		results = append(results, []string{"Missing API", api})
+	}
+	return append(results, []string{"Dry Run Errors", fmt.Sprintf("%d", len(p.Errors))})
+}
+
+func sortedKinds(counts map[string]int) []string {
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
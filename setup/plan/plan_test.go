@@ -0,0 +1,154 @@
+package plan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestMake(t *testing.T) {
+	// given
+	s, err := configuration.NewScheme()
+	require.NoError(t, err)
+	templatePath := filepath.Join(t.TempDir(), "template.yaml")
+	require.NoError(t, os.WriteFile(templatePath, []byte(template), 0600))
+	usages := []Usage{
+		{Template: templatePath, Username: "user0001", Users: 10, Params: map[string]string{"SUFFIX": "a"}},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		t.Run("sample user namespace not found", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			var patched []string
+			cl.MockPatch = dryRunOnly(t, &patched)
+
+			// when
+			p, err := Make(context.TODO(), test.NewTerminal(), withCoreAPIs(cl), s, usages)
+
+			// then
+			require.NoError(t, err)
+			require.Len(t, p.Entries, 1)
+			assert.Equal(t, FallbackNamespace, p.Entries[0].Namespace)
+			assert.Equal(t, map[string]int{"ConfigMap": 2, "VirtualMachine": 1}, p.Entries[0].Objects)
+			// the objects whose API is missing are not applied
+			assert.Equal(t, []string{"default/config-a", "default/other-config-a"}, patched)
+			assert.Equal(t, []string{"kubevirt.io/v1, VirtualMachine"}, p.MissingAPIs)
+			assert.Empty(t, p.Errors)
+			assert.Equal(t, map[string]int{"ConfigMap": 20, "VirtualMachine": 10}, p.Totals())
+			assert.Equal(t, [][]string{
+				{"Template Users - " + templatePath, "10"},
+				{"Dry Run Namespace - " + templatePath, "default"},
+				{"Objects Per User - " + templatePath + " - ConfigMap", "2"},
+				{"Objects Per User - " + templatePath + " - VirtualMachine", "1"},
+				{"Total Objects - ConfigMap", "20"},
+				{"Total Objects - VirtualMachine", "10"},
+				{"Total Objects", "30"},
+				{"Missing API", "kubevirt.io/v1, VirtualMachine"},
+				{"Dry Run Errors", "0"},
+			}, p.Results())
+		})
+
+		t.Run("sample user namespace found", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "user0001-dev"}})
+			var patched []string
+			cl.MockPatch = dryRunOnly(t, &patched)
+
+			// when
+			p, err := Make(context.TODO(), test.NewTerminal(), withCoreAPIs(cl), s, usages)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, "user0001-dev", p.Entries[0].Namespace)
+			assert.Equal(t, []string{"user0001-dev/config-a", "user0001-dev/other-config-a"}, patched)
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("dry-run errors are collected", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			cl.MockPatch = func(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				return assert.AnError
+			}
+
+			// when
+			p, err := Make(context.TODO(), test.NewTerminal(), withCoreAPIs(cl), s, usages)
+
+			// then
+			require.NoError(t, err)
+			assert.Len(t, p.Errors, 2)
+			assert.Contains(t, p.Results(), []string{"Dry Run Errors", "2"})
+		})
+
+		t.Run("template not found", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+
+			// when
+			_, err := Make(context.TODO(), test.NewTerminal(), cl, s, []Usage{{Template: "unknown.yaml", Username: "user0001", Users: 1}})
+
+			// then
+			require.EqualError(t, err, "invalid template file: 'unknown.yaml': open unknown.yaml: no such file or directory")
+		})
+	})
+}
+
+// dryRunOnly returns a patch function that records the patched objects and fails the test when a patch is not a dry-run
+func dryRunOnly(t *testing.T, patched *[]string) func(context.Context, client.Object, client.Patch, ...client.PatchOption) error {
+	return func(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+		patchOpts := &client.PatchOptions{}
+		patchOpts.ApplyOptions(opts)
+		assert.Equal(t, []string{metav1.DryRunAll}, patchOpts.DryRun, "the object '%s' should only be applied in dry-run mode", obj.GetName())
+		*patched = append(*patched, obj.GetNamespace()+"/"+obj.GetName())
+		return nil
+	}
+}
+
+// withCoreAPIs returns the given client with a REST mapper that only serves the core APIs, like a cluster without the kubevirt CRDs
+func withCoreAPIs(cl client.Client) client.Client {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	return test.WithRESTMapper(cl, mapper)
+}
+
+const template = `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: plan-test
+objects:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: config-${SUFFIX}
+    namespace: ${CURRENT_USER_NAMESPACE}
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: other-config-${SUFFIX}
+    namespace: ${CURRENT_USER_NAMESPACE}
+- apiVersion: kubevirt.io/v1
+  kind: VirtualMachine
+  metadata:
+    name: vm-${SUFFIX}
+    namespace: ${CURRENT_USER_NAMESPACE}
+parameters:
+- name: CURRENT_USER_NAMESPACE
+  required: true
+- name: SUFFIX
+  required: true
+`
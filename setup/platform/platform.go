@@ -0,0 +1,66 @@
+package platform
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the APIs whose presence tells which features of the cluster are available
+var (
+	routeKind        = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}
+	subscriptionKind = schema.GroupVersionKind{Group: "operators.coreos.com", Version: "v1alpha1", Kind: "Subscription"}
+	olmConfigKind    = schema.GroupVersionKind{Group: "operators.coreos.com", Version: "v1", Kind: "OLMConfig"}
+)
+
+// Platform is what the cluster provides beyond the Kubernetes APIs, the setup steps that depend on a missing feature
+// are skipped or substituted, eg. to profile the operators on a local kind cluster
+type Platform struct {
+	// OpenShift is true when the cluster serves the OpenShift APIs, eg. the routes of the monitoring stack
+	OpenShift bool
+	// OLM is true when the Operator Lifecycle Manager is installed, the operators can then be installed with subscriptions
+	OLM bool
+	// OLMConfig is true when the OLM version supports its cluster-wide configuration, eg. to disable the copied CSVs
+	OLMConfig bool
+}
+
+// Detect returns the platform of the cluster of the given client
+func Detect(cl client.Client) (Platform, error) {
+	var p Platform
+	var err error
+	if p.OpenShift, err = Serves(cl, routeKind); err != nil {
+		return p, err
+	}
+	if p.OLM, err = Serves(cl, subscriptionKind); err != nil {
+		return p, err
+	}
+	if p.OLMConfig, err = Serves(cl, olmConfigKind); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// Serves returns true if the cluster of the given client serves the API of the given kind
+func Serves(cl client.Client, gvk schema.GroupVersionKind) (bool, error) {
+	if _, err := cl.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to check whether the cluster serves the API of kind '%s': %w", gvk, err)
+	}
+	return true, nil
+}
+
+// String returns the name of the platform, eg. for the results
+func (p Platform) String() string {
+	switch {
+	case p.OpenShift:
+		return "OpenShift"
+	case p.OLM:
+		return "Kubernetes with OLM"
+	default:
+		return "Kubernetes"
+	}
+}
@@ -0,0 +1,86 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestDetect(t *testing.T) {
+
+	t.Run("success", func(t *testing.T) {
+		t.Run("openshift", func(t *testing.T) {
+			// given
+			cl := withAPIs(test.NewFakeClient(t), routeKind, subscriptionKind, olmConfigKind)
+
+			// when
+			p, err := Detect(cl)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, Platform{OpenShift: true, OLM: true, OLMConfig: true}, p)
+			assert.Equal(t, "OpenShift", p.String())
+		})
+
+		t.Run("kubernetes with olm", func(t *testing.T) {
+			// given
+			cl := withAPIs(test.NewFakeClient(t), subscriptionKind)
+
+			// when
+			p, err := Detect(cl)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, Platform{OLM: true}, p)
+			assert.Equal(t, "Kubernetes with OLM", p.String())
+		})
+
+		t.Run("kubernetes", func(t *testing.T) {
+			// given
+			cl := withAPIs(test.NewFakeClient(t))
+
+			// when
+			p, err := Detect(cl)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, Platform{}, p)
+			assert.Equal(t, "Kubernetes", p.String())
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		// given
+		cl := test.WithRESTMapper(test.NewFakeClient(t), failingMapper{RESTMapper: meta.NewDefaultRESTMapper(nil)})
+
+		// when
+		_, err := Detect(cl)
+
+		// then
+		require.EqualError(t, err, "unable to check whether the cluster serves the API of kind 'route.openshift.io/v1, Kind=Route': "+assert.AnError.Error())
+	})
+}
+
+// withAPIs returns the given client with a REST mapper that only serves the given kinds
+func withAPIs(cl client.Client, kinds ...schema.GroupVersionKind) client.Client {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, kind := range kinds {
+		mapper.Add(kind, meta.RESTScopeNamespace)
+	}
+	return test.WithRESTMapper(cl, mapper)
+}
+
+// failingMapper fails to look up the APIs, eg. when the API server is not reachable
+type failingMapper struct {
+	meta.RESTMapper
+}
+
+func (m failingMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	return nil, assert.AnError
+}
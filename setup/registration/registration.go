@@ -0,0 +1,223 @@
+package registration
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	commonauth "github.com/codeready-toolchain/toolchain-common/pkg/test/auth"
+	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/latency"
+	authsupport "github.com/codeready-toolchain/toolchain-e2e/testsupport/auth"
+
+	"github.com/google/uuid"
+	routev1 "github.com/openshift/api/route/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8swait "k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RouteName is the name of the route of the registration service
+	RouteName = "registration-service"
+
+	signupPath = "/api/v1/signup"
+)
+
+// the endpoints whose requests are measured
+const (
+	PostSignup = http.MethodPost + " " + signupPath
+	GetSignup  = http.MethodGet + " " + signupPath
+)
+
+// GetURL returns the URL of the registration service route in the given namespace
+func GetURL(cl client.Client, namespace string) (string, error) {
+	route := &routev1.Route{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: RouteName}, route); err != nil {
+		return "", fmt.Errorf("unable to get the registration service route: %w", err)
+	}
+	if route.Spec.TLS != nil {
+		return "https://" + route.Spec.Host, nil
+	}
+	return "http://" + route.Spec.Host, nil
+}
+
+// Client signs up the users through the registration service like real users do, with tokens signed by the e2e test key.
+// The registration service must be configured to trust this key, which is the case when it's deployed for the e2e tests.
+// The latency and the errors of the requests are recorded per endpoint. It's safe for concurrent use.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	mu         sync.Mutex
+	stats      map[string]*endpointStats
+}
+
+type endpointStats struct {
+	durations []time.Duration
+	errors    int
+}
+
+// NewClient returns a client of the registration service at the given URL
+func NewClient(url string) *Client {
+	return &Client{
+		url: url,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: true, // nolint:gosec
+				},
+			},
+		},
+		stats: map[string]*endpointStats{
+			PostSignup: {},
+			GetSignup:  {},
+		},
+	}
+}
+
+// Signup calls the signup endpoint with a token of the given user, a user that already signed up is not considered as an error
+func (c *Client) Signup(username string) error {
+	status, body, err := c.invoke(http.MethodPost, username, http.StatusAccepted, http.StatusConflict)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusAccepted && status != http.StatusConflict {
+		return fmt.Errorf("failed to sign up user '%s': unexpected response status %d with body: %s", username, status, body)
+	}
+	return nil
+}
+
+// WaitUntilReady polls the signup endpoint with a token of the given user until the signup of the user is ready
+func (c *Client) WaitUntilReady(username string) error {
+	var lastErr error
+	err := k8swait.PollUntilContextTimeout(context.TODO(), cfg.DefaultRetryInterval, cfg.DefaultTimeout, true, func(ctx context.Context) (bool, error) {
+		var ready bool
+		ready, lastErr = c.signupReady(username)
+		return ready, nil
+	})
+	if err != nil {
+		if lastErr != nil {
+			err = lastErr
+		}
+		return fmt.Errorf("signup of user '%s' was not ready: %w", username, err)
+	}
+	return nil
+}
+
+func (c *Client) signupReady(username string) (bool, error) {
+	// the signup is not found until the host operator has processed the UserSignup
+	status, body, err := c.invoke(http.MethodGet, username, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case http.StatusNotFound:
+		return false, nil
+	case http.StatusOK:
+		signup := struct {
+			Status struct {
+				Ready  bool   `json:"ready"`
+				Reason string `json:"reason"`
+			} `json:"status"`
+		}{}
+		if err := json.Unmarshal(body, &signup); err != nil {
+			return false, fmt.Errorf("invalid signup response: %w", err)
+		}
+		return signup.Status.Ready, nil
+	default:
+		return false, fmt.Errorf("unexpected response status %d with body: %s", status, body)
+	}
+}
+
+// invoke calls the signup endpoint with the given method and records the duration of the request, the requests that fail
+// or that get a response status other than the expected ones are recorded as errors
+func (c *Client) invoke(method, username string, expectedStatuses ...int) (int, []byte, error) {
+	token, err := newToken(username)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to generate the token of user '%s': %w", username, err)
+	}
+	req, err := http.NewRequest(method, c.url+signupPath, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	var body []byte
+	if err == nil {
+		defer resp.Body.Close()
+		body, err = io.ReadAll(resp.Body)
+	}
+	duration := time.Since(start)
+
+	failed := err != nil
+	if !failed {
+		failed = true
+		for _, s := range expectedStatuses {
+			if resp.StatusCode == s {
+				failed = false
+			}
+		}
+	}
+	c.record(method+" "+signupPath, duration, failed)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to call the registration service: %w", err)
+	}
+	return resp.StatusCode, body, nil
+}
+
+func (c *Client) record(endpoint string, d time.Duration, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats[endpoint]
+	s.durations = append(s.durations, d)
+	if failed {
+		s.errors++
+	}
+}
+
+// newToken returns a token of the given user, whose identity is derived from the username so that the same user gets the same identity across runs
+func newToken(username string) (string, error) {
+	identity := &commonauth.Identity{
+		ID:       uuid.NewSHA1(uuid.NameSpaceOID, []byte(username)),
+		Username: username,
+	}
+	return authsupport.NewTokenFromIdentity(identity, authsupport.WithEmail(fmt.Sprintf("%s@fake.test", username)))
+}
+
+// Results returns the number of requests, the error rate and the latency distribution of each endpoint that was called.
+// There are no results for a nil client.
+func (c *Client) Results() [][]string {
+	var results [][]string
+	if c == nil {
+		return results
+	}
+	for _, endpoint := range []string{PostSignup, GetSignup} {
+		c.mu.Lock()
+		durations := append([]time.Duration{}, c.stats[endpoint].durations...)
+		errors := c.stats[endpoint].errors
+		c.mu.Unlock()
+		if len(durations) == 0 {
+			continue
+		}
+		s := latency.Summarize(durations)
+		results = append(results,
+			[]string{fmt.Sprintf("Registration Service %s Requests", endpoint), fmt.Sprintf("%d", s.Count)},
+			[]string{fmt.Sprintf("Registration Service %s Errors", endpoint), fmt.Sprintf("%d", errors)},
+			[]string{fmt.Sprintf("Registration Service %s Error Rate (%%)", endpoint), fmt.Sprintf("%.2f", 100*float64(errors)/float64(s.Count))},
+		)
+		for _, p := range latency.Percentiles {
+			results = append(results, []string{fmt.Sprintf("Registration Service %s Latency p%g (s)", endpoint, p), fmt.Sprintf("%.3f", s.Percentiles[p].Seconds())})
+		}
+		results = append(results, []string{fmt.Sprintf("Registration Service %s Latency max (s)", endpoint), fmt.Sprintf("%.3f", s.Max.Seconds())})
+	}
+	return results
+}
@@ -0,0 +1,161 @@
+package registration
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/test"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeRegistrationService replies to the signup requests of each user with the recorded statuses, the last one being repeated
+type fakeRegistrationService struct {
+	mu        sync.Mutex
+	responses map[string][]fakeResponse
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+func (s *fakeRegistrationService) handle(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/signup", r.URL.Path)
+		username := preferredUsername(t, r)
+		s.mu.Lock()
+		key := r.Method + " " + username
+		responses := s.responses[key]
+		require.NotEmpty(t, responses, "no response for %s", key)
+		response := responses[0]
+		if len(responses) > 1 {
+			s.responses[key] = responses[1:]
+		}
+		s.mu.Unlock()
+		w.WriteHeader(response.status)
+		_, _ = w.Write([]byte(response.body))
+	}
+}
+
+// preferredUsername returns the username of the token of the request
+func preferredUsername(t *testing.T, r *http.Request) string {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	claims := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, claims["preferred_username"].(string)+"@fake.test", claims["email"])
+	return claims["preferred_username"].(string)
+}
+
+func TestClient(t *testing.T) {
+	// given
+	cfg.DefaultRetryInterval = time.Millisecond
+	cfg.DefaultTimeout = time.Second
+	s := &fakeRegistrationService{
+		responses: map[string][]fakeResponse{
+			"POST zippy-0001": {{status: http.StatusAccepted}},
+			"GET zippy-0001":  {{status: http.StatusNotFound}, {status: http.StatusOK, body: `{"status":{"ready":false}}`}, {status: http.StatusOK, body: `{"status":{"ready":true}}`}},
+			"POST zippy-0002": {{status: http.StatusConflict}},
+			"POST zippy-0003": {{status: http.StatusInternalServerError, body: "oops"}},
+			"GET zippy-0003":  {{status: http.StatusServiceUnavailable, body: "unavailable"}},
+		},
+	}
+	server := httptest.NewServer(s.handle(t))
+	t.Cleanup(server.Close)
+	c := NewClient(server.URL)
+
+	t.Run("success", func(t *testing.T) {
+		t.Run("signup", func(t *testing.T) {
+			require.NoError(t, c.Signup("zippy-0001"))
+		})
+
+		t.Run("already signed up", func(t *testing.T) {
+			require.NoError(t, c.Signup("zippy-0002"))
+		})
+
+		t.Run("wait until ready", func(t *testing.T) {
+			require.NoError(t, c.WaitUntilReady("zippy-0001"))
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("signup", func(t *testing.T) {
+			err := c.Signup("zippy-0003")
+			require.EqualError(t, err, "failed to sign up user 'zippy-0003': unexpected response status 500 with body: oops")
+		})
+
+		t.Run("wait until ready", func(t *testing.T) {
+			err := c.WaitUntilReady("zippy-0003")
+			require.EqualError(t, err, "signup of user 'zippy-0003' was not ready: unexpected response status 503 with body: unavailable")
+		})
+	})
+
+	t.Run("results", func(t *testing.T) {
+		// when
+		results := c.Results()
+
+		// then
+		require.Len(t, results, 2*(3+len([]string{"p50", "p90", "p95", "p99", "max"})))
+		assert.Equal(t, []string{"Registration Service POST /api/v1/signup Requests", "3"}, results[0])
+		assert.Equal(t, []string{"Registration Service POST /api/v1/signup Errors", "1"}, results[1])
+		assert.Equal(t, []string{"Registration Service POST /api/v1/signup Error Rate (%)", "33.33"}, results[2])
+		assert.Equal(t, "Registration Service POST /api/v1/signup Latency p50 (s)", results[3][0])
+		// the unavailable signup was polled until the timeout, the other one was not found once then not ready once
+		assert.Equal(t, "Registration Service GET /api/v1/signup Requests", results[8][0])
+		assert.Equal(t, "Registration Service GET /api/v1/signup Errors", results[9][0])
+		requests, err := strconv.Atoi(results[8][1])
+		require.NoError(t, err)
+		assert.Equal(t, strconv.Itoa(requests-3), results[9][1])
+	})
+}
+
+func TestGetURL(t *testing.T) {
+	for name, tc := range map[string]struct {
+		tls      *routev1.TLSConfig
+		expected string
+	}{
+		"http":  {expected: "http://registration-service.example.com"},
+		"https": {tls: &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge}, expected: "https://registration-service.example.com"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t, &routev1.Route{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "toolchain-host-operator", Name: RouteName},
+				Spec:       routev1.RouteSpec{Host: "registration-service.example.com", TLS: tc.tls},
+			})
+
+			// when
+			url, err := GetURL(cl, "toolchain-host-operator")
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, url)
+		})
+	}
+
+	t.Run("route not found", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t)
+
+		// when
+		_, err := GetURL(cl, "toolchain-host-operator")
+
+		// then
+		require.ErrorContains(t, err, "unable to get the registration service route")
+	})
+}
@@ -3,8 +3,10 @@ package resources
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	ctemplate "github.com/codeready-toolchain/toolchain-common/pkg/template"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/platform"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/templates"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/wait"
 
@@ -15,38 +17,92 @@ import (
 
 const userNSParam = "CURRENT_USER_NAMESPACE"
 
-var tmpls map[string]*templatev1.Template = make(map[string]*templatev1.Template)
+var (
+	tmplsMu sync.Mutex
+	tmpls   map[string]*templatev1.Template = make(map[string]*templatev1.Template)
+)
 
-func CreateUserResourcesFromTemplateFiles(ctx context.Context, cl runtimeclient.Client, s *runtime.Scheme, username string, templatePaths []string) error {
-	userNS := fmt.Sprintf("%s-dev", username)
-	combinedObjsToProcess := []runtimeclient.Object{}
-	for _, templatePath := range templatePaths {
-		// get the template from the file if it hasn't been processed already
-		if _, ok := tmpls[templatePath]; !ok {
-			var err error
-			if tmpls[templatePath], err = templates.GetTemplateFromFile(templatePath); err != nil {
-				return fmt.Errorf("invalid template file: '%s': %w", templatePath, err)
-			}
+// CreateUserResourcesFromTemplateFiles creates the objects of the templates at the given paths in the namespace of the given user,
+// the given parameter values are passed to the templates in addition to the namespace of the user. When servedOnly is true, the objects
+// whose kind isn't served by the cluster are skipped, eg. the routes and the builds on a cluster without the OpenShift APIs.
+func CreateUserResourcesFromTemplateFiles(ctx context.Context, cl runtimeclient.Client, s *runtime.Scheme, username string, templatePaths []string, params map[string]string, servedOnly bool) error {
+	combinedObjsToProcess, err := ProcessTemplateFiles(s, username, templatePaths, params)
+	if err != nil {
+		return err
+	}
+	if servedOnly {
+		if combinedObjsToProcess, err = ServedObjects(cl, combinedObjsToProcess); err != nil {
+			return err
 		}
-		tmpl := tmpls[templatePath]
+	}
 
-		// waiting for each space here prevents some edge cases where the setup job can progress beyond the usersignup job and fail with a timeout
-		if err := wait.ForSpace(cl, username); err != nil {
-			return err
+	// waiting for the space here prevents some edge cases where the setup job can progress beyond the usersignup job and fail with a timeout
+	if err := wait.ForSpace(cl, username); err != nil {
+		return err
+	}
+
+	return templates.ApplyObjectsConcurrently(ctx, cl, combinedObjsToProcess, templates.NamespaceModifier(UserNamespace(username)))
+}
+
+// ProcessTemplateFiles returns the objects of the templates at the given paths, processed for the namespace of the given user with the given parameter values.
+// The values of the parameters that a template doesn't declare are ignored.
+func ProcessTemplateFiles(s *runtime.Scheme, username string, templatePaths []string, params map[string]string) ([]runtimeclient.Object, error) {
+	values := map[string]string{}
+	for name, value := range params {
+		values[name] = value
+	}
+	values[userNSParam] = UserNamespace(username)
+	combinedObjsToProcess := []runtimeclient.Object{}
+	for _, templatePath := range templatePaths {
+		tmpl, err := getTemplate(templatePath)
+		if err != nil {
+			return nil, err
 		}
 		processor := ctemplate.NewProcessor(s)
-		objsToProcess, err := processor.Process(tmpl.DeepCopy(), map[string]string{
-			userNSParam: userNS,
-		})
+		objsToProcess, err := processor.Process(tmpl.DeepCopy(), values)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		combinedObjsToProcess = append(combinedObjsToProcess, objsToProcess...)
 	}
 
 	if len(combinedObjsToProcess) == 0 {
-		return fmt.Errorf("no objects found in templates %v", templatePaths)
+		return nil, fmt.Errorf("no objects found in templates %v", templatePaths)
 	}
+	return combinedObjsToProcess, nil
+}
+
+// ServedObjects returns the given objects whose kind is served by the cluster of the given client
+func ServedObjects(cl runtimeclient.Client, objs []runtimeclient.Object) ([]runtimeclient.Object, error) {
+	served := make([]runtimeclient.Object, 0, len(objs))
+	for _, obj := range objs {
+		ok, err := platform.Serves(cl, obj.GetObjectKind().GroupVersionKind())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			served = append(served, obj)
+		}
+	}
+	return served, nil
+}
+
+// getTemplate returns the template from the file if it hasn't been read already
+func getTemplate(templatePath string) (*templatev1.Template, error) {
+	tmplsMu.Lock()
+	defer tmplsMu.Unlock()
+	if tmpl, ok := tmpls[templatePath]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := templates.GetTemplateFromFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template file: '%s': %w", templatePath, err)
+	}
+	tmpls[templatePath] = tmpl
+	return tmpl, nil
+}
 
-	return templates.ApplyObjectsConcurrently(ctx, cl, combinedObjsToProcess, templates.NamespaceModifier(userNS))
+// UserNamespace returns the namespace of the given user in which the resources of the templates are created
+func UserNamespace(username string) string {
+	return fmt.Sprintf("%s-dev", username)
 }
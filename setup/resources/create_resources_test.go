@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -11,13 +12,18 @@ import (
 	commontest "github.com/codeready-toolchain/toolchain-common/pkg/test"
 	testspace "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/test"
+	routev1 "github.com/openshift/api/route/v1"
 	templatev1 "github.com/openshift/api/template/v1"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 )
 
@@ -43,7 +49,7 @@ func TestCreateUserResourcesFromTemplateFiles(t *testing.T) {
 		templatePath := "user-workloads.yaml"
 
 		// when
-		err := CreateUserResourcesFromTemplateFiles(context.TODO(), cl, s, username, []string{templatePath})
+		err := CreateUserResourcesFromTemplateFiles(context.TODO(), cl, s, username, []string{templatePath}, nil, false)
 
 		// then
 		require.NoError(t, err)
@@ -61,7 +67,116 @@ func TestCreateUserResourcesFromTemplateFiles(t *testing.T) {
 			&corev1.Service{}))
 	})
 
+	t.Run("success with parameters", func(t *testing.T) {
+		// given
+		t.Cleanup(func() {
+			tmpls = make(map[string]*templatev1.Template)
+		})
+		space := testspace.NewSpace(configuration.HostOperatorNamespace, "user0001", testspace.WithCondition(
+			toolchainv1alpha1.Condition{
+				Type:   toolchainv1alpha1.ConditionReady,
+				Status: corev1.ConditionTrue,
+				Reason: "Provisioned",
+			}))
+		cl := commontest.NewFakeClient(t, space)
+		templatePath := filepath.Join(t.TempDir(), "template.yaml")
+		require.NoError(t, os.WriteFile(templatePath, []byte(parameterizedTemplate), 0600))
+
+		// when
+		err := CreateUserResourcesFromTemplateFiles(context.TODO(), cl, s, "user0001", []string{templatePath}, map[string]string{
+			"REPLICAS": "3",
+			"UNKNOWN":  "ignored",
+		}, false)
+
+		// then
+		require.NoError(t, err)
+		deployment := &appsv1.Deployment{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: "user0001-dev", Name: "app-user0001-dev"}, deployment))
+		assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+	})
+
+	t.Run("success with plain yaml documents", func(t *testing.T) {
+		// given
+		t.Cleanup(func() {
+			tmpls = make(map[string]*templatev1.Template)
+		})
+		space := testspace.NewSpace(configuration.HostOperatorNamespace, "user0001", testspace.WithCondition(
+			toolchainv1alpha1.Condition{
+				Type:   toolchainv1alpha1.ConditionReady,
+				Status: corev1.ConditionTrue,
+				Reason: "Provisioned",
+			}))
+		cl := commontest.NewFakeClient(t, space)
+		templatePath := filepath.Join(t.TempDir(), "workloads.yaml")
+		require.NoError(t, os.WriteFile(templatePath, []byte("# the workloads of the user\n---\n"+deployment+"\n---\n"+configMap), 0600))
+
+		// when
+		err := CreateUserResourcesFromTemplateFiles(context.TODO(), cl, s, "user0001", []string{templatePath}, nil, false)
+
+		// then
+		require.NoError(t, err)
+		assert.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: "user0001-dev", Name: "nginx-deployment"}, &appsv1.Deployment{}))
+		assert.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: "user0001-dev", Name: "game-config"}, &corev1.ConfigMap{}))
+	})
+
+	t.Run("success with the served kinds only", func(t *testing.T) {
+		// given
+		t.Cleanup(func() {
+			tmpls = make(map[string]*templatev1.Template)
+		})
+		space := testspace.NewSpace(configuration.HostOperatorNamespace, "user0001", testspace.WithCondition(
+			toolchainv1alpha1.Condition{
+				Type:   toolchainv1alpha1.ConditionReady,
+				Status: corev1.ConditionTrue,
+				Reason: "Provisioned",
+			}))
+		fakeClient := commontest.NewFakeClient(t, space)
+		// like a cluster without the OpenShift APIs
+		mapper := meta.NewDefaultRESTMapper(nil)
+		for gvk := range s.AllKnownTypes() {
+			if gvk.Group != "route.openshift.io" && gvk.Group != "image.openshift.io" && gvk.Group != "build.openshift.io" {
+				mapper.Add(gvk, meta.RESTScopeNamespace)
+			}
+		}
+		cl := test.WithRESTMapper(fakeClient, mapper)
+
+		// when
+		err := CreateUserResourcesFromTemplateFiles(context.TODO(), cl, s, "user0001", []string{"user-workloads.yaml"}, nil, true)
+
+		// then
+		require.NoError(t, err)
+		assert.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: "user0001-dev", Name: "nginx-deployment"}, &appsv1.Deployment{}))
+		assert.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: "user0001-dev", Name: "nginx-service"}, &corev1.Service{}))
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(routev1.GroupVersion.WithKind("Route"))
+		err = cl.Get(context.TODO(), types.NamespacedName{Namespace: "user0001-dev", Name: "nginx-route"}, route)
+		assert.True(t, apierrors.IsNotFound(err), "the route should not be created: %v", err)
+	})
+
 	t.Run("failures", func(t *testing.T) {
+		t.Run("interrupted", func(t *testing.T) {
+			// given
+			t.Cleanup(func() {
+				tmpls = make(map[string]*templatev1.Template)
+			})
+			space := testspace.NewSpace(configuration.HostOperatorNamespace, "user0001", testspace.WithCondition(
+				toolchainv1alpha1.Condition{
+					Type:   toolchainv1alpha1.ConditionReady,
+					Status: corev1.ConditionTrue,
+					Reason: "Provisioned",
+				}))
+			cl := commontest.NewFakeClient(t, space)
+			ctx, cancel := context.WithCancel(context.TODO())
+			cancel()
+
+			// when
+			err := CreateUserResourcesFromTemplateFiles(ctx, cl, s, "user0001", []string{"user-workloads.yaml"}, nil, false)
+
+			// then
+			require.ErrorIs(t, err, context.Canceled)
+			assert.True(t, apierrors.IsNotFound(cl.Get(context.TODO(), types.NamespacedName{Namespace: "user0001-dev", Name: "nginx-deployment"}, &appsv1.Deployment{})))
+		})
+
 		t.Run("invalid template", func(t *testing.T) {
 			t.Run("file not found", func(t *testing.T) {
 				// given
@@ -75,7 +190,7 @@ func TestCreateUserResourcesFromTemplateFiles(t *testing.T) {
 				templatePath := "not-found.yaml"
 
 				// when
-				err := CreateUserResourcesFromTemplateFiles(context.TODO(), cl, s, username, []string{templatePath})
+				err := CreateUserResourcesFromTemplateFiles(context.TODO(), cl, s, username, []string{templatePath}, nil, false)
 
 				// then
 				require.Error(t, err)
@@ -93,19 +208,48 @@ func TestCreateUserResourcesFromTemplateFiles(t *testing.T) {
 				username := "user0001"
 				tmpFile, err := os.CreateTemp(os.TempDir(), "setup-template-")
 				require.NoError(t, err)
-				_, _ = tmpFile.WriteString(deployment)
+				_, _ = tmpFile.WriteString(deployment + "\n---\nmetadata:\n  name: no-kind\n")
 
 				// when
-				err = CreateUserResourcesFromTemplateFiles(context.TODO(), cl, s, username, []string{tmpFile.Name()})
+				err = CreateUserResourcesFromTemplateFiles(context.TODO(), cl, s, username, []string{tmpFile.Name()}, nil, false)
 
 				// then
 				require.Error(t, err)
-				assert.EqualError(t, err, fmt.Sprintf("invalid template file: '%s': wrong kind of object in the template file: 'apps/v1, Kind=Deployment'", tmpFile.Name()))
+				assert.EqualError(t, err, fmt.Sprintf("invalid template file: '%s': the document #2 of the template file has no apiVersion or kind", tmpFile.Name()))
 			})
 		})
 	})
 }
 
+const parameterizedTemplate = `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: parameterized
+objects:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: app-${CURRENT_USER_NAMESPACE}
+  spec:
+    replicas: ${{REPLICAS}}
+    selector:
+      matchLabels:
+        app: app
+    template:
+      metadata:
+        labels:
+          app: app
+      spec:
+        containers:
+        - name: app
+          image: quay.io/bitnami/nginx
+parameters:
+- name: CURRENT_USER_NAMESPACE
+  required: true
+- name: REPLICAS
+  value: "1"
+`
+
 const deployment = `apiVersion: apps/v1
 kind: Deployment
 metadata:
@@ -134,3 +278,10 @@ spec:
             memory: 250Mi
         ports:
         - containerPort: 80`
+
+const configMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: game-config
+data:
+  lives: "3"`
@@ -0,0 +1,107 @@
+package resources
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the generators of the per-user values of the template parameters
+var generatorRegexp = regexp.MustCompile(`^(index|random|pick)\((.*)\)$`)
+
+const randomChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// generator returns the value of a parameter for the user with the given index, using the given random source of the user
+type generator func(index int, r *rand.Rand) string
+
+// Params are the parameters of the custom templates. A value is either used as is or generated for each user with one of:
+//   - index(): the index of the user in the run, starting at 1
+//   - random(N): a random string of N lowercase alphanumeric characters
+//   - pick(a|b|c): one of the given values, picked at random
+//
+// The random values are seeded with the seed of the params and the index of the user, so that a user gets the same values across runs.
+type Params struct {
+	names      []string
+	generators map[string]generator
+	seed       int64
+}
+
+// ParseParams returns the params of the given values, the CURRENT_USER_NAMESPACE parameter is always set by the tool and can't be overridden
+func ParseParams(values map[string]string, seed int64) (*Params, error) {
+	p := &Params{
+		generators: make(map[string]generator, len(values)),
+		seed:       seed,
+	}
+	for name, value := range values {
+		if name == "" {
+			return nil, fmt.Errorf("invalid template parameter '%s=%s': the name must not be empty", name, value)
+		}
+		if name == userNSParam {
+			return nil, fmt.Errorf("invalid template parameter '%s': the parameter is set by the tool", name)
+		}
+		g, err := parseGenerator(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template parameter '%s=%s': %w", name, value, err)
+		}
+		p.names = append(p.names, name)
+		p.generators[name] = g
+	}
+	// the values are generated in a fixed order so that the random values don't depend on the iteration order of the map
+	sort.Strings(p.names)
+	return p, nil
+}
+
+func parseGenerator(value string) (generator, error) {
+	match := generatorRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return func(int, *rand.Rand) string {
+			return value
+		}, nil
+	}
+	args := match[2]
+	switch match[1] {
+	case "index":
+		if args != "" {
+			return nil, fmt.Errorf("index() takes no argument")
+		}
+		return func(index int, _ *rand.Rand) string {
+			return strconv.Itoa(index)
+		}, nil
+	case "random":
+		length, err := strconv.Atoi(args)
+		if err != nil || length < 1 {
+			return nil, fmt.Errorf("the length of random() must be a number more than 0")
+		}
+		return func(_ int, r *rand.Rand) string {
+			b := make([]byte, length)
+			for i := range b {
+				b[i] = randomChars[r.Intn(len(randomChars))]
+			}
+			return string(b)
+		}, nil
+	default: // pick
+		if args == "" {
+			return nil, fmt.Errorf("pick() takes at least one value")
+		}
+		choices := strings.Split(args, "|")
+		return func(_ int, r *rand.Rand) string {
+			return choices[r.Intn(len(choices))]
+		}, nil
+	}
+}
+
+// For returns the values of the parameters for the user with the given index. There are no values for nil params.
+func (p *Params) For(index int) map[string]string {
+	if p == nil || len(p.names) == 0 {
+		return nil
+	}
+	r := rand.New(rand.NewSource(p.seed + int64(index))) // nolint:gosec
+	values := make(map[string]string, len(p.names))
+	for _, name := range p.names {
+		values[name] = p.generators[name](index, r)
+	}
+	return values
+}
@@ -0,0 +1,99 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParams(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// given
+		params, err := ParseParams(map[string]string{
+			"IMAGE":    "quay.io/bitnami/nginx",
+			"INDEX":    "index()",
+			"SUFFIX":   "random(8)",
+			"REPLICAS": "pick(1|2|3)",
+		}, 42)
+		require.NoError(t, err)
+
+		// when
+		values := params.For(7)
+
+		// then
+		assert.Equal(t, "quay.io/bitnami/nginx", values["IMAGE"])
+		assert.Equal(t, "7", values["INDEX"])
+		assert.Regexp(t, "^[a-z0-9]{8}$", values["SUFFIX"])
+		assert.Contains(t, []string{"1", "2", "3"}, values["REPLICAS"])
+
+		t.Run("same values for the same user", func(t *testing.T) {
+			// given
+			again, err := ParseParams(map[string]string{
+				"IMAGE":    "quay.io/bitnami/nginx",
+				"INDEX":    "index()",
+				"SUFFIX":   "random(8)",
+				"REPLICAS": "pick(1|2|3)",
+			}, 42)
+			require.NoError(t, err)
+
+			// when
+			againValues := again.For(7)
+
+			// then
+			assert.Equal(t, values, againValues)
+		})
+
+		t.Run("different values for another user", func(t *testing.T) {
+			// when
+			other := params.For(8)
+
+			// then
+			assert.Equal(t, "8", other["INDEX"])
+			assert.NotEqual(t, values["SUFFIX"], other["SUFFIX"])
+		})
+	})
+
+	t.Run("no params", func(t *testing.T) {
+		// given
+		var params *Params
+
+		// when
+		values := params.For(1)
+
+		// then
+		assert.Empty(t, values)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			values map[string]string
+			errMsg string
+		}{
+			"namespace param": {
+				values: map[string]string{"CURRENT_USER_NAMESPACE": "ns"},
+				errMsg: "invalid template parameter 'CURRENT_USER_NAMESPACE': the parameter is set by the tool",
+			},
+			"index with argument": {
+				values: map[string]string{"INDEX": "index(2)"},
+				errMsg: "invalid template parameter 'INDEX=index(2)': index() takes no argument",
+			},
+			"random without length": {
+				values: map[string]string{"SUFFIX": "random()"},
+				errMsg: "invalid template parameter 'SUFFIX=random()': the length of random() must be a number more than 0",
+			},
+			"pick without values": {
+				values: map[string]string{"REPLICAS": "pick()"},
+				errMsg: "invalid template parameter 'REPLICAS=pick()': pick() takes at least one value",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// when
+				_, err := ParseParams(tc.values, 1)
+
+				// then
+				require.EqualError(t, err, tc.errMsg)
+			})
+		}
+	})
+}
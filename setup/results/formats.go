@@ -0,0 +1,218 @@
+package results
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// the formats of the results files
+const (
+	CSV      = "csv"
+	JSON     = "json"
+	Markdown = "markdown"
+	JUnit    = "junit"
+)
+
+// Formats lists all the supported formats of the results files
+var Formats = []string{CSV, JSON, Markdown, JUnit}
+
+var extensions = map[string]string{
+	CSV:      ".csv",
+	JSON:     ".json",
+	Markdown: ".md",
+	JUnit:    "-junit.xml",
+}
+
+// ValidateFormats checks that all the given formats are supported
+func ValidateFormats(formats []string) error {
+	for _, f := range formats {
+		if _, ok := extensions[f]; !ok {
+			return fmt.Errorf("invalid output format '%s': must be one of %s", f, strings.Join(Formats, ", "))
+		}
+	}
+	return nil
+}
+
+// Entry is a single result with typed fields
+type Entry struct {
+	// Name is the full name of the result item, as in the csv file
+	Name string `json:"name"`
+	// Value is a number when the value of the result can be parsed as such, otherwise a string
+	Value interface{} `json:"value"`
+	// Unit is the unit in the parentheses at the end of the name, if any
+	Unit string `json:"unit,omitempty"`
+	// Aggregation is how the value was computed from several samples, eg. average, max or p95, if any
+	Aggregation string `json:"aggregation,omitempty"`
+}
+
+var (
+	unitRegexp        = regexp.MustCompile(`^(.*?)\s*\(([^()]+)\)$`)
+	percentileRegexp  = regexp.MustCompile(`\s(min|max|p\d+)$`)
+	aggregationPrefix = map[string]string{
+		"Average ":   "average",
+		"Max ":       "max",
+		"Number of ": "count",
+		"Total ":     "total",
+	}
+)
+
+// NewEntry parses the unit and the aggregation from the name of the given result item, and the value as a number if possible
+func NewEntry(name, value string) Entry {
+	e := Entry{
+		Name:  name,
+		Value: value,
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		e.Value = v
+	}
+	base := name
+	if m := unitRegexp.FindStringSubmatch(name); m != nil {
+		base, e.Unit = m[1], m[2]
+	}
+	for prefix, aggregation := range aggregationPrefix {
+		if strings.HasPrefix(base, prefix) {
+			e.Aggregation = aggregation
+		}
+	}
+	if m := percentileRegexp.FindStringSubmatch(base); m != nil {
+		e.Aggregation = m[1]
+	}
+	return e
+}
+
+// entries returns the typed entries of the given results, without the header row
+func entries(results [][]string) []Entry {
+	es := make([]Entry, 0, len(results))
+	for _, r := range results {
+		if len(r) < 2 || (r[0] == "Item" && r[1] == "Value") {
+			continue
+		}
+		es = append(es, NewEntry(r[0], r[1]))
+	}
+	return es
+}
+
+type jsonWriter struct {
+	path string
+}
+
+func (w jsonWriter) Write(results [][]string) error {
+	data, err := json.MarshalIndent(entries(results), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(w.path, data, 0600)
+}
+
+func (w jsonWriter) Close() error {
+	return nil
+}
+
+type markdownWriter struct {
+	path string
+}
+
+func (w markdownWriter) Write(results [][]string) error {
+	buf := &bytes.Buffer{}
+	buf.WriteString("| Item | Value |\n|---|---|\n")
+	for _, e := range entries(results) {
+		// the pipes would break the table
+		fmt.Fprintf(buf, "| %s | %s |\n", strings.ReplaceAll(e.Name, "|", `\|`), strings.ReplaceAll(fmt.Sprint(e.Value), "|", `\|`))
+	}
+	return os.WriteFile(w.path, buf.Bytes(), 0600)
+}
+
+func (w markdownWriter) Close() error {
+	return nil
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitWriter writes a test case per result, the results that break their threshold are failures,
+// as well as the items that have a threshold but no result
+type junitWriter struct {
+	path       string
+	thresholds Thresholds
+}
+
+func (w junitWriter) Write(results [][]string) error {
+	suite := junitTestSuite{
+		Name: "setup",
+	}
+	es := entries(results)
+	for _, e := range es {
+		tc := junitTestCase{
+			Name:      e.Name,
+			ClassName: "setup",
+			SystemOut: fmt.Sprint(e.Value),
+		}
+		if msg := w.thresholds.Check(e); msg != "" {
+			tc.Failure = &junitFailure{Message: msg}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	for _, name := range w.thresholds.Missing(es) {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      name,
+			ClassName: "setup",
+			Failure:   &junitFailure{Message: missingMessage},
+		})
+		suite.Failures++
+	}
+	suite.Tests = len(suite.TestCases)
+
+	data, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(w.path, append([]byte(xml.Header), data...), 0600)
+}
+
+func (w junitWriter) Close() error {
+	return nil
+}
+
+// Load reads the entries of the results file at the given path, either a csv or a json results file
+func Load(path string) ([]Entry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, extensions[JSON]) {
+		var es []Entry
+		if err := json.Unmarshal(content, &es); err != nil {
+			return nil, fmt.Errorf("invalid results file '%s': %w", path, err)
+		}
+		return es, nil
+	}
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid results file '%s': %w", path, err)
+	}
+	return entries(rows), nil
+}
@@ -0,0 +1,240 @@
+package results
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testResults = [][]string{
+	{"Item", "Value"},
+	{"Number of Users", "10"},
+	{"Resumed Run", "false"},
+	{"Average Idler Update Time (s)", "1.50"},
+	{"Max host-operator-controller-manager Memory Usage (MB)", "612.25"},
+	{"Time Per User - space-ready p95 (s)", "4.00"},
+	{"Average cluster CPU Usage (%)", "12.50"},
+}
+
+func TestNewEntry(t *testing.T) {
+	for _, tc := range []struct {
+		row      []string
+		expected Entry
+	}{
+		{row: testResults[1], expected: Entry{Name: "Number of Users", Value: 10.0, Aggregation: "count"}},
+		{row: testResults[2], expected: Entry{Name: "Resumed Run", Value: "false"}},
+		{row: testResults[3], expected: Entry{Name: "Average Idler Update Time (s)", Value: 1.5, Unit: "s", Aggregation: "average"}},
+		{row: testResults[4], expected: Entry{Name: "Max host-operator-controller-manager Memory Usage (MB)", Value: 612.25, Unit: "MB", Aggregation: "max"}},
+		{row: testResults[5], expected: Entry{Name: "Time Per User - space-ready p95 (s)", Value: 4.0, Unit: "s", Aggregation: "p95"}},
+		{row: testResults[6], expected: Entry{Name: "Average cluster CPU Usage (%)", Value: 12.5, Unit: "%", Aggregation: "average"}},
+	} {
+		t.Run(tc.row[0], func(t *testing.T) {
+			assert.Equal(t, tc.expected, NewEntry(tc.row[0], tc.row[1]))
+		})
+	}
+}
+
+func TestValidateFormats(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, ValidateFormats(Formats))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		require.EqualError(t, ValidateFormats([]string{CSV, "xlsx"}), "invalid output format 'xlsx': must be one of csv, json, markdown, junit")
+	})
+}
+
+func TestWriters(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		// given
+		path := filepath.Join(t.TempDir(), "results.json")
+
+		// when
+		err := jsonWriter{path: path}.Write(testResults[:4])
+
+		// then
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"name": "Number of Users", "value": 10, "aggregation": "count"},
+			{"name": "Resumed Run", "value": "false"},
+			{"name": "Average Idler Update Time (s)", "value": 1.5, "unit": "s", "aggregation": "average"}
+		]`, readFile(t, path))
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		// given
+		path := filepath.Join(t.TempDir(), "results.md")
+
+		// when
+		err := markdownWriter{path: path}.Write(append(testResults[:3:3], []string{"Scenario", "a|b"}))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "| Item | Value |\n|---|---|\n| Number of Users | 10 |\n| Resumed Run | false |\n| Scenario | a\\|b |\n", readFile(t, path))
+	})
+
+	t.Run("junit", func(t *testing.T) {
+		// given
+		path := filepath.Join(t.TempDir(), "results-junit.xml")
+		thresholds := Thresholds{
+			"Average Idler Update Time (s)":                          {Max: ptr(2)},
+			"Max host-operator-controller-manager Memory Usage (MB)": {Max: ptr(500)},
+			"Number of Users":      {Min: ptr(100)},
+			"Users Churn Time (m)": {Max: ptr(10)},
+		}
+
+		// when
+		err := junitWriter{path: path, thresholds: thresholds}.Write(testResults[:5])
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="setup" tests="5" failures="3">
+  <testcase name="Number of Users" classname="setup">
+    <failure message="value 10 is below the min threshold of 100"></failure>
+    <system-out>10</system-out>
+  </testcase>
+  <testcase name="Resumed Run" classname="setup">
+    <system-out>false</system-out>
+  </testcase>
+  <testcase name="Average Idler Update Time (s)" classname="setup">
+    <system-out>1.5</system-out>
+  </testcase>
+  <testcase name="Max host-operator-controller-manager Memory Usage (MB)" classname="setup">
+    <failure message="value 612.25 is above the max threshold of 500"></failure>
+    <system-out>612.25</system-out>
+  </testcase>
+  <testcase name="Users Churn Time (m)" classname="setup">
+    <failure message="no result for this item, the threshold can&#39;t be checked"></failure>
+  </testcase>
+</testsuite>`, readFile(t, path))
+	})
+}
+
+func TestThresholds(t *testing.T) {
+	t.Run("load", func(t *testing.T) {
+		// given
+		path := filepath.Join(t.TempDir(), "thresholds.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+"Average Idler Update Time (s)":
+  max: 2
+"Number of Users":
+  min: 1
+  max: 2000
+`), 0600))
+
+		// when
+		thresholds, err := LoadThresholds(path)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, Thresholds{
+			"Average Idler Update Time (s)": {Max: ptr(2)},
+			"Number of Users":               {Min: ptr(1), Max: ptr(2000)},
+		}, thresholds)
+	})
+
+	t.Run("load failures", func(t *testing.T) {
+		t.Run("file not found", func(t *testing.T) {
+			_, err := LoadThresholds(filepath.Join(t.TempDir(), "not-found.yaml"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+
+		t.Run("invalid content", func(t *testing.T) {
+			// given
+			path := filepath.Join(t.TempDir(), "thresholds.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(`"Number of Users": 10`), 0600))
+
+			// when
+			_, err := LoadThresholds(path)
+
+			// then
+			require.ErrorContains(t, err, "invalid thresholds file '"+path+"'")
+		})
+	})
+
+	t.Run("check", func(t *testing.T) {
+		thresholds := Thresholds{
+			"Resumed Run":     {Max: ptr(1)},
+			"Number of Users": {Min: ptr(1), Max: ptr(2000)},
+		}
+		assert.Empty(t, thresholds.Check(NewEntry("Number of Users", "10")))
+		assert.Empty(t, thresholds.Check(NewEntry("Total Running Time (m)", "100")))
+		assert.Equal(t, "value 2001 is above the max threshold of 2000", thresholds.Check(NewEntry("Number of Users", "2001")))
+		assert.Equal(t, "value 0 is below the min threshold of 1", thresholds.Check(NewEntry("Number of Users", "0")))
+		assert.Equal(t, "value 'false' is not a number", thresholds.Check(NewEntry("Resumed Run", "false")))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		thresholds := Thresholds{
+			"Users Churn Time (m)":          {Max: ptr(10)},
+			"Number of Users":               {Min: ptr(1)},
+			"Average Idler Update Time (s)": {Max: ptr(2)},
+		}
+		assert.Equal(t, []string{"Average Idler Update Time (s)", "Users Churn Time (m)"}, thresholds.Missing([]Entry{NewEntry("Number of Users", "10")}))
+		assert.Empty(t, thresholds.Missing([]Entry{NewEntry("Number of Users", "10"), NewEntry("Users Churn Time (m)", "5"), NewEntry("Average Idler Update Time (s)", "1")}))
+	})
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("csv", func(t *testing.T) {
+		// given
+		path := filepath.Join(t.TempDir(), "results.csv")
+		require.NoError(t, os.WriteFile(path, []byte("Item,Value\nNumber of Users,10\nAverage Idler Update Time (s),1.50\n"), 0600))
+
+		// when
+		es, err := Load(path)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []Entry{
+			NewEntry("Number of Users", "10"),
+			NewEntry("Average Idler Update Time (s)", "1.50"),
+		}, es)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		// given
+		path := filepath.Join(t.TempDir(), "results.json")
+		require.NoError(t, jsonWriter{path: path}.Write(testResults[:4]))
+
+		// when
+		es, err := Load(path)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, entries(testResults[:4]), es)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("file not found", func(t *testing.T) {
+			_, err := Load(filepath.Join(t.TempDir(), "not-found.csv"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+
+		t.Run("invalid json", func(t *testing.T) {
+			// given
+			path := filepath.Join(t.TempDir(), "results.json")
+			require.NoError(t, os.WriteFile(path, []byte("Item,Value"), 0600))
+
+			// when
+			_, err := Load(path)
+
+			// then
+			require.ErrorContains(t, err, "invalid results file '"+path+"'")
+		})
+	})
+}
@@ -3,6 +3,7 @@ package results
 import (
 	"encoding/csv"
 	"os"
+	"strings"
 
 	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
@@ -15,28 +16,58 @@ type Writer interface {
 
 type Results struct {
 	stdOutWriter Writer
-	csvWriter    Writer
+	fileWriters  []Writer
+	filepaths    []string
 	results      [][]string
+	thresholds   Thresholds
 	term         terminal.Terminal
 }
 
-func New(term terminal.Terminal) *Results {
-	csvFile, err := os.Create(cfg.ResultsFilepath())
-	if err != nil {
-		term.Infof("failed creating file: %s", err)
-		os.Exit(1)
-	}
-
-	return &Results{
+// New returns the results of a run that are written to the terminal and to a file per given format, next to each other.
+// The thresholds are used to mark the results as failures in the JUnit format.
+func New(term terminal.Terminal, formats []string, thresholds Thresholds) *Results {
+	r := &Results{
 		results:      make([][]string, 0),
-		csvWriter:    csvWriter{csvFile},
+		thresholds:   thresholds,
 		stdOutWriter: terminalWriter{term},
 		term:         term,
 	}
+	for _, format := range formats {
+		path := Filepath(format)
+		var w Writer
+		switch format {
+		case CSV:
+			csvFile, err := os.Create(path)
+			if err != nil {
+				term.Infof("failed creating file: %s", err)
+				os.Exit(1)
+			}
+			w = csvWriter{csvFile}
+		case JSON:
+			w = jsonWriter{path: path}
+		case Markdown:
+			w = markdownWriter{path: path}
+		case JUnit:
+			w = junitWriter{path: path, thresholds: thresholds}
+		default:
+			term.Fatalf(ValidateFormats(formats), "invalid output format")
+		}
+		r.fileWriters = append(r.fileWriters, w)
+		r.filepaths = append(r.filepaths, path)
+	}
+	return r
+}
+
+// Filepath returns the path of the results file of the given format
+func Filepath(format string) string {
+	if format == CSV {
+		return cfg.ResultsFilepath()
+	}
+	return strings.TrimSuffix(cfg.ResultsFilepath(), ".csv") + extensions[format]
 }
 
 func (r *Results) writeResults() error {
-	for _, w := range []Writer{r.stdOutWriter, r.csvWriter} {
+	for _, w := range append([]Writer{r.stdOutWriter}, r.fileWriters...) {
 		if err := w.Write(r.results); err != nil {
 			return err
 		}
@@ -76,11 +107,16 @@ func (w terminalWriter) Close() error {
 	return nil
 }
 
-// OutputResults outputs the aggregated results to the terminal and a csv file
+// OutputResults outputs the aggregated results to the terminal and the results files
 func (r *Results) OutputResults() {
 	if err := r.writeResults(); err != nil {
 		r.term.Fatalf(err, "failed to write results")
 	}
+	for _, name := range r.thresholds.Missing(entries(r.results)) {
+		r.term.Infof("⚠️  '%s': %s", name, missingMessage)
+	}
 
-	r.term.Infof("\nResults file: " + cfg.ResultsFilepath())
+	for _, path := range r.filepaths {
+		r.term.Infof("\nResults file: " + path)
+	}
 }
@@ -0,0 +1,73 @@
+package results
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ghodss/yaml"
+)
+
+// Threshold is the range of acceptable values of a result, a bound is not checked when it's not set
+type Threshold struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// Thresholds are the thresholds of the results, by result item name
+type Thresholds map[string]Threshold
+
+// LoadThresholds reads the thresholds from the YAML file at the given path, eg.
+//
+//	"Average Idler Update Time (s)":
+//	  max: 2
+func LoadThresholds(path string) (Thresholds, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	thresholds := Thresholds{}
+	if err := yaml.Unmarshal(content, &thresholds); err != nil {
+		return nil, fmt.Errorf("invalid thresholds file '%s': %w", path, err)
+	}
+	return thresholds, nil
+}
+
+// Check returns a message describing why the given entry breaks its threshold, or an empty string if it doesn't
+func (t Thresholds) Check(e Entry) string {
+	threshold, ok := t[e.Name]
+	if !ok {
+		return ""
+	}
+	value, ok := e.Value.(float64)
+	if !ok {
+		return fmt.Sprintf("value '%v' is not a number", e.Value)
+	}
+	if threshold.Max != nil && value > *threshold.Max {
+		return fmt.Sprintf("value %g is above the max threshold of %g", value, *threshold.Max)
+	}
+	if threshold.Min != nil && value < *threshold.Min {
+		return fmt.Sprintf("value %g is below the min threshold of %g", value, *threshold.Min)
+	}
+	return ""
+}
+
+// Missing returns the names of the items that have a threshold but are not part of the given entries, sorted by name,
+// eg. when the item was renamed or its phase was skipped
+func (t Thresholds) Missing(es []Entry) []string {
+	found := make(map[string]bool, len(es))
+	for _, e := range es {
+		found[e.Name] = true
+	}
+	var missing []string
+	for name := range t {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// missingMessage is the failure message of an item that has a threshold but is not part of the results
+const missingMessage = "no result for this item, the threshold can't be checked"
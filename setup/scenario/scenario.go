@@ -0,0 +1,290 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/arrival"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/churn"
+	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/lifecycle"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/metrics/queries"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/resources"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/users"
+
+	"github.com/ghodss/yaml"
+)
+
+// the methods to sign up the users
+const (
+	// UserSignupMethod creates the UserSignups directly in the host operator namespace
+	UserSignupMethod = "usersignup"
+	// RegistrationServiceMethod signs up the users through the registration service API, like real users do
+	RegistrationServiceMethod = "registration-service"
+)
+
+// Scenario holds all the settings of a setup run. The users are provisioned in cohorts, when no cohort is defined
+// a single cohort is derived from the top-level user settings.
+type Scenario struct {
+	Name                 string   `json:"name,omitempty"`
+	UsernamePrefix       string   `json:"usernamePrefix,omitempty"`
+	Users                int      `json:"users"`
+	DefaultTemplateUsers int      `json:"defaultTemplateUsers"`
+	CustomTemplateUsers  int      `json:"customTemplateUsers"`
+	Templates            []string `json:"templates,omitempty"`
+	IdlerTimeout         string   `json:"idlerTimeout,omitempty"`
+	OperatorsLimit       int      `json:"operatorsLimit"`
+	Workloads            []string `json:"workloads,omitempty"`
+	// Queries is the path to a file with additional PromQL queries whose results are gathered during the run
+	Queries              string   `json:"queries,omitempty"`
+	SkipAdditionalWait   bool     `json:"skipWait,omitempty"`
+	SkipIdlerSetup       bool     `json:"skipIdler,omitempty"`
+	SkipInstallOperators bool     `json:"skipInstallOperators,omitempty"`
+	Cohorts              []Cohort `json:"cohorts,omitempty"`
+	// SignupProfile defines when the users are signed up, they are signed up as fast as possible when not set
+	SignupProfile *arrival.Profile `json:"signupProfile,omitempty"`
+	// Placement defines on which member cluster each user is provisioned, all the users are provisioned on a single member cluster by default
+	Placement users.PlacementStrategy `json:"placement,omitempty"`
+	// MemberWeights are the weights of the member clusters of the weighted placement
+	MemberWeights map[string]int `json:"memberWeights,omitempty"`
+	// SignupMethod defines how the users are signed up, the UserSignups are created directly by default
+	SignupMethod string `json:"signupMethod,omitempty"`
+	// Churn defines the churn made once the users are provisioned, there is no churn when not set
+	Churn *churn.Profile `json:"churn,omitempty"`
+	// Lifecycle are the steps in which some users are deactivated, reactivated or banned once the users are provisioned
+	Lifecycle []lifecycle.Step `json:"lifecycle,omitempty"`
+	// TemplateParams are the values of the parameters of the custom templates, which can be generated for each user (see resources.Params)
+	TemplateParams map[string]string `json:"templateParams,omitempty"`
+	// TemplateParamsSeed is the seed of the random values of the template parameters
+	TemplateParamsSeed int64 `json:"templateParamsSeed,omitempty"`
+	// OperatorsParallelism is the number of operators that are installed in parallel, they are installed one at a time by default
+	OperatorsParallelism int `json:"operatorsParallelism,omitempty"`
+}
+
+// Cohort is a group of users that share the same username prefix, space tier and custom templates
+type Cohort struct {
+	Name           string `json:"name"`
+	UsernamePrefix string `json:"usernamePrefix"`
+	Users          int    `json:"users"`
+	// SpaceTier is the tier the spaces of the users are moved to once provisioned, the default space tier is kept when empty
+	SpaceTier string `json:"spaceTier,omitempty"`
+	// DefaultTemplateUsers is how many users of the cohort have the default template applied, all of them when not set
+	DefaultTemplateUsers int `json:"defaultTemplateUsers"`
+	// Templates are the paths of the custom templates to apply for the users of the cohort
+	Templates []string `json:"templates,omitempty"`
+	// CustomTemplateUsers is how many users of the cohort have the custom templates applied, all of them when not set
+	CustomTemplateUsers int `json:"customTemplateUsers"`
+}
+
+// UnmarshalJSON sets the number of default and custom template users of the cohort to its number of users when they are not specified
+func (c *Cohort) UnmarshalJSON(data []byte) error {
+	type cohort Cohort
+	aux := struct {
+		*cohort
+		DefaultTemplateUsers *int `json:"defaultTemplateUsers"`
+		CustomTemplateUsers  *int `json:"customTemplateUsers"`
+	}{
+		cohort: (*cohort)(c),
+	}
+	if err := decodeStrict(data, &aux); err != nil {
+		return err
+	}
+	c.DefaultTemplateUsers = c.Users
+	if aux.DefaultTemplateUsers != nil {
+		c.DefaultTemplateUsers = *aux.DefaultTemplateUsers
+	}
+	if aux.CustomTemplateUsers != nil {
+		c.CustomTemplateUsers = *aux.CustomTemplateUsers
+	} else if len(c.Templates) > 0 {
+		c.CustomTemplateUsers = c.Users
+	}
+	return nil
+}
+
+// Username returns the name of the i-th user (starting at 1) of the cohort
+func (c Cohort) Username(i int) string {
+	return fmt.Sprintf("%s-%04d", c.UsernamePrefix, i)
+}
+
+// Load reads the scenario file at the given path on top of the given base scenario,
+// the settings that are not specified in the file keep the values of the base scenario
+func Load(path string, base Scenario) (Scenario, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	data, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("invalid scenario file '%s': %w", path, err)
+	}
+	s := base
+	// cohorts are never inherited from the base scenario
+	s.Cohorts = nil
+	if err := decodeStrict(data, &s); err != nil {
+		return Scenario{}, fmt.Errorf("invalid scenario file '%s': %w", path, err)
+	}
+	return s, nil
+}
+
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// Resolve fills the settings that are derived from others: when cohorts are defined the total number of users is the sum of the users
+// of all cohorts, otherwise a single cohort is derived from the top-level user settings. The scenario is named after the username prefix by default.
+func (s *Scenario) Resolve() {
+	if len(s.Cohorts) == 0 {
+		s.Cohorts = []Cohort{{
+			Name:                 "default",
+			UsernamePrefix:       s.UsernamePrefix,
+			Users:                s.Users,
+			DefaultTemplateUsers: s.DefaultTemplateUsers,
+			Templates:            s.Templates,
+			CustomTemplateUsers:  s.CustomTemplateUsers,
+		}}
+	} else {
+		s.Users, s.DefaultTemplateUsers, s.CustomTemplateUsers = 0, 0, 0
+		s.UsernamePrefix, s.Templates = "", nil
+		for _, c := range s.Cohorts {
+			s.Users += c.Users
+			s.DefaultTemplateUsers += c.DefaultTemplateUsers
+			s.CustomTemplateUsers += c.CustomTemplateUsers
+		}
+	}
+	if s.Name == "" {
+		s.Name = s.Cohorts[0].UsernamePrefix
+	}
+	if s.Placement == "" {
+		s.Placement = users.Single
+	}
+	if s.SignupMethod == "" {
+		s.SignupMethod = UserSignupMethod
+	}
+	if s.OperatorsParallelism == 0 {
+		s.OperatorsParallelism = 1
+	}
+}
+
+// Validate checks the settings of a resolved scenario, the maxOperators is the number of operators that can be installed
+func (s Scenario) Validate(maxOperators int) error {
+	if s.Users < 1 {
+		return fmt.Errorf("invalid users value '%d': value must be more than 0", s.Users)
+	}
+	if s.OperatorsLimit < 0 || s.OperatorsLimit > maxOperators {
+		return fmt.Errorf("invalid operators limit value '%d': the operators limit value must be between 0 and '%d'", s.OperatorsLimit, maxOperators)
+	}
+	if s.OperatorsParallelism < 1 {
+		return fmt.Errorf("invalid operators parallelism value '%d': value must be more than 0", s.OperatorsParallelism)
+	}
+	if _, err := time.ParseDuration(s.IdlerTimeout); err != nil {
+		return fmt.Errorf("invalid idler-timeout value '%s': %w", s.IdlerTimeout, err)
+	}
+	for _, w := range s.Workloads {
+		if pair := strings.Split(w, ":"); len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return fmt.Errorf("invalid workloads values provided '%v' - values must be namespace:name pairs", s.Workloads)
+		}
+	}
+
+	if _, err := resources.ParseParams(s.TemplateParams, s.TemplateParamsSeed); err != nil {
+		return err
+	}
+	if s.Queries != "" {
+		if _, err := queries.LoadDefinitions(s.Queries); err != nil {
+			return err
+		}
+	}
+	if s.SignupProfile != nil {
+		if err := s.SignupProfile.Validate(s.Users); err != nil {
+			return err
+		}
+	}
+	if s.Churn != nil {
+		if err := s.Churn.Validate(s.Users); err != nil {
+			return err
+		}
+	}
+	if err := lifecycle.ValidateSteps(s.Lifecycle); err != nil {
+		return err
+	}
+	if err := users.ValidatePlacement(s.Placement, s.MemberWeights); err != nil {
+		return err
+	}
+	if s.SignupMethod != UserSignupMethod && s.SignupMethod != RegistrationServiceMethod {
+		return fmt.Errorf("invalid signup method '%s': must be one of [%s %s]", s.SignupMethod, UserSignupMethod, RegistrationServiceMethod)
+	}
+
+	names := map[string]bool{}
+	prefixes := map[string]bool{}
+	for _, c := range s.Cohorts {
+		if c.Name == "" || c.UsernamePrefix == "" {
+			return fmt.Errorf("invalid cohort '%s': the name and the username prefix of a cohort must be set", c.Name)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("invalid cohort '%s': the name is used by several cohorts", c.Name)
+		}
+		if prefixes[c.UsernamePrefix] {
+			return fmt.Errorf("invalid cohort '%s': the username prefix '%s' is used by several cohorts", c.Name, c.UsernamePrefix)
+		}
+		names[c.Name] = true
+		prefixes[c.UsernamePrefix] = true
+
+		if c.Users < 1 {
+			return fmt.Errorf("invalid cohort '%s': invalid users value '%d': value must be more than 0", c.Name, c.Users)
+		}
+		if err := usersWithinBounds(c.DefaultTemplateUsers, c.Users, cfg.DefaultTemplateUsersParam); err != nil {
+			return fmt.Errorf("invalid cohort '%s': %w", c.Name, err)
+		}
+		if err := usersWithinBounds(c.CustomTemplateUsers, c.Users, cfg.CustomTemplateUsersParam); err != nil {
+			return fmt.Errorf("invalid cohort '%s': %w", c.Name, err)
+		}
+		if c.CustomTemplateUsers > 0 && len(c.Templates) == 0 {
+			return fmt.Errorf("invalid cohort '%s': '%d' users are set to have custom templates applied but no custom templates were provided", c.Name, c.CustomTemplateUsers)
+		}
+		for _, p := range c.Templates {
+			absPath, err := filepath.Abs(p)
+			if err != nil {
+				return fmt.Errorf("invalid template file: '%s': %w", p, err)
+			}
+			if _, err := os.ReadFile(absPath); err != nil {
+				return fmt.Errorf("invalid template file: '%s': %w", absPath, err)
+			}
+		}
+	}
+	return nil
+}
+
+func usersWithinBounds(value, total int, templateType string) error {
+	if value < 0 || value > total {
+		return fmt.Errorf("invalid '%s' users value '%d': value must be between 0 and %d", templateType, value, total)
+	}
+	return nil
+}
+
+// SpaceTiers returns the space tiers that are set by the cohorts
+func (s Scenario) SpaceTiers() []string {
+	var tiers []string
+	found := map[string]bool{}
+	for _, c := range s.Cohorts {
+		if c.SpaceTier != "" && !found[c.SpaceTier] {
+			found[c.SpaceTier] = true
+			tiers = append(tiers, c.SpaceTier)
+		}
+	}
+	return tiers
+}
+
+// String returns the scenario as a compact JSON document so that it can be embedded in the results
+func (s Scenario) String() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
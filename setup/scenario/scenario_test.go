@@ -0,0 +1,407 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/arrival"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/churn"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/lifecycle"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/users"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoad(t *testing.T) {
+	// given
+	base := Scenario{
+		UsernamePrefix:       "zippy",
+		Users:                2000,
+		DefaultTemplateUsers: 2000,
+		CustomTemplateUsers:  2000,
+		IdlerTimeout:         "15s",
+		OperatorsLimit:       12,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		t.Run("settings only", func(t *testing.T) {
+			// given
+			path := writeScenario(t, `
+name: small
+users: 10
+defaultTemplateUsers: 5
+customTemplateUsers: 0
+idlerTimeout: 5m
+skipWait: true
+`)
+
+			// when
+			s, err := Load(path, base)
+
+			// then
+			require.NoError(t, err)
+			s.Resolve()
+			assert.Equal(t, "small", s.Name)
+			assert.Equal(t, 10, s.Users)
+			assert.Equal(t, 5, s.DefaultTemplateUsers)
+			assert.Equal(t, 0, s.CustomTemplateUsers)
+			assert.Equal(t, "5m", s.IdlerTimeout)
+			assert.Equal(t, 12, s.OperatorsLimit)      // inherited from the base
+			assert.Equal(t, 1, s.OperatorsParallelism) // one operator at a time by default
+			assert.True(t, s.SkipAdditionalWait)
+			require.Len(t, s.Cohorts, 1)
+			assert.Equal(t, Cohort{Name: "default", UsernamePrefix: "zippy", Users: 10, DefaultTemplateUsers: 5}, s.Cohorts[0])
+			require.NoError(t, s.Validate(12))
+		})
+
+		t.Run("cohorts", func(t *testing.T) {
+			// given
+			template := writeScenario(t, "") // any existing file will do
+			path := writeScenario(t, `
+name: mixed
+cohorts:
+- name: base
+  usernamePrefix: base
+  users: 10
+- name: onboarding
+  usernamePrefix: onboard
+  users: 5
+  spaceTier: appstudio
+  defaultTemplateUsers: 0
+  templates:
+  - `+template+`
+- name: partial
+  usernamePrefix: partial
+  users: 4
+  spaceTier: appstudio
+  templates:
+  - `+template+`
+  customTemplateUsers: 2
+`)
+
+			// when
+			s, err := Load(path, base)
+
+			// then
+			require.NoError(t, err)
+			s.Resolve()
+			assert.Equal(t, "mixed", s.Name)
+			assert.Equal(t, 19, s.Users)
+			assert.Equal(t, 14, s.DefaultTemplateUsers)
+			assert.Equal(t, 7, s.CustomTemplateUsers)
+			assert.Equal(t, []Cohort{
+				{Name: "base", UsernamePrefix: "base", Users: 10, DefaultTemplateUsers: 10},
+				{Name: "onboarding", UsernamePrefix: "onboard", Users: 5, SpaceTier: "appstudio", Templates: []string{template}, CustomTemplateUsers: 5},
+				{Name: "partial", UsernamePrefix: "partial", Users: 4, SpaceTier: "appstudio", DefaultTemplateUsers: 4, Templates: []string{template}, CustomTemplateUsers: 2},
+			}, s.Cohorts)
+			assert.Equal(t, []string{"appstudio"}, s.SpaceTiers())
+			assert.Equal(t, "onboard-0003", s.Cohorts[1].Username(3))
+			require.NoError(t, s.Validate(12))
+		})
+
+		t.Run("placement", func(t *testing.T) {
+			// given
+			path := writeScenario(t, `
+customTemplateUsers: 0
+placement: weighted
+memberWeights:
+  member-1: 3
+  member-2: 1
+`)
+
+			// when
+			s, err := Load(path, base)
+
+			// then
+			require.NoError(t, err)
+			s.Resolve()
+			assert.Equal(t, users.Weighted, s.Placement)
+			assert.Equal(t, UserSignupMethod, s.SignupMethod) // default
+			assert.Equal(t, map[string]int{"member-1": 3, "member-2": 1}, s.MemberWeights)
+			require.NoError(t, s.Validate(12))
+		})
+
+		t.Run("signup profile", func(t *testing.T) {
+			// given
+			path := writeScenario(t, `
+users: 100
+defaultTemplateUsers: 100
+customTemplateUsers: 0
+signupProfile:
+  type: step
+  rate: 1
+  rateStep: 2
+  stepDuration: 1m
+  duration: 10m
+`)
+
+			// when
+			s, err := Load(path, base)
+
+			// then
+			require.NoError(t, err)
+			s.Resolve()
+			assert.Equal(t, &arrival.Profile{
+				Type:         arrival.Step,
+				Rate:         1,
+				RateStep:     2,
+				StepDuration: metav1.Duration{Duration: time.Minute},
+				Duration:     metav1.Duration{Duration: 10 * time.Minute},
+			}, s.SignupProfile)
+			require.NoError(t, s.Validate(12))
+		})
+
+		t.Run("churn", func(t *testing.T) {
+			// given
+			path := writeScenario(t, `
+users: 100
+defaultTemplateUsers: 100
+customTemplateUsers: 0
+churn:
+  duration: 30m
+  rate: 5
+  users: 20
+`)
+
+			// when
+			s, err := Load(path, base)
+
+			// then
+			require.NoError(t, err)
+			s.Resolve()
+			assert.Equal(t, &churn.Profile{
+				Duration: metav1.Duration{Duration: 30 * time.Minute},
+				Rate:     5,
+				Users:    20,
+			}, s.Churn)
+			require.NoError(t, s.Validate(12))
+		})
+
+		t.Run("template params", func(t *testing.T) {
+			// given
+			path := writeScenario(t, `
+customTemplateUsers: 0
+templateParams:
+  REPLICAS: pick(1|2|3)
+  IMAGE: quay.io/bitnami/nginx
+templateParamsSeed: 42
+`)
+
+			// when
+			s, err := Load(path, base)
+
+			// then
+			require.NoError(t, err)
+			s.Resolve()
+			assert.Equal(t, map[string]string{"REPLICAS": "pick(1|2|3)", "IMAGE": "quay.io/bitnami/nginx"}, s.TemplateParams)
+			assert.Equal(t, int64(42), s.TemplateParamsSeed)
+			require.NoError(t, s.Validate(12))
+		})
+
+		t.Run("lifecycle", func(t *testing.T) {
+			// given
+			path := writeScenario(t, `
+customTemplateUsers: 0
+lifecycle:
+- action: deactivate
+  percentage: 10
+  rate: 2
+- action: reactivate
+  percentage: 5
+  rate: 1
+  maxInFlight: 10
+`)
+
+			// when
+			s, err := Load(path, base)
+
+			// then
+			require.NoError(t, err)
+			s.Resolve()
+			assert.Equal(t, []lifecycle.Step{
+				{Action: lifecycle.Deactivate, Percentage: 10, Rate: 2},
+				{Action: lifecycle.Reactivate, Percentage: 5, Rate: 1, MaxInFlight: 10},
+			}, s.Lifecycle)
+			require.NoError(t, s.Validate(12))
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("file not found", func(t *testing.T) {
+			// when
+			_, err := Load(filepath.Join(t.TempDir(), "not-found.yaml"), base)
+
+			// then
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+
+		t.Run("unknown setting", func(t *testing.T) {
+			// given
+			path := writeScenario(t, `usres: 10`)
+
+			// when
+			_, err := Load(path, base)
+
+			// then
+			require.EqualError(t, err, "invalid scenario file '"+path+"': json: unknown field \"usres\"")
+		})
+
+		t.Run("unknown cohort setting", func(t *testing.T) {
+			// given
+			path := writeScenario(t, `
+cohorts:
+- name: base
+  usernamePrefix: base
+  tier: base
+`)
+
+			// when
+			_, err := Load(path, base)
+
+			// then
+			require.EqualError(t, err, "invalid scenario file '"+path+"': json: unknown field \"tier\"")
+		})
+	})
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Scenario {
+		return Scenario{
+			Name:                 "test",
+			Users:                10,
+			IdlerTimeout:         "15s",
+			OperatorsLimit:       2,
+			OperatorsParallelism: 2,
+			Workloads:            []string{"ns:name"},
+			Placement:            users.Single,
+			SignupMethod:         UserSignupMethod,
+			Cohorts: []Cohort{
+				{Name: "first", UsernamePrefix: "first", Users: 5, DefaultTemplateUsers: 5},
+				{Name: "second", UsernamePrefix: "second", Users: 5},
+			},
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, valid().Validate(2))
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			modify func(s *Scenario)
+			err    string
+		}{
+			"no users": {
+				modify: func(s *Scenario) { s.Users = 0 },
+				err:    "invalid users value '0': value must be more than 0",
+			},
+			"operators limit": {
+				modify: func(s *Scenario) { s.OperatorsLimit = 3 },
+				err:    "invalid operators limit value '3': the operators limit value must be between 0 and '2'",
+			},
+			"operators parallelism": {
+				modify: func(s *Scenario) { s.OperatorsParallelism = -1 },
+				err:    "invalid operators parallelism value '-1': value must be more than 0",
+			},
+			"idler timeout": {
+				modify: func(s *Scenario) { s.IdlerTimeout = "soon" },
+				err:    "invalid idler-timeout value 'soon': time: invalid duration \"soon\"",
+			},
+			"workloads": {
+				modify: func(s *Scenario) { s.Workloads = []string{"ns:name:other"} },
+				err:    "invalid workloads values provided '[ns:name:other]' - values must be namespace:name pairs",
+			},
+			"missing cohort prefix": {
+				modify: func(s *Scenario) { s.Cohorts[1].UsernamePrefix = "" },
+				err:    "invalid cohort 'second': the name and the username prefix of a cohort must be set",
+			},
+			"duplicate cohort name": {
+				modify: func(s *Scenario) { s.Cohorts[1].Name = "first" },
+				err:    "invalid cohort 'first': the name is used by several cohorts",
+			},
+			"duplicate cohort prefix": {
+				modify: func(s *Scenario) { s.Cohorts[1].UsernamePrefix = "first" },
+				err:    "invalid cohort 'second': the username prefix 'first' is used by several cohorts",
+			},
+			"cohort without users": {
+				modify: func(s *Scenario) { s.Cohorts[1].Users = 0 },
+				err:    "invalid cohort 'second': invalid users value '0': value must be more than 0",
+			},
+			"too many default template users": {
+				modify: func(s *Scenario) { s.Cohorts[0].DefaultTemplateUsers = 6 },
+				err:    "invalid cohort 'first': invalid 'default' users value '6': value must be between 0 and 5",
+			},
+			"signup profile": {
+				modify: func(s *Scenario) { s.SignupProfile = &arrival.Profile{Type: arrival.Constant} },
+				err:    "invalid constant arrival profile: the rate must be more than 0",
+			},
+			"churn": {
+				modify: func(s *Scenario) {
+					s.Churn = &churn.Profile{Duration: metav1.Duration{Duration: time.Minute}, Rate: 1, Users: 100}
+				},
+				err: "invalid churn: invalid users value '100': value must be between 1 and 10",
+			},
+			"template params": {
+				modify: func(s *Scenario) { s.TemplateParams = map[string]string{"SUFFIX": "random(0)"} },
+				err:    "invalid template parameter 'SUFFIX=random(0)': the length of random() must be a number more than 0",
+			},
+			"lifecycle": {
+				modify: func(s *Scenario) {
+					s.Lifecycle = []lifecycle.Step{{Action: lifecycle.Reactivate, Percentage: 10, Rate: 1}}
+				},
+				err: "invalid lifecycle step 1: only 0% of the users were deactivated",
+			},
+			"placement": {
+				modify: func(s *Scenario) { s.Placement = users.Weighted },
+				err:    "invalid placement 'weighted': the weight of at least one member cluster must be set",
+			},
+			"signup method": {
+				modify: func(s *Scenario) { s.SignupMethod = "api" },
+				err:    "invalid signup method 'api': must be one of [usersignup registration-service]",
+			},
+			"custom template users without templates": {
+				modify: func(s *Scenario) { s.Cohorts[0].CustomTemplateUsers = 1 },
+				err:    "invalid cohort 'first': '1' users are set to have custom templates applied but no custom templates were provided",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				s := valid()
+				tc.modify(&s)
+
+				// when
+				err := s.Validate(2)
+
+				// then
+				require.EqualError(t, err, tc.err)
+			})
+		}
+
+		t.Run("template not found", func(t *testing.T) {
+			// given
+			s := valid()
+			s.Cohorts[0].Templates = []string{"not-found.yaml"}
+			absPath, err := filepath.Abs("not-found.yaml")
+			require.NoError(t, err)
+
+			// when
+			err = s.Validate(2)
+
+			// then
+			require.EqualError(t, err, "invalid template file: '"+absPath+"': open "+absPath+": no such file or directory")
+		})
+	})
+}
+
+func writeScenario(t *testing.T, content string) string {
+	f, err := os.CreateTemp(t.TempDir(), "scenario-*.yaml")
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return f.Name()
+}
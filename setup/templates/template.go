@@ -1,8 +1,12 @@
 package templates
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"sync"
 	"time"
@@ -10,10 +14,14 @@ import (
 	applyclientlib "github.com/codeready-toolchain/toolchain-common/pkg/client"
 
 	cfg "github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
 	multierror "github.com/hashicorp/go-multierror"
 	templatev1 "github.com/openshift/api/template/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	k8swait "k8s.io/apimachinery/pkg/util/wait"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/kubectl/pkg/scheme"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -28,24 +36,57 @@ func GetTemplateFromFile(filepath string) (*templatev1.Template, error) {
 	return GetTemplateFromContent(content)
 }
 
+// GetTemplateFromContent returns the OpenShift template of the given content, or a template with the objects of the given content
+// when it's made of plain YAML documents, eg. to run the setup on a cluster without the OpenShift APIs
 func GetTemplateFromContent(content []byte) (*templatev1.Template, error) {
 	decoder := serializer.NewCodecFactory(scheme.Scheme).UniversalDeserializer()
 	tmpl := &templatev1.Template{}
-	_, gvk, err := decoder.Decode(content, nil, tmpl)
-	if err != nil {
-		return nil, err
-	}
-	if gvk.Kind == "Template" { // expect an OpenShift template
+	if _, gvk, err := decoder.Decode(content, nil, tmpl); err == nil && gvk.Kind == "Template" { // expect an OpenShift template
 		return tmpl, nil
 	}
-	return nil, fmt.Errorf("wrong kind of object in the template file: '%s'", gvk)
+	return getTemplateFromDocuments(content)
+}
+
+// getTemplateFromDocuments returns a template with the objects of the YAML documents of the given content, one object per document.
+// The template has no parameters, the namespace of the objects is set when they are applied.
+func getTemplateFromDocuments(content []byte) (*templatev1.Template, error) {
+	tmpl := &templatev1.Template{}
+	reader := k8syaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := k8syaml.ToJSON(doc)
+		if err != nil {
+			return nil, err
+		}
+		if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || string(trimmed) == "null" { // empty document, eg. only comments
+			continue
+		}
+		typeMeta := metav1.TypeMeta{}
+		if err := json.Unmarshal(data, &typeMeta); err != nil {
+			return nil, fmt.Errorf("the document #%d of the template file is not an object: %w", len(tmpl.Objects)+1, err)
+		}
+		if typeMeta.APIVersion == "" || typeMeta.Kind == "" {
+			return nil, fmt.Errorf("the document #%d of the template file has no apiVersion or kind", len(tmpl.Objects)+1)
+		}
+		tmpl.Objects = append(tmpl.Objects, runtime.RawExtension{Raw: data})
+	}
+	if len(tmpl.Objects) == 0 {
+		return nil, fmt.Errorf("no objects found in the template file")
+	}
+	return tmpl, nil
 }
 
 // ApplyObjects applies the given objects in order
-func ApplyObjects(ctx context.Context, cl runtimeclient.Client, objsToApply []runtimeclient.Object, modifiers ...ClientObjectModifier) error {
+func ApplyObjects(ctx context.Context, term terminal.Terminal, cl runtimeclient.Client, objsToApply []runtimeclient.Object, modifiers ...ClientObjectModifier) error {
 	applycl := applyclientlib.NewSSAApplyClient(cl, fieldManager)
 	for _, obj := range objsToApply {
-		fmt.Printf("Applying %s object with name '%s' in namespace '%s'\n", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), obj.GetNamespace())
+		term.Debugw("applying object", "kind", obj.GetObjectKind().GroupVersionKind().Kind, "name", obj.GetName(), "namespace", obj.GetNamespace())
 		if err := applyObject(ctx, applycl, obj, modifiers...); err != nil {
 			return err
 		}
@@ -53,33 +94,43 @@ func ApplyObjects(ctx context.Context, cl runtimeclient.Client, objsToApply []ru
 	return nil
 }
 
-// ApplyObjectsConcurrently applies multiple objects concurrently
+// ApplyObjectsConcurrently applies multiple objects concurrently. When the context is done, the remaining objects are not applied
+// and the context error is returned once the objects being applied are done, those are not interrupted.
 func ApplyObjectsConcurrently(ctx context.Context, cl runtimeclient.Client, combinedObjsToProcess []runtimeclient.Object, modifiers ...ClientObjectModifier) error {
 	var objProcessors []<-chan error
-	objChannel := distribute(combinedObjsToProcess)
-	for i := 0; i < len(combinedObjsToProcess); i++ {
+	objChannel := distribute(ctx, combinedObjsToProcess)
+	for i := 0; i < len(combinedObjsToProcess) && ctx.Err() == nil; i++ {
 		objProcessors = append(objProcessors, startObjectProcessor(ctx, cl, objChannel, modifiers...))
 		time.Sleep(100 * time.Millisecond) // wait for a short time before starting each object processor to avoid hitting rate limits
 	}
 
 	// combine the results
 	var overallErr error
+	applied := 0
 	for err := range combineResults(objProcessors...) {
+		applied++
 		if err != nil {
 			overallErr = multierror.Append(overallErr, err)
 		}
 	}
+	if applied < len(combinedObjsToProcess) {
+		overallErr = multierror.Append(overallErr, fmt.Errorf("only %d of %d objects were applied: %w", applied, len(combinedObjsToProcess), ctx.Err()))
+	}
 
 	return overallErr
 }
 
-func distribute(objs []runtimeclient.Object) <-chan runtimeclient.Object {
+func distribute(ctx context.Context, objs []runtimeclient.Object) <-chan runtimeclient.Object {
 	out := make(chan runtimeclient.Object)
 	go func() {
+		defer close(out)
 		for _, obj := range objs {
-			out <- obj
+			select {
+			case out <- obj:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(out)
 	}()
 	return out
 }
@@ -113,7 +164,7 @@ func startObjectProcessor(ctx context.Context, cl runtimeclient.Client, objSourc
 	go func() {
 		applycl := applyclientlib.NewSSAApplyClient(cl, fieldManager)
 		for obj := range objSource {
-			out <- applyObject(ctx, applycl, obj, modifiers...)
+			out <- applyObject(context.WithoutCancel(ctx), applycl, obj, modifiers...)
 			time.Sleep(100 * time.Millisecond)
 		}
 		close(out)
@@ -1,18 +1,23 @@
 package terminal
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/fatih/color"
 	"github.com/manifoldco/promptui"
 )
 
 // Terminal a wrapper around a Cobra command, with extra methods
-// to display messages.
+// to display messages. Each message is also an event with a level and
+// key/value pairs, which is written to the log file when one is set.
 type Terminal interface {
 	InOrStdin() io.Reader
 	OutOrStdout() io.Writer
@@ -20,8 +25,13 @@ type Terminal interface {
 	Infof(msg string, args ...interface{})
 	Errorf(err error, msg string, args ...interface{})
 	Fatalf(err error, msg string, args ...interface{})
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Errorw(err error, msg string, keysAndValues ...interface{})
 	PromptBoolf(msg string, args ...interface{}) bool
 	AddPreFatalExitHook(func())
+	SetLogFile(path string) error
+	MuteConsole() func()
 }
 
 // New returns a new terminal with the given funcs to
@@ -34,6 +44,25 @@ func New(in func() io.Reader, out func() io.Writer, verbose bool) Terminal {
 	}
 }
 
+// NewLogWriter returns a writer whose lines are logged as debug events of the given source, eg. to log the messages of client-go
+func NewLogWriter(t Terminal, source string) io.Writer {
+	return logWriter{term: t, source: source}
+}
+
+type logWriter struct {
+	term   Terminal
+	source string
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\n") {
+		if line != "" {
+			w.term.Debugw(line, "source", w.source)
+		}
+	}
+	return len(p), nil
+}
+
 // InOrStdin returns an `io.Reader` to read the user's input
 func (t *DefaultTerminal) InOrStdin() io.Reader {
 	return t.in()
@@ -47,43 +76,117 @@ func (t *DefaultTerminal) OutOrStdout() io.Writer {
 // DefaultTerminal a wrapper around a Cobra command, with extra methods
 // to display messages.
 type DefaultTerminal struct {
-	in             func() io.Reader
-	out            func() io.Writer
+	in  func() io.Reader
+	out func() io.Writer
+	// hooksMu guards the hooks, which can be run by another goroutine, eg. when the run is interrupted
+	hooksMu        sync.Mutex
 	fatalExitHooks []func()
 	verbose        bool
+	// muted is true while the console is muted, the events are then only written to the log file
+	muted atomic.Bool
+	logMu sync.RWMutex
+	log   *slog.Logger
 }
 
-// Debugf prints a message (if verbose was enabled)
-func (t *DefaultTerminal) Debugf(msg string, args ...interface{}) {
-	if !t.verbose {
+// SetLogFile writes all the events from now on to the file at the given path as JSON lines, whatever their level
+func (t *DefaultTerminal) SetLogFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	t.logMu.Lock()
+	defer t.logMu.Unlock()
+	t.log = slog.New(slog.NewJSONHandler(f, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return nil
+}
+
+// MuteConsole stops displaying the events in the console, eg. while progress bars are displayed, until the returned func is called.
+// The events are still written to the log file and the fatal errors are always displayed.
+func (t *DefaultTerminal) MuteConsole() func() {
+	t.muted.Store(true)
+	return func() {
+		t.muted.Store(false)
+	}
+}
+
+// event writes the event to the log file, if any
+func (t *DefaultTerminal) event(level slog.Level, err error, msg string, keysAndValues ...interface{}) {
+	t.logMu.RLock()
+	defer t.logMu.RUnlock()
+	if t.log == nil || msg == "" {
+		return
+	}
+	if err != nil {
+		keysAndValues = append(keysAndValues, "error", err.Error())
+	}
+	t.log.Log(context.TODO(), level, msg, keysAndValues...)
+}
+
+// println displays the message in the console, followed by the key/value pairs
+func (t *DefaultTerminal) println(c *color.Color, msg string, keysAndValues ...interface{}) {
+	if t.muted.Load() {
 		return
 	}
-	if msg == "" {
-		fmt.Fprintln(t.OutOrStdout(), "")
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) {
+			msg += fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1])
+		} else {
+			msg += fmt.Sprintf(" %v", keysAndValues[i])
+		}
+	}
+	if c != nil {
+		c.Fprintln(t.OutOrStdout(), msg) // nolint:errcheck
 		return
 	}
-	fmt.Fprintln(t.OutOrStdout(), fmt.Sprintf(msg, args...))
+	fmt.Fprintln(t.OutOrStdout(), msg)
+}
+
+// Debugf prints a message (if verbose was enabled)
+func (t *DefaultTerminal) Debugf(msg string, args ...interface{}) {
+	t.Debugw(fmt.Sprintf(msg, args...))
 }
 
 // Infof displays a message with the default color
 func (t *DefaultTerminal) Infof(msg string, args ...interface{}) {
-	if msg == "" {
-		fmt.Fprintln(t.OutOrStdout(), "")
-		return
-	}
-	fmt.Fprintln(t.OutOrStdout(), fmt.Sprintf(msg, args...))
+	t.Infow(fmt.Sprintf(msg, args...))
 }
 
 // Errorf prints a message with the red color
 func (t *DefaultTerminal) Errorf(err error, msg string, args ...interface{}) {
-	color.New(color.FgRed).Fprintln(t.OutOrStdout(), fmt.Sprintf("%s: %s", fmt.Sprintf(msg, args...), err.Error())) // nolint:errcheck
+	t.Errorw(err, fmt.Sprintf(msg, args...))
+}
+
+// Debugw prints a message with the given key/value pairs (if verbose was enabled)
+func (t *DefaultTerminal) Debugw(msg string, keysAndValues ...interface{}) {
+	t.event(slog.LevelDebug, nil, msg, keysAndValues...)
+	if !t.verbose {
+		return
+	}
+	t.println(nil, msg, keysAndValues...)
+}
+
+// Infow displays a message with the given key/value pairs with the default color
+func (t *DefaultTerminal) Infow(msg string, keysAndValues ...interface{}) {
+	t.event(slog.LevelInfo, nil, msg, keysAndValues...)
+	t.println(nil, msg, keysAndValues...)
+}
+
+// Errorw prints a message with the given key/value pairs with the red color
+func (t *DefaultTerminal) Errorw(err error, msg string, keysAndValues ...interface{}) {
+	t.event(slog.LevelError, err, msg, keysAndValues...)
+	t.println(color.New(color.FgRed), fmt.Sprintf("%s: %s", msg, err.Error()), keysAndValues...)
 }
 
 // Fatalf prints a message with the red color and exits the program with a `1` return code
 func (t *DefaultTerminal) Fatalf(err error, msg string, args ...interface{}) {
 	defer os.Exit(1)
-	for _, hook := range t.fatalExitHooks {
-		hook()
+	t.muted.Store(false)
+	t.hooksMu.Lock()
+	hooks := append([]func(){}, t.fatalExitHooks...)
+	t.hooksMu.Unlock()
+	// like deferred calls, the last hook registered runs first, so that eg. the resources used by a hook are deleted after it
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
 	}
 	t.Errorf(err, msg, args...)
 }
@@ -107,6 +210,9 @@ func (t *DefaultTerminal) PromptBoolf(msg string, args ...interface{}) bool {
 	return strings.ToLower(result) == "y"
 }
 
+// AddPreFatalExitHook registers a hook that is run before a fatal exit, the hooks are run in the reverse order of their registration
 func (t *DefaultTerminal) AddPreFatalExitHook(hook func()) {
+	t.hooksMu.Lock()
+	defer t.hooksMu.Unlock()
 	t.fatalExitHooks = append(t.fatalExitHooks, hook)
 }
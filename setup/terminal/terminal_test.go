@@ -0,0 +1,102 @@
+package terminal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	term := New(func() io.Reader { return nil }, func() io.Writer { return out }, false)
+	logPath := filepath.Join(t.TempDir(), "log.jsonl")
+	require.NoError(t, term.SetLogFile(logPath))
+
+	// when
+	term.Infof("🍿 provisioning %d users", 10)
+	term.Infow("verified installation of operator", "subscription", "kiali-ossm", "duration", "2s")
+	term.Debugw("applying object", "kind", "ConfigMap")
+	term.Errorw(fmt.Errorf("timeout"), "failed to sample the metrics query", "query", "up")
+
+	// then
+	t.Run("console", func(t *testing.T) {
+		assert.Equal(t, "🍿 provisioning 10 users\n"+
+			"verified installation of operator subscription=kiali-ossm duration=2s\n"+
+			"failed to sample the metrics query: timeout query=up\n", out.String())
+	})
+
+	t.Run("log file", func(t *testing.T) {
+		events := readEvents(t, logPath)
+		require.Len(t, events, 4)
+		assert.Equal(t, "INFO", events[0]["level"])
+		assert.Equal(t, "🍿 provisioning 10 users", events[0]["msg"])
+		assert.Equal(t, "kiali-ossm", events[1]["subscription"])
+		assert.Equal(t, "2s", events[1]["duration"])
+		// the debug events are always written to the log file
+		assert.Equal(t, "DEBUG", events[2]["level"])
+		assert.Equal(t, "ConfigMap", events[2]["kind"])
+		assert.Equal(t, "ERROR", events[3]["level"])
+		assert.Equal(t, "timeout", events[3]["error"])
+		assert.Equal(t, "up", events[3]["query"])
+	})
+}
+
+func TestMuteConsole(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	term := New(func() io.Reader { return nil }, func() io.Writer { return out }, true)
+	logPath := filepath.Join(t.TempDir(), "log.jsonl")
+	require.NoError(t, term.SetLogFile(logPath))
+
+	// when
+	unmute := term.MuteConsole()
+	term.Infof("muted")
+	term.Debugf("muted too")
+	unmute()
+	term.Infof("unmuted")
+
+	// then
+	assert.Equal(t, "unmuted\n", out.String())
+	assert.Len(t, readEvents(t, logPath), 3)
+}
+
+func TestLogWriter(t *testing.T) {
+	// given
+	out := &bytes.Buffer{}
+	term := New(func() io.Reader { return nil }, func() io.Writer { return out }, false)
+	logPath := filepath.Join(t.TempDir(), "log.jsonl")
+	require.NoError(t, term.SetLogFile(logPath))
+	w := NewLogWriter(term, "client-go")
+
+	// when
+	_, err := w.Write([]byte("Waited for 1.1s due to client-side throttling\n"))
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, out.String()) // only written to the log file when not verbose
+	events := readEvents(t, logPath)
+	require.Len(t, events, 1)
+	assert.Equal(t, "Waited for 1.1s due to client-side throttling", events[0]["msg"])
+	assert.Equal(t, "client-go", events[0]["source"])
+}
+
+func readEvents(t *testing.T, path string) []map[string]interface{} {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var events []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		event := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,155 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// PrometheusResponse is a recorded response of the prometheus HTTP API
+type PrometheusResponse struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// FakePrometheus is an in-process prometheus HTTP API that replays the responses recorded for each query. When several responses
+// are recorded for a query, they are replayed in order and the last one is repeated. The queries with no recorded response get the
+// default response if one is set, otherwise a 400 error.
+type FakePrometheus struct {
+	*httptest.Server
+	mu              sync.Mutex
+	responses       map[string][]PrometheusResponse
+	defaultResponse *PrometheusResponse
+	calls           map[string]int
+}
+
+// NewFakePrometheus starts a fake prometheus server that is closed at the end of the test
+func NewFakePrometheus(t *testing.T) *FakePrometheus {
+	p := &FakePrometheus{
+		responses: map[string][]PrometheusResponse{},
+		calls:     map[string]int{},
+	}
+	p.Server = httptest.NewServer(http.HandlerFunc(p.handle))
+	t.Cleanup(p.Close)
+	return p
+}
+
+// Record adds the responses to replay for the given query
+func (p *FakePrometheus) Record(query string, responses ...PrometheusResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responses[query] = append(p.responses[query], responses...)
+}
+
+// RecordDefault sets the response of the queries that have no recorded response
+func (p *FakePrometheus) RecordDefault(response PrometheusResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.defaultResponse = &response
+}
+
+// LoadRecording adds the responses of the recording file at the given path, a JSON object with the list of responses of each query
+func (p *FakePrometheus) LoadRecording(t *testing.T, path string) {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	recording := map[string][]PrometheusResponse{}
+	require.NoError(t, json.Unmarshal(content, &recording))
+	for query, responses := range recording {
+		p.Record(query, responses...)
+	}
+}
+
+// Calls returns how many times the given query was received
+func (p *FakePrometheus) Calls(query string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[query]
+}
+
+func (p *FakePrometheus) handle(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := r.Form.Get("query")
+
+	p.mu.Lock()
+	p.calls[query]++
+	var response PrometheusResponse
+	if responses := p.responses[query]; len(responses) > 0 {
+		response = responses[0]
+		if len(responses) > 1 {
+			p.responses[query] = responses[1:]
+		}
+	} else if p.defaultResponse != nil {
+		response = *p.defaultResponse
+	} else {
+		response = ErrorResponse(http.StatusBadRequest, fmt.Sprintf("no recorded response for query '%s'", query))
+	}
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.Status)
+	_, _ = w.Write(response.Body)
+}
+
+// VectorResponse returns a successful response of an instant query with a sample per given value
+func VectorResponse(values ...float64) PrometheusResponse {
+	return successResponse("vector", vectorResult(values), "")
+}
+
+// VectorResponseWithWarnings returns a successful response of an instant query with a sample per given value and the given warnings
+func VectorResponseWithWarnings(warnings []string, values ...float64) PrometheusResponse {
+	data, _ := json.Marshal(warnings)
+	return successResponse("vector", vectorResult(values), string(data))
+}
+
+// ScalarResponse returns a successful response of an instant query that evaluates to a scalar
+func ScalarResponse(value float64) PrometheusResponse {
+	return successResponse("scalar", fmt.Sprintf(`[%d,"%g"]`, time.Now().Unix(), value), "")
+}
+
+func vectorResult(values []float64) string {
+	samples := make([]string, 0, len(values))
+	for i, v := range values {
+		samples = append(samples, fmt.Sprintf(`{"metric":{"pod":"pod-%d"},"value":[%d,"%g"]}`, i, time.Now().Unix(), v))
+	}
+	return "[" + strings.Join(samples, ",") + "]"
+}
+
+// MatrixResponse returns a successful response of a range query with a single series made of the given values, one per step starting at the given time
+func MatrixResponse(start time.Time, step time.Duration, values ...float64) PrometheusResponse {
+	points := make([]string, 0, len(values))
+	for i, v := range values {
+		points = append(points, fmt.Sprintf(`[%d,"%g"]`, start.Add(time.Duration(i)*step).Unix(), v))
+	}
+	return successResponse("matrix", `[{"metric":{"pod":"pod-0"},"values":[`+strings.Join(points, ",")+`]}]`, "")
+}
+
+// ErrorResponse returns an error response with the given status code
+func ErrorResponse(status int, msg string) PrometheusResponse {
+	data, _ := json.Marshal(msg)
+	return PrometheusResponse{
+		Status: status,
+		Body:   json.RawMessage(fmt.Sprintf(`{"status":"error","errorType":"bad_data","error":%s}`, data)),
+	}
+}
+
+func successResponse(resultType, result, warnings string) PrometheusResponse {
+	body := fmt.Sprintf(`{"status":"success","data":{"resultType":"%s","result":%s}`, resultType, result)
+	if warnings != "" {
+		body += `,"warnings":` + warnings
+	}
+	return PrometheusResponse{
+		Status: http.StatusOK,
+		Body:   json.RawMessage(body + "}"),
+	}
+}
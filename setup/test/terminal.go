@@ -0,0 +1,12 @@
+package test
+
+import (
+	"io"
+
+	"github.com/codeready-toolchain/toolchain-e2e/setup/terminal"
+)
+
+// NewTerminal returns a terminal that discards all its messages
+func NewTerminal() terminal.Terminal {
+	return terminal.New(func() io.Reader { return nil }, func() io.Writer { return io.Discard }, false)
+}
@@ -3,25 +3,25 @@ package users
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
-	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
 	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
 	"github.com/codeready-toolchain/toolchain-common/pkg/states"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
 
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	k8swait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-var memberClusterName string
-
-func Create(cl client.Client, username, hostOperatorNamespace, memberOperatorNamespace string) error {
-	memberClusterName, err := getMemberClusterName(cl, hostOperatorNamespace, memberOperatorNamespace)
-	if err != nil {
-		return fmt.Errorf("unable to lookup member cluster name, ensure the sandbox setup steps are followed")
-	}
+// Create creates the UserSignup of the given user on the given target cluster, the host operator picks the member cluster when it's empty.
+// A UserSignup that already exists is not considered as an error, eg. when it was created by an interrupted run that is resumed.
+func Create(cl client.Client, username, hostOperatorNamespace, targetCluster string) error {
 	usersignup := &toolchainv1alpha1.UserSignup{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: hostOperatorNamespace,
@@ -31,7 +31,7 @@ func Create(cl client.Client, username, hostOperatorNamespace, memberOperatorNam
 			},
 		},
 		Spec: toolchainv1alpha1.UserSignupSpec{
-			TargetCluster: memberClusterName,
+			TargetCluster: targetCluster,
 			IdentityClaims: toolchainv1alpha1.IdentityClaimsEmbedded{
 				PropagatedClaims: toolchainv1alpha1.PropagatedClaims{
 					Email: fmt.Sprintf("%s@fake.test", username),
@@ -43,26 +43,85 @@ func Create(cl client.Client, username, hostOperatorNamespace, memberOperatorNam
 	}
 	states.SetApprovedManually(usersignup, true)
 
-	return cl.Create(context.TODO(), usersignup)
+	if err := cl.Create(context.TODO(), usersignup); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
 }
 
-func getMemberClusterName(cl client.Client, hostOperatorNamespace, memberOperatorNamespace string) (string, error) {
-	if memberClusterName != "" {
-		return memberClusterName, nil
-	}
-	var memberCluster toolchainv1alpha1.ToolchainCluster
+// Approve approves the UserSignup of the given user, once it has been created by the registration service, and sets its target cluster.
+// The host operator picks the member cluster when the target cluster is empty.
+func Approve(cl client.Client, username, hostOperatorNamespace, targetCluster string) error {
+	var usersignup *toolchainv1alpha1.UserSignup
 	err := k8swait.PollUntilContextTimeout(context.TODO(), configuration.DefaultRetryInterval, configuration.DefaultTimeout, true, func(ctx context.Context) (bool, error) {
-		clusters := &toolchainv1alpha1.ToolchainClusterList{}
-		if err := cl.List(context.TODO(), clusters, client.InNamespace(hostOperatorNamespace)); err != nil {
+		usersignup = &toolchainv1alpha1.UserSignup{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: hostOperatorNamespace, Name: username}, usersignup); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return false, nil
+			}
 			return false, err
 		}
-		for _, cluster := range clusters.Items {
-			if cluster.Status.OperatorNamespace == memberOperatorNamespace && condition.IsTrue(cluster.Status.Conditions, toolchainv1alpha1.ConditionReady) {
-				memberCluster = cluster
-				return true, nil
-			}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("usersignup '%s' was not found: %w", username, err)
+	}
+	// the usersignup is updated by the host operator at the same time, so retry on conflicts
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: hostOperatorNamespace, Name: username}, usersignup); err != nil {
+			return err
+		}
+		if states.ApprovedManually(usersignup) && usersignup.Spec.TargetCluster == targetCluster {
+			return nil
+		}
+		states.SetApprovedManually(usersignup, true)
+		usersignup.Spec.TargetCluster = targetCluster
+		return cl.Update(context.TODO(), usersignup)
+	})
+}
+
+// ListNames returns the sorted names of the UserSignups that were created with the given username prefix
+func ListNames(cl client.Client, hostOperatorNamespace, usernamePrefix string) ([]string, error) {
+	usersignups := &toolchainv1alpha1.UserSignupList{}
+	if err := cl.List(context.TODO(), usersignups, client.InNamespace(hostOperatorNamespace)); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, us := range usersignups.Items {
+		if strings.HasPrefix(us.Name, usernamePrefix+"-") {
+			names = append(names, us.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete deletes the UserSignup with the given name, a UserSignup that is already gone is not considered as an error
+func Delete(cl client.Client, username, hostOperatorNamespace string) error {
+	usersignup := &toolchainv1alpha1.UserSignup{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: hostOperatorNamespace,
+			Name:      username,
+		},
+	}
+	if err := cl.Delete(context.TODO(), usersignup); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// SetSpaceTier moves the space of the given user to the given tier
+func SetSpaceTier(cl client.Client, username, hostOperatorNamespace, tier string) error {
+	// the space is updated by the host operator at the same time, so retry on conflicts
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		space := &toolchainv1alpha1.Space{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: hostOperatorNamespace, Name: username}, space); err != nil {
+			return err
+		}
+		if space.Spec.TierName == tier {
+			return nil
 		}
-		return false, nil
+		space.Spec.TierName = tier
+		return cl.Update(context.TODO(), space)
 	})
-	return memberCluster.Name, err
 }
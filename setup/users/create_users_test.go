@@ -1,71 +1,158 @@
 package users
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/states"
 	commontest "github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testspace "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func TestCreate(t *testing.T) {
 	// given
-	configuration.DefaultTimeout = time.Second * 5
+	hostOperatorNamespace := "toolchain-host-operator"
+
+	for name, targetCluster := range map[string]string{
+		"on target cluster":     "member-abcd",
+		"host operator decides": "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			// given
+			cl := commontest.NewFakeClient(t)
+
+			// when
+			err := Create(cl, "user-0001", hostOperatorNamespace, targetCluster)
+
+			// then
+			require.NoError(t, err)
+			usersignup := &toolchainv1alpha1.UserSignup{}
+			require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: hostOperatorNamespace, Name: "user-0001"}, usersignup))
+			assert.Equal(t, targetCluster, usersignup.Spec.TargetCluster)
+		})
+	}
+
+	t.Run("already exists", func(t *testing.T) {
+		// given
+		cl := commontest.NewFakeClient(t, userSignup(hostOperatorNamespace, "user-0001"))
+
+		// when
+		err := Create(cl, "user-0001", hostOperatorNamespace, "member-abcd")
+
+		// then
+		require.NoError(t, err)
+	})
+}
+
+func TestApprove(t *testing.T) {
+	// given
+	hostOperatorNamespace := "toolchain-host-operator"
 
 	t.Run("success", func(t *testing.T) {
 		// given
-		// needed otherwise the following tests can't verify what happens when `memberClusterName` cannot be retrieved
-		t.Cleanup(func() {
-			memberClusterName = ""
-		})
-		hostOperatorNamespace := "toolchain-host-operator"
-		memberOperatorNamespace := "toolchain-member-operator"
-		memberCluster := &toolchainv1alpha1.ToolchainCluster{
-			ObjectMeta: metav1.ObjectMeta{
-				Namespace: hostOperatorNamespace,
-				Name:      "member-abcd",
-			},
-			Status: toolchainv1alpha1.ToolchainClusterStatus{
-				OperatorNamespace: memberOperatorNamespace,
-				Conditions: []toolchainv1alpha1.Condition{
-					{
-						Type:   toolchainv1alpha1.ConditionReady,
-						Status: corev1.ConditionTrue,
-					},
-				},
-			},
-		}
-		cl := commontest.NewFakeClient(t, memberCluster)
-		username := "user-0001"
+		cl := commontest.NewFakeClient(t, userSignup(hostOperatorNamespace, "zippy-0001"))
 
 		// when
-		err := Create(cl, username, hostOperatorNamespace, memberOperatorNamespace)
+		err := Approve(cl, "zippy-0001", hostOperatorNamespace, "member-abcd")
 
 		// then
 		require.NoError(t, err)
+		usersignup := &toolchainv1alpha1.UserSignup{}
+		require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: hostOperatorNamespace, Name: "zippy-0001"}, usersignup))
+		assert.True(t, states.ApprovedManually(usersignup))
+		assert.Equal(t, "member-abcd", usersignup.Spec.TargetCluster)
 	})
 
-	t.Run("failures", func(t *testing.T) {
-		t.Run("missing ToolchainCluster resource for member cluster", func(t *testing.T) {
-			// given
-			configuration.DefaultTimeout = time.Second * 5
-			cl := commontest.NewFakeClient(t) // no ToolchainCluster
-			username := "user-0001"
-			hostOperatorNamespace := "toolchain-host-operator"
-			memberOperatorNamespace := "toolchain-member-operator"
+	t.Run("usersignup not found", func(t *testing.T) {
+		// given
+		configuration.DefaultTimeout = time.Second
+		cl := commontest.NewFakeClient(t)
 
-			// when
-			err := Create(cl, username, hostOperatorNamespace, memberOperatorNamespace)
+		// when
+		err := Approve(cl, "zippy-0001", hostOperatorNamespace, "")
 
-			// then
-			require.Error(t, err)
-			assert.EqualError(t, err, "unable to lookup member cluster name, ensure the sandbox setup steps are followed")
-		})
+		// then
+		require.EqualError(t, err, "usersignup 'zippy-0001' was not found: context deadline exceeded")
+	})
+}
+
+func TestListNames(t *testing.T) {
+	// given
+	hostOperatorNamespace := "toolchain-host-operator"
+	cl := commontest.NewFakeClient(t,
+		userSignup(hostOperatorNamespace, "zippy-0002"),
+		userSignup(hostOperatorNamespace, "zippy-0001"),
+		userSignup(hostOperatorNamespace, "zippyzoo-0001"),
+		userSignup(hostOperatorNamespace, "cupcake-0001"),
+		userSignup("other-namespace", "zippy-0003"),
+	)
+
+	// when
+	names, err := ListNames(cl, hostOperatorNamespace, "zippy")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zippy-0001", "zippy-0002"}, names)
+}
+
+func TestDelete(t *testing.T) {
+	// given
+	hostOperatorNamespace := "toolchain-host-operator"
+
+	t.Run("usersignup exists", func(t *testing.T) {
+		// given
+		cl := commontest.NewFakeClient(t, userSignup(hostOperatorNamespace, "zippy-0001"))
+
+		// when
+		err := Delete(cl, "zippy-0001", hostOperatorNamespace)
+
+		// then
+		require.NoError(t, err)
+		names, err := ListNames(cl, hostOperatorNamespace, "zippy")
+		require.NoError(t, err)
+		assert.Empty(t, names)
 	})
+
+	t.Run("usersignup already gone", func(t *testing.T) {
+		// given
+		cl := commontest.NewFakeClient(t)
+
+		// when
+		err := Delete(cl, "zippy-0001", hostOperatorNamespace)
+
+		// then
+		require.NoError(t, err)
+	})
+}
+
+func TestSetSpaceTier(t *testing.T) {
+	// given
+	hostOperatorNamespace := "toolchain-host-operator"
+	cl := commontest.NewFakeClient(t, testspace.NewSpace(hostOperatorNamespace, "zippy-0001", testspace.WithTierName("base1ns")))
+
+	// when
+	err := SetSpaceTier(cl, "zippy-0001", hostOperatorNamespace, "appstudio")
+
+	// then
+	require.NoError(t, err)
+	space := &toolchainv1alpha1.Space{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: hostOperatorNamespace, Name: "zippy-0001"}, space))
+	assert.Equal(t, "appstudio", space.Spec.TierName)
+}
+
+func userSignup(namespace, name string) *toolchainv1alpha1.UserSignup {
+	return &toolchainv1alpha1.UserSignup{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
 }
@@ -0,0 +1,108 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
+	"github.com/codeready-toolchain/toolchain-common/pkg/states"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Deactivate deactivates the UserSignup of the given user, the host operator then deprovisions the user
+func Deactivate(cl client.Client, username, hostOperatorNamespace string) error {
+	return updateUserSignup(cl, username, hostOperatorNamespace, func(usersignup *toolchainv1alpha1.UserSignup) bool {
+		if states.Deactivated(usersignup) {
+			return false
+		}
+		states.SetDeactivated(usersignup, true)
+		return true
+	})
+}
+
+// Reactivate approves again the deactivated UserSignup of the given user, the host operator then provisions the user again
+func Reactivate(cl client.Client, username, hostOperatorNamespace string) error {
+	return updateUserSignup(cl, username, hostOperatorNamespace, func(usersignup *toolchainv1alpha1.UserSignup) bool {
+		if !states.Deactivated(usersignup) && states.ApprovedManually(usersignup) {
+			return false
+		}
+		states.SetApprovedManually(usersignup, true)
+		return true
+	})
+}
+
+// Ban creates the BannedUser of the email of the given user, the host operator then deprovisions the user.
+// The BannedUser is named after the user so that a user that is already banned is not considered as an error.
+func Ban(cl client.Client, username, hostOperatorNamespace string) error {
+	email := fmt.Sprintf("%s@fake.test", username)
+	bannedUser := &toolchainv1alpha1.BannedUser{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: hostOperatorNamespace,
+			Name:      username,
+			Labels: map[string]string{
+				toolchainv1alpha1.BannedUserEmailHashLabelKey: hash.EncodeString(email),
+			},
+		},
+		Spec: toolchainv1alpha1.BannedUserSpec{
+			Email: email,
+		},
+	}
+	if err := cl.Create(context.TODO(), bannedUser); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// ListBannedNames returns the sorted names of the BannedUsers created by Ban for the users with the given username prefix
+func ListBannedNames(cl client.Client, hostOperatorNamespace, usernamePrefix string) ([]string, error) {
+	bannedUsers := &toolchainv1alpha1.BannedUserList{}
+	if err := cl.List(context.TODO(), bannedUsers, client.InNamespace(hostOperatorNamespace)); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, bu := range bannedUsers.Items {
+		if strings.HasPrefix(bu.Name, usernamePrefix+"-") {
+			names = append(names, bu.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Unban deletes the BannedUser created by Ban for the given user so that the user can sign up again, eg. in a later run with the same
+// username prefix. A BannedUser that is already gone is not considered as an error.
+func Unban(cl client.Client, username, hostOperatorNamespace string) error {
+	bannedUser := &toolchainv1alpha1.BannedUser{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: hostOperatorNamespace,
+			Name:      username,
+		},
+	}
+	if err := cl.Delete(context.TODO(), bannedUser); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// updateUserSignup applies the given change to the UserSignup of the given user, which is only updated when the change returns true
+func updateUserSignup(cl client.Client, username, hostOperatorNamespace string, change func(usersignup *toolchainv1alpha1.UserSignup) bool) error {
+	// the usersignup is updated by the host operator at the same time, so retry on conflicts
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		usersignup := &toolchainv1alpha1.UserSignup{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: hostOperatorNamespace, Name: username}, usersignup); err != nil {
+			return err
+		}
+		if !change(usersignup) {
+			return nil
+		}
+		return cl.Update(context.TODO(), usersignup)
+	})
+}
@@ -0,0 +1,127 @@
+package users
+
+import (
+	"context"
+	"testing"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
+	"github.com/codeready-toolchain/toolchain-common/pkg/states"
+	commontest "github.com/codeready-toolchain/toolchain-common/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDeactivateAndReactivate(t *testing.T) {
+	// given
+	hostOperatorNamespace := "toolchain-host-operator"
+	cl := commontest.NewFakeClient(t)
+	require.NoError(t, Create(cl, "user0001", hostOperatorNamespace, "member-abcd"))
+
+	t.Run("deactivate", func(t *testing.T) {
+		// when
+		err := Deactivate(cl, "user0001", hostOperatorNamespace)
+
+		// then
+		require.NoError(t, err)
+		usersignup := getUserSignup(t, cl, hostOperatorNamespace, "user0001")
+		assert.True(t, states.Deactivated(usersignup))
+
+		t.Run("already deactivated", func(t *testing.T) {
+			// when
+			err := Deactivate(cl, "user0001", hostOperatorNamespace)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, usersignup.ResourceVersion, getUserSignup(t, cl, hostOperatorNamespace, "user0001").ResourceVersion)
+		})
+	})
+
+	t.Run("reactivate", func(t *testing.T) {
+		// when
+		err := Reactivate(cl, "user0001", hostOperatorNamespace)
+
+		// then
+		require.NoError(t, err)
+		usersignup := getUserSignup(t, cl, hostOperatorNamespace, "user0001")
+		assert.False(t, states.Deactivated(usersignup))
+		assert.True(t, states.ApprovedManually(usersignup))
+	})
+
+	t.Run("usersignup not found", func(t *testing.T) {
+		// when
+		err := Deactivate(cl, "user0002", hostOperatorNamespace)
+
+		// then
+		require.EqualError(t, err, `usersignups.toolchain.dev.openshift.com "user0002" not found`)
+	})
+}
+
+func TestBan(t *testing.T) {
+	// given
+	hostOperatorNamespace := "toolchain-host-operator"
+	cl := commontest.NewFakeClient(t)
+
+	// when
+	err := Ban(cl, "user0001", hostOperatorNamespace)
+
+	// then
+	require.NoError(t, err)
+	bannedUser := &toolchainv1alpha1.BannedUser{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: hostOperatorNamespace, Name: "user0001"}, bannedUser))
+	assert.Equal(t, "user0001@fake.test", bannedUser.Spec.Email)
+	assert.Equal(t, hash.EncodeString("user0001@fake.test"), bannedUser.Labels[toolchainv1alpha1.BannedUserEmailHashLabelKey])
+
+	t.Run("already banned", func(t *testing.T) {
+		// when
+		err := Ban(cl, "user0001", hostOperatorNamespace)
+
+		// then
+		require.NoError(t, err)
+	})
+}
+
+func TestListBannedNamesAndUnban(t *testing.T) {
+	// given
+	hostOperatorNamespace := "toolchain-host-operator"
+	cl := commontest.NewFakeClient(t)
+	for _, username := range []string{"zippy-0002", "zippy-0001", "zippyzoo-0001", "cupcake-0001"} {
+		require.NoError(t, Ban(cl, username, hostOperatorNamespace))
+	}
+
+	t.Run("list", func(t *testing.T) {
+		// when
+		names, err := ListBannedNames(cl, hostOperatorNamespace, "zippy")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{"zippy-0001", "zippy-0002"}, names)
+	})
+
+	t.Run("unban", func(t *testing.T) {
+		// when
+		err := Unban(cl, "zippy-0001", hostOperatorNamespace)
+
+		// then
+		require.NoError(t, err)
+		names, err := ListBannedNames(cl, hostOperatorNamespace, "zippy")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"zippy-0002"}, names)
+
+		t.Run("already unbanned", func(t *testing.T) {
+			// when
+			err := Unban(cl, "zippy-0001", hostOperatorNamespace)
+
+			// then
+			require.NoError(t, err)
+		})
+	})
+}
+
+func getUserSignup(t *testing.T, cl *commontest.FakeClient, namespace, name string) *toolchainv1alpha1.UserSignup {
+	usersignup := &toolchainv1alpha1.UserSignup{}
+	require.NoError(t, cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, usersignup))
+	return usersignup
+}
@@ -0,0 +1,204 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/condition"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+
+	k8swait "k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PlacementStrategy defines on which member cluster each user is provisioned
+type PlacementStrategy string
+
+const (
+	// Single provisions all the users on the first ready member cluster whose operator runs in the member operator namespace
+	Single PlacementStrategy = "single"
+	// RoundRobin provisions the users on each ready member cluster in turn
+	RoundRobin PlacementStrategy = "round-robin"
+	// Weighted provisions the users on the member clusters of the weights, in proportion to their weights
+	Weighted PlacementStrategy = "weighted"
+	// HostDecides leaves the target cluster of the users empty so that the host operator picks the member cluster
+	HostDecides PlacementStrategy = "host"
+)
+
+// PlacementStrategies lists all the supported placement strategies
+var PlacementStrategies = []PlacementStrategy{Single, RoundRobin, Weighted, HostDecides}
+
+// ValidatePlacement checks the given strategy and the weights of the member clusters, which are only supported by the weighted strategy
+func ValidatePlacement(strategy PlacementStrategy, weights map[string]int) error {
+	switch strategy {
+	case Single, RoundRobin, HostDecides:
+		if len(weights) > 0 {
+			return fmt.Errorf("invalid placement '%s': the member weights are only supported by the '%s' placement", strategy, Weighted)
+		}
+	case Weighted:
+		if len(weights) == 0 {
+			return fmt.Errorf("invalid placement '%s': the weight of at least one member cluster must be set", strategy)
+		}
+		for member, weight := range weights {
+			if weight < 1 {
+				return fmt.Errorf("invalid placement '%s': invalid weight '%d' of the member cluster '%s': value must be more than 0", strategy, weight, member)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid placement '%s': must be one of %v", strategy, PlacementStrategies)
+	}
+	return nil
+}
+
+// Placement picks the target cluster of each user according to its strategy. The member clusters are picked with a smooth weighted
+// round-robin so that the users are spread evenly over the run, the round-robin strategy being the weighted one with equal weights.
+// It's safe for concurrent use.
+type Placement struct {
+	mu       sync.Mutex
+	strategy PlacementStrategy
+	members  []toolchainv1alpha1.ToolchainCluster
+	weights  []int
+	current  []int
+}
+
+// NewPlacement looks up the ready member clusters and returns the placement of the given strategy over them
+func NewPlacement(cl client.Client, hostOperatorNamespace, memberOperatorNamespace string, strategy PlacementStrategy, weights map[string]int) (*Placement, error) {
+	var members []toolchainv1alpha1.ToolchainCluster
+	err := k8swait.PollUntilContextTimeout(context.TODO(), configuration.DefaultRetryInterval, configuration.DefaultTimeout, true, func(ctx context.Context) (bool, error) {
+		clusters := &toolchainv1alpha1.ToolchainClusterList{}
+		if err := cl.List(context.TODO(), clusters, client.InNamespace(hostOperatorNamespace)); err != nil {
+			return false, err
+		}
+		members = nil
+		for _, cluster := range clusters.Items {
+			if !condition.IsTrue(cluster.Status.Conditions, toolchainv1alpha1.ConditionReady) {
+				continue
+			}
+			if strategy == Single && cluster.Status.OperatorNamespace != memberOperatorNamespace {
+				continue
+			}
+			if strategy == Weighted && weights[cluster.Name] == 0 {
+				continue
+			}
+			members = append(members, cluster)
+		}
+		if strategy == Weighted {
+			return len(members) == len(weights), nil
+		}
+		return len(members) > 0, nil
+	})
+	if err != nil {
+		if strategy == Weighted {
+			return nil, fmt.Errorf("unable to lookup the member clusters %v, ensure they are all ready", sortedKeys(weights))
+		}
+		return nil, fmt.Errorf("unable to lookup member cluster name, ensure the sandbox setup steps are followed")
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	if strategy == Single {
+		members = members[:1]
+	}
+
+	p := &Placement{
+		strategy: strategy,
+		members:  members,
+		weights:  make([]int, len(members)),
+		current:  make([]int, len(members)),
+	}
+	for i, m := range members {
+		p.weights[i] = 1
+		if strategy == Weighted {
+			p.weights[i] = weights[m.Name]
+		}
+	}
+	return p, nil
+}
+
+// Members returns the member clusters of the placement, sorted by name
+func (p *Placement) Members() []toolchainv1alpha1.ToolchainCluster {
+	return p.members
+}
+
+// Next returns the target cluster of the next user, which is empty when the host operator decides
+func (p *Placement) Next() string {
+	if p.strategy == HostDecides {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	total, best := 0, 0
+	for i, w := range p.weights {
+		p.current[i] += w
+		total += w
+		if p.current[i] > p.current[best] {
+			best = i
+		}
+	}
+	p.current[best] -= total
+	return p.members[best].Name
+}
+
+// CountPerMember returns the number of spaces of the given users that are provisioned on each member cluster
+func CountPerMember(cl client.Client, hostOperatorNamespace string, usernames []string) (map[string]int, error) {
+	spaces := &toolchainv1alpha1.SpaceList{}
+	if err := cl.List(context.TODO(), spaces, client.InNamespace(hostOperatorNamespace)); err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		wanted[username] = true
+	}
+	counts := map[string]int{}
+	for _, space := range spaces.Items {
+		if wanted[space.Name] && space.Status.TargetCluster != "" {
+			counts[space.Status.TargetCluster]++
+		}
+	}
+	return counts, nil
+}
+
+// MemberOperatorNamespaces returns the namespace of the member operator of the member cluster on which the space of each of the given users
+// is provisioned, which is where the NSTemplateSet of the user is. The users whose space is not provisioned are not part of the result.
+func MemberOperatorNamespaces(cl client.Client, hostOperatorNamespace string, usernames []string) (map[string]string, error) {
+	clusters := &toolchainv1alpha1.ToolchainClusterList{}
+	if err := cl.List(context.TODO(), clusters, client.InNamespace(hostOperatorNamespace)); err != nil {
+		return nil, err
+	}
+	operatorNamespaces := map[string]string{}
+	for _, cluster := range clusters.Items {
+		operatorNamespaces[cluster.Name] = cluster.Status.OperatorNamespace
+	}
+	spaces := &toolchainv1alpha1.SpaceList{}
+	if err := cl.List(context.TODO(), spaces, client.InNamespace(hostOperatorNamespace)); err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		wanted[username] = true
+	}
+	namespaces := map[string]string{}
+	for _, space := range spaces.Items {
+		if !wanted[space.Name] || space.Status.TargetCluster == "" {
+			continue
+		}
+		namespace, found := operatorNamespaces[space.Status.TargetCluster]
+		if !found {
+			return nil, fmt.Errorf("unable to find the member cluster '%s' of space '%s'", space.Status.TargetCluster, space.Name)
+		}
+		namespaces[space.Name] = namespace
+	}
+	return namespaces, nil
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
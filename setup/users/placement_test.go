@@ -0,0 +1,231 @@
+package users
+
+import (
+	"testing"
+	"time"
+
+	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	commontest "github.com/codeready-toolchain/toolchain-common/pkg/test"
+	testspace "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
+	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	hostOperatorNamespace   = "toolchain-host-operator"
+	memberOperatorNamespace = "toolchain-member-operator"
+)
+
+func TestNewPlacement(t *testing.T) {
+	// given
+	configuration.DefaultTimeout = time.Second
+	objs := []client.Object{
+		memberCluster("member-b", memberOperatorNamespace, true),
+		memberCluster("member-a", memberOperatorNamespace+"2", true),
+		memberCluster("member-c", memberOperatorNamespace, true),
+		memberCluster("member-d", memberOperatorNamespace, false), // not ready
+	}
+
+	t.Run("success", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			strategy PlacementStrategy
+			weights  map[string]int
+			expected []string
+		}{
+			"single": {
+				strategy: Single,
+				expected: []string{"member-b", "member-b", "member-b", "member-b", "member-b", "member-b"},
+			},
+			"round-robin": {
+				strategy: RoundRobin,
+				expected: []string{"member-a", "member-b", "member-c", "member-a", "member-b", "member-c"},
+			},
+			"weighted": {
+				strategy: Weighted,
+				weights:  map[string]int{"member-a": 2, "member-c": 1},
+				expected: []string{"member-a", "member-c", "member-a", "member-a", "member-c", "member-a"},
+			},
+			"host": {
+				strategy: HostDecides,
+				expected: []string{"", "", "", "", "", ""},
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				cl := commontest.NewFakeClient(t, objs...)
+
+				// when
+				p, err := NewPlacement(cl, hostOperatorNamespace, memberOperatorNamespace, tc.strategy, tc.weights)
+
+				// then
+				require.NoError(t, err)
+				var targetClusters []string
+				for i := 0; i < len(tc.expected); i++ {
+					targetClusters = append(targetClusters, p.Next())
+				}
+				assert.Equal(t, tc.expected, targetClusters)
+			})
+		}
+
+		t.Run("members", func(t *testing.T) {
+			// given
+			cl := commontest.NewFakeClient(t, objs...)
+
+			// when
+			p, err := NewPlacement(cl, hostOperatorNamespace, memberOperatorNamespace, HostDecides, nil)
+
+			// then
+			require.NoError(t, err)
+			var names []string
+			for _, m := range p.Members() {
+				names = append(names, m.Name)
+			}
+			assert.Equal(t, []string{"member-a", "member-b", "member-c"}, names)
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("no ready member cluster", func(t *testing.T) {
+			// given
+			cl := commontest.NewFakeClient(t, memberCluster("member-d", memberOperatorNamespace, false))
+
+			// when
+			_, err := NewPlacement(cl, hostOperatorNamespace, memberOperatorNamespace, RoundRobin, nil)
+
+			// then
+			require.EqualError(t, err, "unable to lookup member cluster name, ensure the sandbox setup steps are followed")
+		})
+
+		t.Run("weighted member cluster not ready", func(t *testing.T) {
+			// given
+			cl := commontest.NewFakeClient(t, objs...)
+
+			// when
+			_, err := NewPlacement(cl, hostOperatorNamespace, memberOperatorNamespace, Weighted, map[string]int{"member-a": 1, "member-d": 1})
+
+			// then
+			require.EqualError(t, err, "unable to lookup the member clusters [member-a member-d], ensure they are all ready")
+		})
+	})
+}
+
+func TestValidatePlacement(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, ValidatePlacement(Single, nil))
+		require.NoError(t, ValidatePlacement(RoundRobin, nil))
+		require.NoError(t, ValidatePlacement(HostDecides, map[string]int{}))
+		require.NoError(t, ValidatePlacement(Weighted, map[string]int{"member-a": 3, "member-b": 1}))
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			strategy PlacementStrategy
+			weights  map[string]int
+			err      string
+		}{
+			"unknown strategy": {
+				strategy: "random",
+				err:      "invalid placement 'random': must be one of [single round-robin weighted host]",
+			},
+			"weights without weighted strategy": {
+				strategy: RoundRobin,
+				weights:  map[string]int{"member-a": 1},
+				err:      "invalid placement 'round-robin': the member weights are only supported by the 'weighted' placement",
+			},
+			"weighted strategy without weights": {
+				strategy: Weighted,
+				err:      "invalid placement 'weighted': the weight of at least one member cluster must be set",
+			},
+			"invalid weight": {
+				strategy: Weighted,
+				weights:  map[string]int{"member-a": 0},
+				err:      "invalid placement 'weighted': invalid weight '0' of the member cluster 'member-a': value must be more than 0",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				require.EqualError(t, ValidatePlacement(tc.strategy, tc.weights), tc.err)
+			})
+		}
+	})
+}
+
+func TestCountPerMember(t *testing.T) {
+	// given
+	cl := commontest.NewFakeClient(t,
+		testspace.NewSpace(hostOperatorNamespace, "zippy-0001", testspace.WithStatusTargetCluster("member-a")),
+		testspace.NewSpace(hostOperatorNamespace, "zippy-0002", testspace.WithStatusTargetCluster("member-b")),
+		testspace.NewSpace(hostOperatorNamespace, "zippy-0003", testspace.WithStatusTargetCluster("member-a")),
+		testspace.NewSpace(hostOperatorNamespace, "zippy-0004"), // not provisioned yet
+		testspace.NewSpace(hostOperatorNamespace, "other-0001", testspace.WithStatusTargetCluster("member-a")),
+	)
+
+	// when
+	counts, err := CountPerMember(cl, hostOperatorNamespace, []string{"zippy-0001", "zippy-0002", "zippy-0003", "zippy-0004"})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"member-a": 2, "member-b": 1}, counts)
+}
+
+func TestMemberOperatorNamespaces(t *testing.T) {
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		cl := commontest.NewFakeClient(t,
+			memberCluster("member-a", memberOperatorNamespace, true),
+			memberCluster("member-b", memberOperatorNamespace+"2", true),
+			testspace.NewSpace(hostOperatorNamespace, "zippy-0001", testspace.WithStatusTargetCluster("member-a")),
+			testspace.NewSpace(hostOperatorNamespace, "zippy-0002", testspace.WithStatusTargetCluster("member-b")),
+			testspace.NewSpace(hostOperatorNamespace, "zippy-0003"), // not provisioned yet
+			testspace.NewSpace(hostOperatorNamespace, "other-0001", testspace.WithStatusTargetCluster("member-a")),
+		)
+
+		// when
+		namespaces, err := MemberOperatorNamespaces(cl, hostOperatorNamespace, []string{"zippy-0001", "zippy-0002", "zippy-0003"})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"zippy-0001": memberOperatorNamespace, "zippy-0002": memberOperatorNamespace + "2"}, namespaces)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		// given
+		cl := commontest.NewFakeClient(t,
+			memberCluster("member-a", memberOperatorNamespace, true),
+			testspace.NewSpace(hostOperatorNamespace, "zippy-0001", testspace.WithStatusTargetCluster("member-gone")),
+		)
+
+		// when
+		_, err := MemberOperatorNamespaces(cl, hostOperatorNamespace, []string{"zippy-0001"})
+
+		// then
+		require.EqualError(t, err, "unable to find the member cluster 'member-gone' of space 'zippy-0001'")
+	})
+}
+
+func memberCluster(name, operatorNamespace string, ready bool) *toolchainv1alpha1.ToolchainCluster {
+	status := corev1.ConditionTrue
+	if !ready {
+		status = corev1.ConditionFalse
+	}
+	return &toolchainv1alpha1.ToolchainCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: hostOperatorNamespace,
+			Name:      name,
+		},
+		Status: toolchainv1alpha1.ToolchainClusterStatus{
+			OperatorNamespace: operatorNamespace,
+			Conditions: []toolchainv1alpha1.Condition{
+				{
+					Type:   toolchainv1alpha1.ConditionReady,
+					Status: status,
+				},
+			},
+		},
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
 	"github.com/codeready-toolchain/toolchain-common/pkg/test"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
 
@@ -18,6 +19,18 @@ import (
 )
 
 func ForSpace(cl client.Client, space string) error {
+	return forSpace(cl, space)
+}
+
+// ForSpaceWithTier waits until the Space with the given name is provisioned with the templates of the given tier
+func ForSpaceWithTier(cl client.Client, space, tier string) error {
+	return forSpace(cl, space, func(sp *toolchainv1alpha1.Space) bool {
+		_, found := sp.Labels[hash.TemplateTierHashLabelKey(tier)]
+		return sp.Spec.TierName == tier && found
+	})
+}
+
+func forSpace(cl client.Client, space string, criteria ...func(sp *toolchainv1alpha1.Space) bool) error {
 	sp := &toolchainv1alpha1.Space{}
 	expectedConditions := []toolchainv1alpha1.Condition{
 		{
@@ -39,6 +52,11 @@ func ForSpace(cl client.Client, space string) error {
 		} else if !test.ConditionsMatch(sp.Status.Conditions, expectedConditions...) {
 			return false, nil
 		}
+		for _, crit := range criteria {
+			if !crit(sp) {
+				return false, nil
+			}
+		}
 		return true, nil
 	}); err != nil {
 		return fmt.Errorf("space '%s' is not ready yet: %w", space, err)
@@ -46,6 +64,53 @@ func ForSpace(cl client.Client, space string) error {
 	return nil
 }
 
+// ForSpaceDeletion waits until the Space with the given name no longer exists in the host operator namespace
+func ForSpaceDeletion(cl client.Client, space string) error {
+	return forDeletion(cl, &toolchainv1alpha1.Space{}, types.NamespacedName{Namespace: configuration.HostOperatorNamespace, Name: space}, "space")
+}
+
+// ForMasterUserRecordDeletion waits until the MasterUserRecord with the given name no longer exists in the host operator namespace
+func ForMasterUserRecordDeletion(cl client.Client, mur string) error {
+	return forDeletion(cl, &toolchainv1alpha1.MasterUserRecord{}, types.NamespacedName{Namespace: configuration.HostOperatorNamespace, Name: mur}, "masteruserrecord")
+}
+
+// ForNSTemplateSetDeletion waits until the NSTemplateSet with the given name no longer exists in the given member operator namespace
+func ForNSTemplateSetDeletion(cl client.Client, memberOperatorNamespace, nsTemplateSet string) error {
+	return forDeletion(cl, &toolchainv1alpha1.NSTemplateSet{}, types.NamespacedName{Namespace: memberOperatorNamespace, Name: nsTemplateSet}, "nstemplateset")
+}
+
+// ForNamespaceDeletion waits until the Namespace with the given name no longer exists
+func ForNamespaceDeletion(cl client.Client, namespace string) error {
+	return forDeletion(cl, &corev1.Namespace{}, types.NamespacedName{Name: namespace}, "namespace")
+}
+
+// ForSpaceNamespacesDeletion waits until none of the namespaces provisioned for the Space with the given name exists
+func ForSpaceNamespacesDeletion(cl client.Client, space string) error {
+	if err := k8swait.PollUntilContextTimeout(context.TODO(), configuration.DefaultRetryInterval, configuration.DefaultTimeout, true, func(ctx context.Context) (bool, error) {
+		namespaces := &corev1.NamespaceList{}
+		if err := cl.List(context.TODO(), namespaces, client.MatchingLabels{toolchainv1alpha1.SpaceLabelKey: space}); err != nil {
+			return false, err
+		}
+		return len(namespaces.Items) == 0, nil
+	}); err != nil {
+		return fmt.Errorf("namespaces of space '%s' were not deleted yet: %w", space, err)
+	}
+	return nil
+}
+
+func forDeletion(cl client.Client, obj client.Object, key types.NamespacedName, kind string) error {
+	if err := k8swait.PollUntilContextTimeout(context.TODO(), configuration.DefaultRetryInterval, configuration.DefaultTimeout, true, func(ctx context.Context) (bool, error) {
+		err := cl.Get(context.TODO(), key, obj)
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		return fmt.Errorf("%s '%s' was not deleted yet: %w", kind, key.Name, err)
+	}
+	return nil
+}
+
 func HasSubscriptionWithCriteria(cl client.Client, name, namespace string, criteria ...subCriteria) (bool, error) {
 	sub := &v1alpha1.Subscription{}
 	if err := cl.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, sub); err != nil {
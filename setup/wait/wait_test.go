@@ -7,6 +7,7 @@ import (
 	"time"
 
 	toolchainv1alpha1 "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/codeready-toolchain/toolchain-common/pkg/hash"
 	testspace "github.com/codeready-toolchain/toolchain-common/pkg/test/space"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/configuration"
 	"github.com/codeready-toolchain/toolchain-e2e/setup/test"
@@ -56,6 +57,124 @@ func TestForSpace(t *testing.T) {
 	})
 }
 
+func TestForSpaceWithTier(t *testing.T) {
+	configuration.DefaultTimeout = time.Millisecond * 1
+	provisioned := testspace.WithCondition(toolchainv1alpha1.Condition{
+		Type:   toolchainv1alpha1.ConditionReady,
+		Status: corev1.ConditionTrue,
+		Reason: "Provisioned",
+	})
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		space := testspace.NewSpace(configuration.HostOperatorNamespace, "user0001", provisioned,
+			testspace.WithTierName("appstudio"), testspace.WithLabel(hash.TemplateTierHashLabelKey("appstudio"), "abc123"))
+		cl := test.NewFakeClient(t, space)
+
+		// when
+		err := wait.ForSpaceWithTier(cl, "user0001", "appstudio")
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("tier hash label of previous tier", func(t *testing.T) {
+			// given
+			space := testspace.NewSpace(configuration.HostOperatorNamespace, "user0001", provisioned,
+				testspace.WithTierName("appstudio"), testspace.WithLabel(hash.TemplateTierHashLabelKey("base1ns"), "abc123"))
+			cl := test.NewFakeClient(t, space)
+
+			// when
+			err := wait.ForSpaceWithTier(cl, "user0001", "appstudio")
+
+			// then
+			require.EqualError(t, err, "space 'user0001' is not ready yet: context deadline exceeded")
+		})
+	})
+}
+
+func TestForDeletion(t *testing.T) {
+	configuration.DefaultTimeout = time.Millisecond * 1
+	configuration.HostOperatorNamespace = "toolchain-host-operator"
+	configuration.MemberOperatorNamespace = "toolchain-member-operator"
+
+	t.Run("success", func(t *testing.T) {
+		// given
+		cl := test.NewFakeClient(t) // nothing exists
+
+		// when
+		murErr := wait.ForMasterUserRecordDeletion(cl, "user0001")
+		spaceErr := wait.ForSpaceDeletion(cl, "user0001")
+		nsTemplateSetErr := wait.ForNSTemplateSetDeletion(cl, configuration.MemberOperatorNamespace, "user0001")
+		namespaceErr := wait.ForNamespaceDeletion(cl, "user0001-dev")
+		spaceNamespacesErr := wait.ForSpaceNamespacesDeletion(cl, "user0001")
+
+		// then
+		require.NoError(t, murErr)
+		require.NoError(t, spaceErr)
+		require.NoError(t, nsTemplateSetErr)
+		require.NoError(t, namespaceErr)
+		require.NoError(t, spaceNamespacesErr)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Run("timeout", func(t *testing.T) {
+			// given
+			mur := &toolchainv1alpha1.MasterUserRecord{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "user0001",
+					Namespace: configuration.HostOperatorNamespace,
+				},
+			}
+			space := testspace.NewSpace(configuration.HostOperatorNamespace, "user0001")
+			nsTemplateSet := &toolchainv1alpha1.NSTemplateSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "user0001",
+					Namespace: configuration.MemberOperatorNamespace,
+				},
+			}
+			namespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "user0001-dev",
+					Labels: map[string]string{
+						toolchainv1alpha1.SpaceLabelKey: "user0001",
+					},
+				},
+			}
+			cl := test.NewFakeClient(t, mur, space, nsTemplateSet, namespace) // everything still exists
+
+			// when
+			murErr := wait.ForMasterUserRecordDeletion(cl, "user0001")
+			spaceErr := wait.ForSpaceDeletion(cl, "user0001")
+			nsTemplateSetErr := wait.ForNSTemplateSetDeletion(cl, configuration.MemberOperatorNamespace, "user0001")
+			namespaceErr := wait.ForNamespaceDeletion(cl, "user0001-dev")
+			spaceNamespacesErr := wait.ForSpaceNamespacesDeletion(cl, "user0001")
+
+			// then
+			require.EqualError(t, murErr, "masteruserrecord 'user0001' was not deleted yet: context deadline exceeded")
+			require.EqualError(t, spaceErr, "space 'user0001' was not deleted yet: context deadline exceeded")
+			require.EqualError(t, nsTemplateSetErr, "nstemplateset 'user0001' was not deleted yet: context deadline exceeded")
+			require.EqualError(t, namespaceErr, "namespace 'user0001-dev' was not deleted yet: context deadline exceeded")
+			require.EqualError(t, spaceNamespacesErr, "namespaces of space 'user0001' were not deleted yet: context deadline exceeded")
+		})
+
+		t.Run("client error", func(t *testing.T) {
+			// given
+			cl := test.NewFakeClient(t)
+			cl.MockGet = func(_ context.Context, _ types.NamespacedName, _ client.Object, _ ...client.GetOption) error {
+				return fmt.Errorf("Test client error")
+			}
+
+			// when
+			err := wait.ForSpaceDeletion(cl, "user0001")
+
+			// then
+			require.EqualError(t, err, "space 'user0001' was not deleted yet: Test client error")
+		})
+	})
+}
+
 func TestHasSubscriptionWithCondition(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		t.Run("without criteria", func(t *testing.T) {